@@ -0,0 +1,25 @@
+// Package testdb opens throwaway databases for repository and use case tests
+package testdb
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open returns an in-memory SQLite database with models migrated, it is gone once tb ends
+func Open(tb testing.TB, models ...interface{}) *gorm.DB {
+	tb.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(tb, err)
+	//**Every connection to :memory: is a new database, keep a single one
+	sqlDB, err := db.DB()
+	require.NoError(tb, err)
+	sqlDB.SetMaxOpenConns(1)
+	tb.Cleanup(func() { sqlDB.Close() })
+	require.NoError(tb, db.AutoMigrate(models...))
+	return db
+}
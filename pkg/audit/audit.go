@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"log"
+	"time"
+)
+
+// Event types recorded by the application
+const (
+	EventAccountLocked   = "account.locked"
+	EventAccountUnlocked = "account.unlocked"
+)
+
+// Event is one security relevant action
+type Event struct {
+	Type     string
+	Actor    string
+	Subject  string
+	ClientIP string
+	Detail   string
+	At       time.Time
+}
+
+// Logger records audit events, implementations must not block the request for long
+type Logger interface {
+	Record(event Event)
+}
+
+// LogLogger writes audit events to the application log
+type LogLogger struct{}
+
+func (LogLogger) Record(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	log.Printf("audit: type=%s actor=%q subject=%q ip=%q detail=%q at=%s",
+		event.Type, event.Actor, event.Subject, event.ClientIP, event.Detail, event.At.Format(time.RFC3339))
+}
@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/cart/usecase"
+	"github.com/ratheeshkumar25/pkg/middleware"
+)
+
+type CartHandler struct {
+	cartUseCase usecase.CartUseCase
+}
+
+type CartUseCases interface {
+	GetCartHandler(c *gin.Context)
+	AddItemHandler(c *gin.Context)
+	UpdateItemHandler(c *gin.Context)
+	RemoveItemHandler(c *gin.Context)
+	ClearCartHandler(c *gin.Context)
+}
+
+func (h *CartHandler) GetCartHandler(c *gin.Context) {
+	userID, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+	current, err := h.cartUseCase.GetCart(userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to load cart"})
+		return
+	}
+	c.JSON(200, NewCartResponse(current))
+}
+
+// AddItemHandler adds quantity more of a product, adding a product twice grows its line
+func (h *CartHandler) AddItemHandler(c *gin.Context) {
+	userID, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+	var request cart.AddItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	current, err := h.cartUseCase.AddItem(userID, &request)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	c.JSON(200, NewCartResponse(current))
+}
+
+// UpdateItemHandler sets the quantity of a line
+func (h *CartHandler) UpdateItemHandler(c *gin.Context) {
+	userID, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+	productID, ok := cartProductID(c)
+	if !ok {
+		return
+	}
+	var request cart.UpdateItemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	current, err := h.cartUseCase.UpdateItem(userID, productID, &request)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	c.JSON(200, NewCartResponse(current))
+}
+
+func (h *CartHandler) RemoveItemHandler(c *gin.Context) {
+	userID, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+	productID, ok := cartProductID(c)
+	if !ok {
+		return
+	}
+	current, err := h.cartUseCase.RemoveItem(userID, productID)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	c.JSON(200, NewCartResponse(current))
+}
+
+func (h *CartHandler) ClearCartHandler(c *gin.Context) {
+	userID, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+	current, err := h.cartUseCase.Clear(userID)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+	c.JSON(200, NewCartResponse(current))
+}
+
+// cartOwner returns the calling user, carts belong to user accounts only
+func cartOwner(c *gin.Context) (uint, bool) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only users have a cart"})
+		return 0, false
+	}
+	return caller.ID, true
+}
+
+func cartProductID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("product_id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid product ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func cartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrProductNotFound), errors.Is(err, usecase.ErrItemNotInCart):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInsufficientStock), errors.Is(err, usecase.ErrCurrencyMismatch):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "failed to update cart"})
+	}
+}
+
+func NewCartHandler(cartUseCase usecase.CartUseCase) *CartHandler {
+	return &CartHandler{
+		cartUseCase: cartUseCase,
+	}
+}
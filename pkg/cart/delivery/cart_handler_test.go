@@ -0,0 +1,133 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/cart/usecase"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockCartUseCase is a mock implementation of the CartUseCase interface
+type MockCartUseCase struct {
+	mock.Mock
+}
+
+func (m *MockCartUseCase) GetCart(userID uint) (*cart.Cart, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*cart.Cart), args.Error(1)
+}
+
+func (m *MockCartUseCase) AddItem(userID uint, request *cart.AddItemRequest) (*cart.Cart, error) {
+	args := m.Called(userID, request)
+	return args.Get(0).(*cart.Cart), args.Error(1)
+}
+
+func (m *MockCartUseCase) UpdateItem(userID, productID uint, request *cart.UpdateItemRequest) (*cart.Cart, error) {
+	args := m.Called(userID, productID, request)
+	return args.Get(0).(*cart.Cart), args.Error(1)
+}
+
+func (m *MockCartUseCase) RemoveItem(userID, productID uint) (*cart.Cart, error) {
+	args := m.Called(userID, productID)
+	return args.Get(0).(*cart.Cart), args.Error(1)
+}
+
+func (m *MockCartUseCase) Clear(userID uint) (*cart.Cart, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*cart.Cart), args.Error(1)
+}
+
+func TestCartHandlers(t *testing.T) {
+	mockCartUseCase := new(MockCartUseCase)
+	handler := NewCartHandler(mockCartUseCase)
+
+	// Simulate the authentication middleware loading the caller
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	withUser := func(c *gin.Context) { c.Set(middleware.UserKey, caller) }
+
+	r := gin.Default()
+	r.GET("/cart", withUser, handler.GetCartHandler)
+	r.POST("/cart/items", withUser, handler.AddItemHandler)
+	r.PUT("/cart/items/:product_id", withUser, handler.UpdateItemHandler)
+	r.GET("/admin/cart", handler.GetCartHandler)
+
+	updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	shoe := &user.Product{Model: gorm.Model{ID: 1}, Quantity: 5, Price: money.Money{Amount: 2499, Currency: "USD"}}
+	filled := &cart.Cart{ID: 3, UserID: 7, UpdatedAt: updated, Items: []cart.CartItem{
+		{ProductID: 1, ProductName: "Shoe", UnitPrice: money.Money{Amount: 1999, Currency: "USD"}, Quantity: 2, Product: shoe},
+		{ProductID: 2, ProductName: "Sock", UnitPrice: money.Money{Amount: 250, Currency: "USD"}, Quantity: 4},
+	}}
+
+	mockCartUseCase.On("AddItem", uint(7), &cart.AddItemRequest{ProductID: 1, Quantity: 2}).Return(filled, nil)
+	mockCartUseCase.On("UpdateItem", uint(7), uint(1), &cart.UpdateItemRequest{Quantity: 9}).
+		Return((*cart.Cart)(nil), fmt.Errorf("%w: 9 requested, 5 in stock", usecase.ErrInsufficientStock))
+
+	req, _ := http.NewRequest("POST", "/cart/items", bytes.NewBufferString(`{"product_id":1,"quantity":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The snapshotted price is charged, the new price is shown next to it
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"id":3,"item_count":6,"subtotal":{"amount":"49.98","currency":"USD"},"updated_at":"2024-01-01T00:00:00Z",
+		"items":[
+			{"product_id":1,"product_name":"Shoe","unit_price":{"amount":"19.99","currency":"USD"},"quantity":2,"line_total":{"amount":"39.98","currency":"USD"},
+			 "available":true,"price_changed":true,"current_price":{"amount":"24.99","currency":"USD"}},
+			{"product_id":2,"product_name":"Sock","unit_price":{"amount":"2.50","currency":"USD"},"quantity":4,"line_total":{"amount":"10.00","currency":"USD"},
+			 "available":false,"price_changed":false}
+		]
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("PUT", "/cart/items/1", bytes.NewBufferString(`{"quantity":9}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"not enough stock for the requested quantity: 9 requested, 5 in stock"}`, w.Body.String())
+
+	// Quantities below one are refused before the use case runs
+	req, _ = http.NewRequest("POST", "/cart/items", bytes.NewBufferString(`{"product_id":1,"quantity":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Admin accounts have no cart
+	req, _ = http.NewRequest("GET", "/admin/cart", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockCartUseCase.AssertExpectations(t)
+}
+
+func TestEmptyCartHasNoSubtotal(t *testing.T) {
+	mockCartUseCase := new(MockCartUseCase)
+	handler := NewCartHandler(mockCartUseCase)
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	r := gin.Default()
+	r.GET("/cart", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.GetCartHandler)
+
+	mockCartUseCase.On("GetCart", uint(7)).Return(&cart.Cart{ID: 3, UserID: 7}, nil)
+
+	req, _ := http.NewRequest("GET", "/cart", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":3,"items":[],"item_count":0,"subtotal":null,"updated_at":"0001-01-01T00:00:00Z"}`, w.Body.String())
+}
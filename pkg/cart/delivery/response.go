@@ -0,0 +1,55 @@
+package delivery
+
+import (
+	"time"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+type CartResponse struct {
+	ID        uint               `json:"id"`
+	Items     []CartItemResponse `json:"items"`
+	ItemCount int                `json:"item_count"`
+	Subtotal  *money.Money       `json:"subtotal"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// CartItemResponse is a cart line, current_price is only sent once the
+// product's price moved away from the snapshotted unit_price
+type CartItemResponse struct {
+	ProductID    uint         `json:"product_id"`
+	ProductName  string       `json:"product_name"`
+	UnitPrice    money.Money  `json:"unit_price"`
+	Quantity     int          `json:"quantity"`
+	LineTotal    money.Money  `json:"line_total"`
+	Available    bool         `json:"available"`
+	PriceChanged bool         `json:"price_changed"`
+	CurrentPrice *money.Money `json:"current_price,omitempty"`
+}
+
+func NewCartResponse(c *cart.Cart) CartResponse {
+	response := CartResponse{ID: c.ID, Items: make([]CartItemResponse, 0, len(c.Items)), UpdatedAt: c.UpdatedAt}
+	for i := range c.Items {
+		item := &c.Items[i]
+		line := CartItemResponse{
+			ProductID:    item.ProductID,
+			ProductName:  item.ProductName,
+			UnitPrice:    item.UnitPrice,
+			Quantity:     item.Quantity,
+			LineTotal:    item.LineTotal(),
+			Available:    item.Available(),
+			PriceChanged: item.PriceChanged(),
+		}
+		if line.PriceChanged {
+			line.CurrentPrice = &item.Product.Price
+		}
+		response.Items = append(response.Items, line)
+		response.ItemCount += item.Quantity
+	}
+	//**Carts are kept in one currency, a failing sum only means the cart is empty
+	if subtotal, err := c.Subtotal(); err == nil && len(c.Items) > 0 {
+		response.Subtotal = &subtotal
+	}
+	return response
+}
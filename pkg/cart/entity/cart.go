@@ -0,0 +1,72 @@
+package cart
+
+import (
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+)
+
+// Cart is the one shopping cart of a registered user, it lives in the database
+// so it survives logging out and moving to another device
+type Cart struct {
+	ID        uint               `gorm:"primarykey" json:"id"`
+	UserID    uint               `gorm:"not null;uniqueIndex" json:"user_id"`
+	User      *user.UserRegister `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Items     []CartItem         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"items"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// CartItem is one product line. Name and unit price are snapshotted whenever the
+// line is written so the cart shows what the user agreed to, Product is the live row
+type CartItem struct {
+	ID          uint          `gorm:"primarykey" json:"id"`
+	CartID      uint          `gorm:"not null;uniqueIndex:idx_cart_items_cart_product,priority:1" json:"cart_id"`
+	ProductID   uint          `gorm:"not null;uniqueIndex:idx_cart_items_cart_product,priority:2" json:"product_id"`
+	Product     *user.Product `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	ProductName string        `gorm:"type:varchar(255);not null" json:"product_name"`
+	UnitPrice   money.Money   `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Quantity    int           `gorm:"not null" json:"quantity"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// LineTotal is the snapshotted unit price times the quantity
+func (i *CartItem) LineTotal() money.Money {
+	return i.UnitPrice.Mul(int64(i.Quantity))
+}
+
+// Available reports whether the product still exists and has enough stock for the line
+func (i *CartItem) Available() bool {
+	return i.Product != nil && i.Product.Quantity >= i.Quantity
+}
+
+// PriceChanged reports whether the product's price moved since the line was written
+func (i *CartItem) PriceChanged() bool {
+	return i.Product != nil && i.Product.Price != i.UnitPrice
+}
+
+// Subtotal adds up the line totals, carts only ever hold one currency
+func (c *Cart) Subtotal() (money.Money, error) {
+	if len(c.Items) == 0 {
+		return money.Money{}, nil
+	}
+	total := money.Money{Currency: c.Items[0].UnitPrice.Currency}
+	for i := range c.Items {
+		var err error
+		if total, err = total.Add(c.Items[i].LineTotal()); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+type AddItemRequest struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+type UpdateItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository interface {
+	GetCart(userID uint) (*cart.Cart, error)
+	SaveItem(item *cart.CartItem) error
+	DeleteItem(cartID, productID uint) error
+	ClearCart(cartID uint) error
+}
+
+type CartDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+// GetCart loads the user's cart with its items and their live products,
+// creating the cart the first time. Items of deleted products have a nil Product
+func (c *CartDataBaseInteraction) GetCart(userID uint) (*cart.Cart, error) {
+	//**Two first requests racing each other end up with the same cart
+	created := cart.Cart{UserID: userID}
+	if err := c.DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(&created).Error; err != nil {
+		return nil, fmt.Errorf("creating cart: %w", err)
+	}
+
+	var found cart.Cart
+	err := c.DB.Where("user_id = ?", userID).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("cart_items.id ASC") }).
+		Preload("Items.Product").
+		First(&found).Error
+	if err != nil {
+		return nil, fmt.Errorf("loading cart: %w", err)
+	}
+	return &found, nil
+}
+
+// SaveItem writes the line for item's product, replacing the one already in the cart
+func (c *CartDataBaseInteraction) SaveItem(item *cart.CartItem) error {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("Product").Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cart_id"}, {Name: "product_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"product_name", "unit_price_amount", "unit_price_currency", "quantity", "updated_at"}),
+		}).Create(item).Error
+		if err != nil {
+			return err
+		}
+		return touchCart(tx, item.CartID)
+	})
+	if err != nil {
+		return fmt.Errorf("saving cart item: %w", err)
+	}
+	return nil
+}
+
+// DeleteItem returns gorm.ErrRecordNotFound when the product is not in the cart
+func (c *CartDataBaseInteraction) DeleteItem(cartID, productID uint) error {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("cart_id = ? AND product_id = ?", cartID, productID).Delete(&cart.CartItem{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return touchCart(tx, cartID)
+	})
+	if err != nil {
+		return fmt.Errorf("removing cart item: %w", err)
+	}
+	return nil
+}
+
+func (c *CartDataBaseInteraction) ClearCart(cartID uint) error {
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cart_id = ?", cartID).Delete(&cart.CartItem{}).Error; err != nil {
+			return err
+		}
+		return touchCart(tx, cartID)
+	})
+	if err != nil {
+		return fmt.Errorf("clearing cart: %w", err)
+	}
+	return nil
+}
+
+// touchCart moves the cart's updated_at along with its items
+func touchCart(tx *gorm.DB, cartID uint) error {
+	return tx.Model(&cart.Cart{}).Where("id = ?", cartID).Update("updated_at", time.Now()).Error
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &CartDataBaseInteraction{
+		DB: db,
+	}
+}
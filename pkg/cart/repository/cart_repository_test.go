@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestCartRepository opens an in-memory SQLite database with the cart and product tables
+func newTestCartRepository(tb testing.TB) *CartDataBaseInteraction {
+	tb.Helper()
+	db := testdb.Open(tb, &user.Category{}, &user.Product{}, &user.UserRegister{}, &cart.Cart{}, &cart.CartItem{})
+	return &CartDataBaseInteraction{DB: db}
+}
+
+func TestCartPersistsItems(t *testing.T) {
+	repo := newTestCartRepository(t)
+	shoe := &user.Product{ProductName: "Shoe", Quantity: 5, Price: money.Money{Amount: 1999, Currency: "USD"}, CategoryID: 1}
+	require.NoError(t, repo.DB.Create(shoe).Error)
+
+	// The cart is created once and found again afterwards
+	first, err := repo.GetCart(7)
+	require.NoError(t, err)
+	again, err := repo.GetCart(7)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Empty(t, again.Items)
+
+	require.NoError(t, repo.SaveItem(&cart.CartItem{CartID: first.ID, ProductID: shoe.ID, ProductName: "Shoe", UnitPrice: shoe.Price, Quantity: 1}))
+	// Writing the same product again replaces its line
+	require.NoError(t, repo.SaveItem(&cart.CartItem{CartID: first.ID, ProductID: shoe.ID, ProductName: "Shoe", UnitPrice: shoe.Price, Quantity: 3}))
+
+	loaded, err := repo.GetCart(7)
+	require.NoError(t, err)
+	require.Len(t, loaded.Items, 1)
+	assert.Equal(t, 3, loaded.Items[0].Quantity)
+	assert.Equal(t, "59.97", loaded.Items[0].LineTotal().Decimal())
+	require.NotNil(t, loaded.Items[0].Product)
+	assert.True(t, loaded.Items[0].Available())
+
+	// A deleted product stays in the cart but is no longer available
+	require.NoError(t, repo.DB.Delete(shoe).Error)
+	loaded, err = repo.GetCart(7)
+	require.NoError(t, err)
+	assert.Nil(t, loaded.Items[0].Product)
+	assert.False(t, loaded.Items[0].Available())
+
+	assert.True(t, errors.Is(repo.DeleteItem(first.ID, 99), gorm.ErrRecordNotFound))
+	require.NoError(t, repo.DeleteItem(first.ID, shoe.ID))
+	loaded, err = repo.GetCart(7)
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Items)
+}
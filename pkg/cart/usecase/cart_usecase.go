@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/cart/repository"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrItemNotInCart     = errors.New("product is not in the cart")
+	ErrInsufficientStock = errors.New("not enough stock for the requested quantity")
+	ErrCurrencyMismatch  = errors.New("cart items must all be priced in the same currency")
+)
+
+type CartUseCase interface {
+	GetCart(userID uint) (*cart.Cart, error)
+	AddItem(userID uint, request *cart.AddItemRequest) (*cart.Cart, error)
+	UpdateItem(userID, productID uint, request *cart.UpdateItemRequest) (*cart.Cart, error)
+	RemoveItem(userID, productID uint) (*cart.Cart, error)
+	Clear(userID uint) (*cart.Cart, error)
+}
+
+type cartInteraction struct {
+	cartRepo  repository.CartRepository
+	adminRepo userRepository.AdminRepository
+}
+
+func (c *cartInteraction) GetCart(userID uint) (*cart.Cart, error) {
+	return c.cartRepo.GetCart(userID)
+}
+
+// AddItem puts quantity more of a product into the cart
+func (c *cartInteraction) AddItem(userID uint, request *cart.AddItemRequest) (*cart.Cart, error) {
+	current, err := c.cartRepo.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	quantity := request.Quantity
+	if line := findLine(current, request.ProductID); line != nil {
+		quantity += line.Quantity
+	}
+	return c.writeLine(current, request.ProductID, quantity)
+}
+
+// UpdateItem sets the quantity of a product already in the cart
+func (c *cartInteraction) UpdateItem(userID, productID uint, request *cart.UpdateItemRequest) (*cart.Cart, error) {
+	current, err := c.cartRepo.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if findLine(current, productID) == nil {
+		return nil, ErrItemNotInCart
+	}
+	return c.writeLine(current, productID, request.Quantity)
+}
+
+func (c *cartInteraction) RemoveItem(userID, productID uint) (*cart.Cart, error) {
+	current, err := c.cartRepo.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cartRepo.DeleteItem(current.ID, productID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrItemNotInCart
+		}
+		return nil, err
+	}
+	return c.cartRepo.GetCart(userID)
+}
+
+func (c *cartInteraction) Clear(userID uint) (*cart.Cart, error) {
+	current, err := c.cartRepo.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cartRepo.ClearCart(current.ID); err != nil {
+		return nil, err
+	}
+	return c.cartRepo.GetCart(userID)
+}
+
+// writeLine checks quantity against the product's stock and snapshots its
+// current name and price into the line
+func (c *cartInteraction) writeLine(current *cart.Cart, productID uint, quantity int) (*cart.Cart, error) {
+	product, err := c.adminRepo.FindProduct(productID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	if quantity > product.Quantity {
+		return nil, fmt.Errorf("%w: %d requested, %d in stock", ErrInsufficientStock, quantity, product.Quantity)
+	}
+	if err := checkCurrency(current, product); err != nil {
+		return nil, err
+	}
+
+	item := &cart.CartItem{
+		CartID:      current.ID,
+		ProductID:   product.ID,
+		ProductName: product.ProductName,
+		UnitPrice:   product.Price,
+		Quantity:    quantity,
+	}
+	if err := c.cartRepo.SaveItem(item); err != nil {
+		return nil, err
+	}
+	return c.cartRepo.GetCart(current.UserID)
+}
+
+// checkCurrency keeps a cart in one currency so it always has a single subtotal
+func checkCurrency(current *cart.Cart, product *user.Product) error {
+	for i := range current.Items {
+		line := &current.Items[i]
+		if line.ProductID != product.ID && line.UnitPrice.Currency != product.Price.Currency {
+			return fmt.Errorf("%w: the cart is in %s, %q costs %s", ErrCurrencyMismatch, line.UnitPrice.Currency, product.ProductName, product.Price.Currency)
+		}
+	}
+	return nil
+}
+
+func findLine(current *cart.Cart, productID uint) *cart.CartItem {
+	for i := range current.Items {
+		if current.Items[i].ProductID == productID {
+			return &current.Items[i]
+		}
+	}
+	return nil
+}
+
+func NewCartUseCase(cartRepo repository.CartRepository, adminRepo userRepository.AdminRepository) CartUseCase {
+	return &cartInteraction{
+		cartRepo:  cartRepo,
+		adminRepo: adminRepo,
+	}
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryCartRepository keeps one cart per user in memory for the use case tests
+type memoryCartRepository struct {
+	carts    map[uint]*cart.Cart
+	products map[uint]*user.Product
+}
+
+func (m *memoryCartRepository) GetCart(userID uint) (*cart.Cart, error) {
+	stored, ok := m.carts[userID]
+	if !ok {
+		stored = &cart.Cart{ID: uint(len(m.carts) + 1), UserID: userID}
+		m.carts[userID] = stored
+	}
+	copied := *stored
+	copied.Items = append([]cart.CartItem(nil), stored.Items...)
+	for i := range copied.Items {
+		copied.Items[i].Product = m.products[copied.Items[i].ProductID]
+	}
+	return &copied, nil
+}
+
+func (m *memoryCartRepository) SaveItem(item *cart.CartItem) error {
+	stored := m.cartByID(item.CartID)
+	for i := range stored.Items {
+		if stored.Items[i].ProductID == item.ProductID {
+			stored.Items[i] = *item
+			return nil
+		}
+	}
+	stored.Items = append(stored.Items, *item)
+	return nil
+}
+
+func (m *memoryCartRepository) DeleteItem(cartID, productID uint) error {
+	stored := m.cartByID(cartID)
+	for i := range stored.Items {
+		if stored.Items[i].ProductID == productID {
+			stored.Items = append(stored.Items[:i], stored.Items[i+1:]...)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *memoryCartRepository) ClearCart(cartID uint) error {
+	m.cartByID(cartID).Items = nil
+	return nil
+}
+
+func (m *memoryCartRepository) cartByID(id uint) *cart.Cart {
+	for _, stored := range m.carts {
+		if stored.ID == id {
+			return stored
+		}
+	}
+	return nil
+}
+
+// productStubRepository serves products from a map, the embedded interface panics for anything else
+type productStubRepository struct {
+	userRepository.AdminRepository
+	products map[uint]*user.Product
+}
+
+func (s *productStubRepository) FindProduct(id uint) (*user.Product, error) {
+	product, ok := s.products[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *product
+	return &copied, nil
+}
+
+func TestCartItems(t *testing.T) {
+	products := map[uint]*user.Product{
+		1: {Model: gorm.Model{ID: 1}, ProductName: "Shoe", Quantity: 5, Price: money.Money{Amount: 1999, Currency: "USD"}},
+		2: {Model: gorm.Model{ID: 2}, ProductName: "Sock", Quantity: 10, Price: money.Money{Amount: 250, Currency: "USD"}},
+		3: {Model: gorm.Model{ID: 3}, ProductName: "Sandal", Quantity: 10, Price: money.Money{Amount: 900, Currency: "EUR"}},
+	}
+	carts := NewCartUseCase(&memoryCartRepository{carts: map[uint]*cart.Cart{}, products: products}, &productStubRepository{products: products})
+
+	// Adding a product twice grows its line
+	_, err := carts.AddItem(7, &cart.AddItemRequest{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+	current, err := carts.AddItem(7, &cart.AddItemRequest{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+	require.Len(t, current.Items, 1)
+	assert.Equal(t, 3, current.Items[0].Quantity)
+
+	current, err = carts.AddItem(7, &cart.AddItemRequest{ProductID: 2, Quantity: 4})
+	require.NoError(t, err)
+	subtotal, err := current.Subtotal()
+	require.NoError(t, err)
+	assert.Equal(t, money.Money{Amount: 3*1999 + 4*250, Currency: "USD"}, subtotal)
+
+	// Quantities are checked against stock, including what is already in the cart
+	_, err = carts.AddItem(7, &cart.AddItemRequest{ProductID: 1, Quantity: 3})
+	assert.True(t, errors.Is(err, ErrInsufficientStock))
+	_, err = carts.UpdateItem(7, 1, &cart.UpdateItemRequest{Quantity: 6})
+	assert.True(t, errors.Is(err, ErrInsufficientStock))
+
+	_, err = carts.AddItem(7, &cart.AddItemRequest{ProductID: 3, Quantity: 1})
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	_, err = carts.AddItem(7, &cart.AddItemRequest{ProductID: 9, Quantity: 1})
+	assert.True(t, errors.Is(err, ErrProductNotFound))
+	_, err = carts.UpdateItem(7, 3, &cart.UpdateItemRequest{Quantity: 1})
+	assert.True(t, errors.Is(err, ErrItemNotInCart))
+
+	// The line keeps the price it was written at until it is written again
+	products[1].Price = money.Money{Amount: 2499, Currency: "USD"}
+	current, err = carts.GetCart(7)
+	require.NoError(t, err)
+	assert.True(t, current.Items[0].PriceChanged())
+	current, err = carts.UpdateItem(7, 1, &cart.UpdateItemRequest{Quantity: 1})
+	require.NoError(t, err)
+	assert.False(t, current.Items[0].PriceChanged())
+	assert.Equal(t, int64(2499), current.Items[0].UnitPrice.Amount)
+
+	current, err = carts.RemoveItem(7, 2)
+	require.NoError(t, err)
+	assert.Len(t, current.Items, 1)
+	_, err = carts.RemoveItem(7, 2)
+	assert.True(t, errors.Is(err, ErrItemNotInCart))
+
+	current, err = carts.Clear(7)
+	require.NoError(t, err)
+	assert.Empty(t, current.Items)
+}
@@ -0,0 +1,160 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/coupon/usecase"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	userDelivery "github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type CouponHandler struct {
+	couponUseCase  usecase.CouponUseCase
+	pricingUseCase usecase.PricingUseCase
+}
+
+type CouponUseCases interface {
+	CreateCouponHandler(c *gin.Context)
+	ListCouponsHandler(c *gin.Context)
+	GetCouponHandler(c *gin.Context)
+	UpdateCouponHandler(c *gin.Context)
+	DeleteCouponHandler(c *gin.Context)
+	QuoteCartHandler(c *gin.Context)
+}
+
+func (h *CouponHandler) CreateCouponHandler(c *gin.Context) {
+	var request coupon.CouponRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	created, err := h.couponUseCase.CreateCoupon(&request)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	c.JSON(201, NewCouponResponse(created))
+}
+
+func (h *CouponHandler) ListCouponsHandler(c *gin.Context) {
+	var query coupon.CouponListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	coupons, total, err := h.couponUseCase.ListCoupons(&query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list coupons"})
+		return
+	}
+	c.JSON(200, userDelivery.NewPageResponse(c.Request.URL, NewCouponListResponse(coupons), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+func (h *CouponHandler) GetCouponHandler(c *gin.Context) {
+	id, ok := couponID(c)
+	if !ok {
+		return
+	}
+	found, err := h.couponUseCase.GetCoupon(id)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	c.JSON(200, NewCouponResponse(found))
+}
+
+// UpdateCouponHandler replaces every setting of a coupon
+func (h *CouponHandler) UpdateCouponHandler(c *gin.Context) {
+	id, ok := couponID(c)
+	if !ok {
+		return
+	}
+	var request coupon.CouponRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := h.couponUseCase.UpdateCoupon(id, &request)
+	if err != nil {
+		couponError(c, err)
+		return
+	}
+	c.JSON(200, NewCouponResponse(updated))
+}
+
+func (h *CouponHandler) DeleteCouponHandler(c *gin.Context) {
+	id, ok := couponID(c)
+	if !ok {
+		return
+	}
+	if err := h.couponUseCase.DeleteCoupon(id); err != nil {
+		couponError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "coupon deleted successfully"})
+}
+
+// QuoteCartHandler prices the caller's cart with the breakdown checkout would charge
+func (h *CouponHandler) QuoteCartHandler(c *gin.Context) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only users have a cart"})
+		return
+	}
+	var request coupon.QuoteRequest
+	//**An empty body quotes the cart without a coupon
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	breakdown, err := h.pricingUseCase.QuoteCart(caller.ID, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, coupon.ErrCouponRejected):
+			c.JSON(422, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrEmptyCart):
+			c.JSON(400, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to price the cart"})
+		}
+		return
+	}
+	c.JSON(200, breakdown)
+}
+
+// couponError maps the coupon use case errors to responses
+func couponError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCouponNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidCoupon), errors.Is(err, usecase.ErrTargetNotFound):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCodeTaken):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "failed to save coupon"})
+	}
+}
+
+func couponID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid coupon ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func NewCouponHandler(couponUseCase usecase.CouponUseCase, pricingUseCase usecase.PricingUseCase) *CouponHandler {
+	return &CouponHandler{
+		couponUseCase:  couponUseCase,
+		pricingUseCase: pricingUseCase,
+	}
+}
@@ -0,0 +1,142 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/coupon/usecase"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pricing"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockCouponUseCase is a mock implementation of the CouponUseCase and PricingUseCase interfaces
+type MockCouponUseCase struct {
+	mock.Mock
+}
+
+func (m *MockCouponUseCase) CreateCoupon(request *coupon.CouponRequest) (*coupon.Coupon, error) {
+	args := m.Called(request)
+	return args.Get(0).(*coupon.Coupon), args.Error(1)
+}
+
+func (m *MockCouponUseCase) UpdateCoupon(id uint, request *coupon.CouponRequest) (*coupon.Coupon, error) {
+	args := m.Called(id, request)
+	return args.Get(0).(*coupon.Coupon), args.Error(1)
+}
+
+func (m *MockCouponUseCase) GetCoupon(id uint) (*coupon.Coupon, error) {
+	args := m.Called(id)
+	return args.Get(0).(*coupon.Coupon), args.Error(1)
+}
+
+func (m *MockCouponUseCase) ListCoupons(query *coupon.CouponListQuery) ([]coupon.Coupon, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]coupon.Coupon), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCouponUseCase) DeleteCoupon(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockCouponUseCase) QuoteCart(userID uint, request *coupon.QuoteRequest) (*pricing.Breakdown, error) {
+	args := m.Called(userID, request)
+	return args.Get(0).(*pricing.Breakdown), args.Error(1)
+}
+
+func TestCreateCouponHandler(t *testing.T) {
+	mockCouponUseCase := new(MockCouponUseCase)
+	handler := NewCouponHandler(mockCouponUseCase, mockCouponUseCase)
+
+	r := gin.Default()
+	r.POST("/admin/coupons", handler.CreateCouponHandler)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockCouponUseCase.On("CreateCoupon", &coupon.CouponRequest{Code: "save10", Kind: coupon.KindPercentage, PercentOff: 10, MaxPerUser: 1}).
+		Return(&coupon.Coupon{ID: 1, Code: "SAVE10", Kind: coupon.KindPercentage, PercentOff: 10, MaxPerUser: 1, Active: true,
+			Targets: []coupon.CouponTarget{}, CreatedAt: created, UpdatedAt: created}, nil)
+	mockCouponUseCase.On("CreateCoupon", &coupon.CouponRequest{Code: "save10", Kind: coupon.KindPercentage, PercentOff: 200}).
+		Return((*coupon.Coupon)(nil), fmt.Errorf("%w: percentage coupons take percent_off between 1 and 100 and no amount_off", usecase.ErrInvalidCoupon))
+
+	req, _ := http.NewRequest("POST", "/admin/coupons", bytes.NewBufferString(`{"code":"save10","kind":"percentage","percent_off":10,"max_per_user":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{
+		"id":1,"code":"SAVE10","description":"","kind":"percentage","percent_off":10,
+		"amount_off":null,"min_order":null,
+		"max_redemptions":0,"max_per_user":1,"starts_at":null,"ends_at":null,"active":true,"targets":[],
+		"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/admin/coupons", bytes.NewBufferString(`{"code":"save10","kind":"percentage","percent_off":200}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Unknown kinds and target kinds are refused before the use case runs
+	req, _ = http.NewRequest("POST", "/admin/coupons", bytes.NewBufferString(`{"code":"x","kind":"bogo"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	req, _ = http.NewRequest("POST", "/admin/coupons", bytes.NewBufferString(`{"code":"x","kind":"fixed","targets":[{"kind":"brand","id":1}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockCouponUseCase.AssertExpectations(t)
+}
+
+func TestQuoteCartHandler(t *testing.T) {
+	mockCouponUseCase := new(MockCouponUseCase)
+	handler := NewCouponHandler(mockCouponUseCase, mockCouponUseCase)
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	r := gin.Default()
+	r.POST("/cart/quote", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.QuoteCartHandler)
+
+	usd := func(amount int64) money.Money { return money.Money{Amount: amount, Currency: "USD"} }
+	mockCouponUseCase.On("QuoteCart", uint(7), &coupon.QuoteRequest{CouponCode: "SAVE10"}).Return(&pricing.Breakdown{
+		Lines:    []pricing.LineBreakdown{{ProductID: 1, ProductName: "Shoe", UnitPrice: usd(2000), Quantity: 1, Subtotal: usd(2000), Discount: usd(200), Total: usd(1800)}},
+		Subtotal: usd(2000), Discount: usd(200), Total: usd(1800),
+		Coupon: &pricing.AppliedCoupon{Code: "SAVE10", Kind: coupon.KindPercentage, PercentOff: 10},
+	}, nil)
+	mockCouponUseCase.On("QuoteCart", uint(7), &coupon.QuoteRequest{CouponCode: "OLD"}).
+		Return((*pricing.Breakdown)(nil), fmt.Errorf("%w: %w", coupon.ErrCouponRejected, coupon.ErrCouponInactive))
+
+	req, _ := http.NewRequest("POST", "/cart/quote", bytes.NewBufferString(`{"coupon_code":"SAVE10"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"lines":[{"product_id":1,"product_name":"Shoe","unit_price":{"amount":"20.00","currency":"USD"},"quantity":1,
+			"subtotal":{"amount":"20.00","currency":"USD"},"discount":{"amount":"2.00","currency":"USD"},"total":{"amount":"18.00","currency":"USD"}}],
+		"subtotal":{"amount":"20.00","currency":"USD"},"discount":{"amount":"2.00","currency":"USD"},"total":{"amount":"18.00","currency":"USD"},
+		"coupon":{"code":"SAVE10","kind":"percentage","percent_off":10}
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/cart/quote", bytes.NewBufferString(`{"coupon_code":"OLD"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error":"coupon cannot be used: coupon is not active"}`, w.Body.String())
+
+	mockCouponUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package delivery
+
+import (
+	"time"
+
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+// CouponResponse is a coupon as admins manage it, amount_off and min_order are
+// null when the coupon has no fixed discount or no minimum
+type CouponResponse struct {
+	ID             uint                   `json:"id"`
+	Code           string                 `json:"code"`
+	Description    string                 `json:"description"`
+	Kind           string                 `json:"kind"`
+	PercentOff     int                    `json:"percent_off"`
+	AmountOff      *money.Money           `json:"amount_off"`
+	MinOrder       *money.Money           `json:"min_order"`
+	MaxRedemptions int                    `json:"max_redemptions"`
+	MaxPerUser     int                    `json:"max_per_user"`
+	StartsAt       *time.Time             `json:"starts_at"`
+	EndsAt         *time.Time             `json:"ends_at"`
+	Active         bool                   `json:"active"`
+	Targets        []CouponTargetResponse `json:"targets"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+type CouponTargetResponse struct {
+	Kind string `json:"kind"`
+	ID   uint   `json:"id"`
+}
+
+func NewCouponResponse(c *coupon.Coupon) CouponResponse {
+	response := CouponResponse{
+		ID:             c.ID,
+		Code:           c.Code,
+		Description:    c.Description,
+		Kind:           c.Kind,
+		PercentOff:     c.PercentOff,
+		AmountOff:      optionalMoney(c.AmountOff),
+		MinOrder:       optionalMoney(c.MinOrder),
+		MaxRedemptions: c.MaxRedemptions,
+		MaxPerUser:     c.MaxPerUser,
+		StartsAt:       c.StartsAt,
+		EndsAt:         c.EndsAt,
+		Active:         c.Active,
+		Targets:        make([]CouponTargetResponse, 0, len(c.Targets)),
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+	for _, target := range c.Targets {
+		response.Targets = append(response.Targets, CouponTargetResponse{Kind: target.Kind, ID: target.TargetID})
+	}
+	return response
+}
+
+func NewCouponListResponse(coupons []coupon.Coupon) []CouponResponse {
+	responses := make([]CouponResponse, 0, len(coupons))
+	for i := range coupons {
+		responses = append(responses, NewCouponResponse(&coupons[i]))
+	}
+	return responses
+}
+
+func optionalMoney(m money.Money) *money.Money {
+	if m.Amount == 0 {
+		return nil
+	}
+	return &m
+}
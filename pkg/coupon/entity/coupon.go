@@ -0,0 +1,147 @@
+package coupon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	"gorm.io/gorm"
+)
+
+// Kinds of discount a coupon gives
+const (
+	KindPercentage = "percentage"
+	KindFixed      = "fixed"
+)
+
+// Kinds of what a coupon can be scoped to
+const (
+	TargetProduct  = "product"
+	TargetCategory = "category"
+)
+
+var (
+	ErrCouponRejected  = errors.New("coupon cannot be used")
+	ErrUnknownCode     = errors.New("coupon code does not exist")
+	ErrCouponInactive  = errors.New("coupon is not active")
+	ErrCouponExhausted = errors.New("coupon has been used up")
+	ErrCouponUserLimit = errors.New("coupon was already used the allowed number of times")
+)
+
+// NormalizeCode makes codes case insensitive, they are stored upper case
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Coupon is a promo code. PercentOff applies to percentage coupons and
+// AmountOff to fixed ones. Without targets it applies to every product,
+// MinOrder, MaxRedemptions and MaxPerUser are off while zero
+type Coupon struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	Code           string         `gorm:"type:varchar(64);not null;uniqueIndex" json:"code"`
+	Description    string         `gorm:"type:text" json:"description"`
+	Kind           string         `gorm:"type:varchar(16);not null" json:"kind"`
+	PercentOff     int            `gorm:"not null;default:0" json:"percent_off"`
+	AmountOff      money.Money    `gorm:"embedded;embeddedPrefix:amount_off_" json:"amount_off"`
+	MinOrder       money.Money    `gorm:"embedded;embeddedPrefix:min_order_" json:"min_order"`
+	MaxRedemptions int            `gorm:"not null;default:0" json:"max_redemptions"`
+	MaxPerUser     int            `gorm:"not null;default:0" json:"max_per_user"`
+	StartsAt       *time.Time     `json:"starts_at"`
+	EndsAt         *time.Time     `json:"ends_at"`
+	Active         bool           `gorm:"not null;default:true" json:"active"`
+	Targets        []CouponTarget `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"targets"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// CouponTarget scopes a coupon to a product or to every product of a category.
+// Products and categories get no foreign keys so the trash can still purge them
+type CouponTarget struct {
+	ID       uint   `gorm:"primarykey" json:"-"`
+	CouponID uint   `gorm:"not null;index" json:"-"`
+	Kind     string `gorm:"type:varchar(16);not null" json:"kind" binding:"oneof=product category"`
+	TargetID uint   `gorm:"not null" json:"id" binding:"required"`
+}
+
+// Redemption records that a coupon paid for part of an order, it counts
+// against the coupon's limits until the order is cancelled
+type Redemption struct {
+	ID        uint        `gorm:"primarykey"`
+	CouponID  uint        `gorm:"not null;index"`
+	UserID    uint        `gorm:"not null;index"`
+	OrderID   uint        `gorm:"not null;uniqueIndex"`
+	Discount  money.Money `gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt time.Time
+}
+
+// CheckActive reports whether the coupon can be used at now
+func (c *Coupon) CheckActive(now time.Time) error {
+	switch {
+	case !c.Active:
+		return ErrCouponInactive
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return fmt.Errorf("%w: valid from %s", ErrCouponInactive, c.StartsAt.Format(time.RFC3339))
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return fmt.Errorf("%w: expired at %s", ErrCouponInactive, c.EndsAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// CheckRedeemable reports whether the coupon is active and within its limits,
+// given how often it was redeemed in total and by the user about to use it
+func (c *Coupon) CheckRedeemable(now time.Time, redeemed, redeemedByUser int64) error {
+	if err := c.CheckActive(now); err != nil {
+		return err
+	}
+	if c.MaxRedemptions > 0 && redeemed >= int64(c.MaxRedemptions) {
+		return ErrCouponExhausted
+	}
+	if c.MaxPerUser > 0 && redeemedByUser >= int64(c.MaxPerUser) {
+		return ErrCouponUserLimit
+	}
+	return nil
+}
+
+// Covers reports whether the coupon applies to a product in categoryID
+func (c *Coupon) Covers(productID, categoryID uint) bool {
+	if len(c.Targets) == 0 {
+		return true
+	}
+	for _, target := range c.Targets {
+		if (target.Kind == TargetProduct && target.TargetID == productID) ||
+			(target.Kind == TargetCategory && target.TargetID == categoryID) {
+			return true
+		}
+	}
+	return false
+}
+
+// CouponRequest creates a coupon or replaces all of its settings
+type CouponRequest struct {
+	Code           string         `json:"code" binding:"required,max=64"`
+	Description    string         `json:"description"`
+	Kind           string         `json:"kind" binding:"required,oneof=percentage fixed"`
+	PercentOff     int            `json:"percent_off"`
+	AmountOff      *money.Money   `json:"amount_off"`
+	MinOrder       *money.Money   `json:"min_order"`
+	MaxRedemptions int            `json:"max_redemptions" binding:"min=0"`
+	MaxPerUser     int            `json:"max_per_user" binding:"min=0"`
+	StartsAt       *time.Time     `json:"starts_at"`
+	EndsAt         *time.Time     `json:"ends_at"`
+	Active         *bool          `json:"active"`
+	Targets        []CouponTarget `json:"targets" binding:"omitempty,dive"`
+}
+
+// CouponListQuery pages through coupons, newest first
+type CouponListQuery struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+// QuoteRequest prices the caller's cart, with a coupon when CouponCode is set
+type QuoteRequest struct {
+	CouponCode string `json:"coupon_code"`
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"fmt"
+
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	CreateCoupon(c *coupon.Coupon) error
+	UpdateCoupon(c *coupon.Coupon) error
+	GetCoupon(id uint) (*coupon.Coupon, error)
+	FindByCode(code string) (*coupon.Coupon, error)
+	CodeTaken(code string, exceptID uint) (bool, error)
+	LockByCode(code string) (*coupon.Coupon, error)
+	ListCoupons(page, limit int) ([]coupon.Coupon, int64, error)
+	DeleteCoupon(id uint) error
+	CountRedemptions(couponID, userID uint) (int64, int64, error)
+	CreateRedemption(redemption *coupon.Redemption) error
+	DeleteOrderRedemption(orderID uint) error
+}
+
+type CouponDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (r *CouponDataBaseInteraction) CreateCoupon(c *coupon.Coupon) error {
+	if err := r.DB.Create(c).Error; err != nil {
+		return fmt.Errorf("creating coupon: %w", err)
+	}
+	return nil
+}
+
+// UpdateCoupon saves c and replaces its targets with c.Targets
+func (r *CouponDataBaseInteraction) UpdateCoupon(c *coupon.Coupon) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Targets").Save(c).Error; err != nil {
+			return fmt.Errorf("updating coupon: %w", err)
+		}
+		if err := tx.Where("coupon_id = ?", c.ID).Delete(&coupon.CouponTarget{}).Error; err != nil {
+			return fmt.Errorf("clearing coupon targets: %w", err)
+		}
+		for i := range c.Targets {
+			c.Targets[i].ID, c.Targets[i].CouponID = 0, c.ID
+		}
+		if len(c.Targets) > 0 {
+			if err := tx.Create(&c.Targets).Error; err != nil {
+				return fmt.Errorf("saving coupon targets: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *CouponDataBaseInteraction) GetCoupon(id uint) (*coupon.Coupon, error) {
+	var found coupon.Coupon
+	if err := r.DB.Preload("Targets").First(&found, id).Error; err != nil {
+		return nil, fmt.Errorf("finding coupon %d: %w", id, err)
+	}
+	return &found, nil
+}
+
+func (r *CouponDataBaseInteraction) FindByCode(code string) (*coupon.Coupon, error) {
+	var found coupon.Coupon
+	if err := r.DB.Preload("Targets").Where("code = ?", code).First(&found).Error; err != nil {
+		return nil, fmt.Errorf("finding coupon %s: %w", code, err)
+	}
+	return &found, nil
+}
+
+// CodeTaken reports whether another coupon, deleted ones included, uses code
+func (r *CouponDataBaseInteraction) CodeTaken(code string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.DB.Unscoped().Model(&coupon.Coupon{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("checking coupon code: %w", err)
+	}
+	return count > 0, nil
+}
+
+// LockByCode finds the coupon and holds its row until the surrounding
+// transaction ends, so concurrent checkouts cannot both take its last use
+func (r *CouponDataBaseInteraction) LockByCode(code string) (*coupon.Coupon, error) {
+	var found coupon.Coupon
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Targets").Where("code = ?", code).First(&found).Error
+	if err != nil {
+		return nil, fmt.Errorf("locking coupon %s: %w", code, err)
+	}
+	return &found, nil
+}
+
+func (r *CouponDataBaseInteraction) ListCoupons(page, limit int) ([]coupon.Coupon, int64, error) {
+	scope := r.DB.Model(&coupon.Coupon{})
+
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting coupons: %w", err)
+	}
+	var coupons []coupon.Coupon
+	err := scope.Preload("Targets").Order("created_at DESC, id DESC").Limit(limit).Offset(pagination.Offset(page, limit)).Find(&coupons).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing coupons: %w", err)
+	}
+	return coupons, total, nil
+}
+
+// DeleteCoupon soft deletes the coupon, its redemptions stay with their orders
+func (r *CouponDataBaseInteraction) DeleteCoupon(id uint) error {
+	result := r.DB.Delete(&coupon.Coupon{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("deleting coupon %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("deleting coupon %d: %w", id, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// CountRedemptions returns how often the coupon was redeemed in total and by userID
+func (r *CouponDataBaseInteraction) CountRedemptions(couponID, userID uint) (int64, int64, error) {
+	var counts struct {
+		Total  int64
+		ByUser int64
+	}
+	err := r.DB.Model(&coupon.Redemption{}).Where("coupon_id = ?", couponID).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN user_id = ? THEN 1 ELSE 0 END), 0) AS by_user", userID).
+		Scan(&counts).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("counting coupon redemptions: %w", err)
+	}
+	return counts.Total, counts.ByUser, nil
+}
+
+func (r *CouponDataBaseInteraction) CreateRedemption(redemption *coupon.Redemption) error {
+	if err := r.DB.Create(redemption).Error; err != nil {
+		return fmt.Errorf("recording coupon redemption: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrderRedemption gives the coupon use of a cancelled order back
+func (r *CouponDataBaseInteraction) DeleteOrderRedemption(orderID uint) error {
+	if err := r.DB.Where("order_id = ?", orderID).Delete(&coupon.Redemption{}).Error; err != nil {
+		return fmt.Errorf("releasing coupon redemption: %w", err)
+	}
+	return nil
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &CouponDataBaseInteraction{
+		DB: db,
+	}
+}
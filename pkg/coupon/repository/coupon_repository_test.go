@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestCouponRepository opens an in-memory SQLite database with the coupon tables
+func newTestCouponRepository(tb testing.TB) *CouponDataBaseInteraction {
+	tb.Helper()
+	db := testdb.Open(tb, &coupon.Coupon{}, &coupon.CouponTarget{}, &coupon.Redemption{})
+	return &CouponDataBaseInteraction{DB: db}
+}
+
+func TestUpdateCouponReplacesTargets(t *testing.T) {
+	repo := newTestCouponRepository(t)
+	created := &coupon.Coupon{Code: "SAVE10", Kind: coupon.KindPercentage, PercentOff: 10, Active: true,
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetProduct, TargetID: 1}, {Kind: coupon.TargetCategory, TargetID: 2}}}
+	require.NoError(t, repo.CreateCoupon(created))
+
+	created.PercentOff = 20
+	created.Targets = []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: 3}}
+	require.NoError(t, repo.UpdateCoupon(created))
+
+	found, err := repo.FindByCode("SAVE10")
+	require.NoError(t, err)
+	assert.Equal(t, 20, found.PercentOff)
+	require.Len(t, found.Targets, 1)
+	assert.Equal(t, uint(3), found.Targets[0].TargetID)
+	var targets int64
+	require.NoError(t, repo.DB.Model(&coupon.CouponTarget{}).Count(&targets).Error)
+	assert.EqualValues(t, 1, targets)
+
+	// Deleted coupons keep their code
+	require.NoError(t, repo.DeleteCoupon(created.ID))
+	_, err = repo.FindByCode("SAVE10")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	taken, err := repo.CodeTaken("SAVE10", 0)
+	require.NoError(t, err)
+	assert.True(t, taken)
+	assert.ErrorIs(t, repo.DeleteCoupon(created.ID), gorm.ErrRecordNotFound)
+}
+
+func TestCountRedemptions(t *testing.T) {
+	repo := newTestCouponRepository(t)
+	off := money.Money{Amount: 500, Currency: "USD"}
+	for orderID, userID := range []uint{7, 7, 8} {
+		require.NoError(t, repo.CreateRedemption(&coupon.Redemption{CouponID: 1, UserID: userID, OrderID: uint(orderID + 1), Discount: off}))
+	}
+	require.NoError(t, repo.CreateRedemption(&coupon.Redemption{CouponID: 2, UserID: 7, OrderID: 4, Discount: off}))
+
+	total, byUser, err := repo.CountRedemptions(1, 7)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	assert.EqualValues(t, 2, byUser)
+
+	require.NoError(t, repo.DeleteOrderRedemption(1))
+	total, byUser, err = repo.CountRedemptions(1, 7)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.EqualValues(t, 1, byUser)
+
+	total, byUser, err = repo.CountRedemptions(9, 7)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Zero(t, byUser)
+}
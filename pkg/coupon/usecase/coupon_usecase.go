@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/coupon/repository"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCouponNotFound = errors.New("coupon not found")
+	ErrInvalidCoupon  = errors.New("invalid coupon")
+	ErrCodeTaken      = errors.New("coupon code is already in use")
+	ErrTargetNotFound = errors.New("coupon target does not exist")
+)
+
+// codePattern keeps codes easy to type and safe in URLs
+var codePattern = regexp.MustCompile(`^[A-Z0-9_-]+$`)
+
+type CouponUseCase interface {
+	CreateCoupon(request *coupon.CouponRequest) (*coupon.Coupon, error)
+	UpdateCoupon(id uint, request *coupon.CouponRequest) (*coupon.Coupon, error)
+	GetCoupon(id uint) (*coupon.Coupon, error)
+	ListCoupons(query *coupon.CouponListQuery) ([]coupon.Coupon, int64, error)
+	DeleteCoupon(id uint) error
+}
+
+type couponInteraction struct {
+	couponRepo   repository.CouponRepository
+	adminRepo    userRepository.AdminRepository
+	categoryRepo userRepository.CategoryRepository
+}
+
+func (c *couponInteraction) CreateCoupon(request *coupon.CouponRequest) (*coupon.Coupon, error) {
+	created := &coupon.Coupon{Active: true}
+	if err := c.fill(created, request); err != nil {
+		return nil, err
+	}
+	if err := c.couponRepo.CreateCoupon(created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateCoupon replaces every setting of the coupon, redemptions so far still count
+func (c *couponInteraction) UpdateCoupon(id uint, request *coupon.CouponRequest) (*coupon.Coupon, error) {
+	existing, err := c.GetCoupon(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.fill(existing, request); err != nil {
+		return nil, err
+	}
+	if err := c.couponRepo.UpdateCoupon(existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (c *couponInteraction) GetCoupon(id uint) (*coupon.Coupon, error) {
+	found, err := c.couponRepo.GetCoupon(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCouponNotFound
+	}
+	return found, err
+}
+
+func (c *couponInteraction) ListCoupons(query *coupon.CouponListQuery) ([]coupon.Coupon, int64, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	return c.couponRepo.ListCoupons(query.Page, query.Limit)
+}
+
+func (c *couponInteraction) DeleteCoupon(id uint) error {
+	err := c.couponRepo.DeleteCoupon(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCouponNotFound
+	}
+	return err
+}
+
+// fill validates request and copies it onto target
+func (c *couponInteraction) fill(target *coupon.Coupon, request *coupon.CouponRequest) error {
+	code := coupon.NormalizeCode(request.Code)
+	if !codePattern.MatchString(code) {
+		return fmt.Errorf("%w: code may only hold letters, digits, - and _", ErrInvalidCoupon)
+	}
+	taken, err := c.couponRepo.CodeTaken(code, target.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrCodeTaken
+	}
+
+	target.Code, target.Description, target.Kind = code, request.Description, request.Kind
+	target.PercentOff, target.AmountOff, target.MinOrder = 0, money.Money{}, money.Money{}
+	switch request.Kind {
+	case coupon.KindPercentage:
+		if request.PercentOff < 1 || request.PercentOff > 100 || request.AmountOff != nil {
+			return fmt.Errorf("%w: percentage coupons take percent_off between 1 and 100 and no amount_off", ErrInvalidCoupon)
+		}
+		target.PercentOff = request.PercentOff
+	case coupon.KindFixed:
+		if request.AmountOff == nil || request.PercentOff != 0 {
+			return fmt.Errorf("%w: fixed coupons take amount_off and no percent_off", ErrInvalidCoupon)
+		}
+		if err := request.AmountOff.Validate(); err != nil || request.AmountOff.Amount == 0 {
+			return fmt.Errorf("%w: amount_off must be a positive amount in a supported currency", ErrInvalidCoupon)
+		}
+		target.AmountOff = *request.AmountOff
+	default:
+		return fmt.Errorf("%w: kind must be percentage or fixed", ErrInvalidCoupon)
+	}
+	if request.MinOrder != nil {
+		if err := request.MinOrder.Validate(); err != nil {
+			return fmt.Errorf("%w: min_order: %v", ErrInvalidCoupon, err)
+		}
+		target.MinOrder = *request.MinOrder
+	}
+
+	if request.StartsAt != nil && request.EndsAt != nil && !request.EndsAt.After(*request.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	}
+	target.StartsAt, target.EndsAt = request.StartsAt, request.EndsAt
+	target.MaxRedemptions, target.MaxPerUser = request.MaxRedemptions, request.MaxPerUser
+	if request.Active != nil {
+		target.Active = *request.Active
+	}
+
+	for _, scope := range request.Targets {
+		if err := c.checkTarget(scope); err != nil {
+			return err
+		}
+	}
+	target.Targets = request.Targets
+	return nil
+}
+
+func (c *couponInteraction) checkTarget(scope coupon.CouponTarget) error {
+	var err error
+	switch scope.Kind {
+	case coupon.TargetProduct:
+		_, err = c.adminRepo.FindProduct(scope.TargetID)
+	case coupon.TargetCategory:
+		_, err = c.categoryRepo.GetCategory(scope.TargetID)
+	default:
+		return fmt.Errorf("%w: target kind must be product or category", ErrInvalidCoupon)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s %d", ErrTargetNotFound, scope.Kind, scope.TargetID)
+	}
+	return err
+}
+
+func NewCouponUseCase(couponRepo repository.CouponRepository, adminRepo userRepository.AdminRepository, categoryRepo userRepository.CategoryRepository) CouponUseCase {
+	return &couponInteraction{
+		couponRepo:   couponRepo,
+		adminRepo:    adminRepo,
+		categoryRepo: categoryRepo,
+	}
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	cartRepository "github.com/ratheeshkumar25/pkg/cart/repository"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/coupon/repository"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pricing"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// catalogStub knows a fixed set of products and categories
+type catalogStub struct {
+	userRepository.AdminRepository
+	userRepository.CategoryRepository
+	products   map[uint]bool
+	categories map[uint]bool
+	children   map[uint][]uint
+}
+
+func (s *catalogStub) FindProduct(id uint) (*user.Product, error) {
+	if !s.products[id] {
+		return nil, fmt.Errorf("unable to find product by ID:%w", gorm.ErrRecordNotFound)
+	}
+	return &user.Product{Model: gorm.Model{ID: id}}, nil
+}
+
+func (s *catalogStub) GetCategory(id uint) (*user.Category, error) {
+	if !s.categories[id] {
+		return nil, fmt.Errorf("getting category: %w", gorm.ErrRecordNotFound)
+	}
+	return &user.Category{Model: gorm.Model{ID: id}}, nil
+}
+
+func (s *catalogStub) SubtreeIDs(id uint) ([]uint, error) {
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, s.children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// cartStub hands out one fixed cart
+type cartStub struct {
+	cartRepository.CartRepository
+	current *cart.Cart
+}
+
+func (s *cartStub) GetCart(userID uint) (*cart.Cart, error) {
+	return s.current, nil
+}
+
+func newTestCouponRepository(tb testing.TB) repository.CouponRepository {
+	tb.Helper()
+	db := testdb.Open(tb, &coupon.Coupon{}, &coupon.CouponTarget{}, &coupon.Redemption{})
+	return repository.NewCouponRepository(db)
+}
+
+func usd(amount int64) *money.Money {
+	return &money.Money{Amount: amount, Currency: "USD"}
+}
+
+func TestCouponValidation(t *testing.T) {
+	catalog := &catalogStub{products: map[uint]bool{1: true}, categories: map[uint]bool{10: true}}
+	coupons := NewCouponUseCase(newTestCouponRepository(t), catalog, catalog)
+
+	created, err := coupons.CreateCoupon(&coupon.CouponRequest{Code: " save10 ", Kind: coupon.KindPercentage, PercentOff: 10,
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetProduct, TargetID: 1}, {Kind: coupon.TargetCategory, TargetID: 10}}})
+	require.NoError(t, err)
+	assert.Equal(t, "SAVE10", created.Code)
+	assert.True(t, created.Active)
+
+	_, err = coupons.CreateCoupon(&coupon.CouponRequest{Code: "Save10", Kind: coupon.KindFixed, AmountOff: usd(500)})
+	assert.ErrorIs(t, err, ErrCodeTaken)
+
+	starts := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.Add(-time.Hour)
+	for name, request := range map[string]*coupon.CouponRequest{
+		"percent too high":   {Code: "A", Kind: coupon.KindPercentage, PercentOff: 101},
+		"percent and amount": {Code: "A", Kind: coupon.KindPercentage, PercentOff: 10, AmountOff: usd(100)},
+		"fixed without":      {Code: "A", Kind: coupon.KindFixed},
+		"zero amount":        {Code: "A", Kind: coupon.KindFixed, AmountOff: usd(0)},
+		"unknown currency":   {Code: "A", Kind: coupon.KindFixed, AmountOff: &money.Money{Amount: 100, Currency: "XYZ"}},
+		"window backwards":   {Code: "A", Kind: coupon.KindFixed, AmountOff: usd(100), StartsAt: &starts, EndsAt: &ends},
+		"spaces in code":     {Code: "TEN OFF", Kind: coupon.KindFixed, AmountOff: usd(100)},
+	} {
+		_, err := coupons.CreateCoupon(request)
+		assert.ErrorIs(t, err, ErrInvalidCoupon, name)
+	}
+
+	_, err = coupons.CreateCoupon(&coupon.CouponRequest{Code: "GHOST", Kind: coupon.KindFixed, AmountOff: usd(100),
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: 99}}})
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+
+	// Updating keeps the code free for the coupon itself
+	inactive := false
+	updated, err := coupons.UpdateCoupon(created.ID, &coupon.CouponRequest{Code: "SAVE10", Kind: coupon.KindFixed, AmountOff: usd(300), Active: &inactive})
+	require.NoError(t, err)
+	assert.Equal(t, 0, updated.PercentOff)
+	assert.False(t, updated.Active)
+	assert.Empty(t, updated.Targets)
+
+	_, err = coupons.GetCoupon(99)
+	assert.ErrorIs(t, err, ErrCouponNotFound)
+}
+
+func TestQuoteCart(t *testing.T) {
+	couponRepo := newTestCouponRepository(t)
+	carts := &cartStub{current: &cart.Cart{ID: 1, UserID: 7}}
+	quotes := NewPricingUseCase(couponRepo, carts, &catalogStub{})
+
+	_, err := quotes.QuoteCart(7, &coupon.QuoteRequest{})
+	assert.ErrorIs(t, err, ErrEmptyCart)
+
+	carts.current.Items = []cart.CartItem{
+		{ProductID: 1, ProductName: "Shoe", UnitPrice: *usd(2000), Quantity: 2, Product: &user.Product{CategoryID: 10}},
+		{ProductID: 2, ProductName: "Sock", UnitPrice: *usd(500), Quantity: 1, Product: &user.Product{CategoryID: 20}},
+	}
+	breakdown, err := quotes.QuoteCart(7, &coupon.QuoteRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "45.00", breakdown.Total.Decimal())
+
+	require.NoError(t, couponRepo.CreateCoupon(&coupon.Coupon{Code: "SHOES", Kind: coupon.KindPercentage, PercentOff: 25, Active: true, MaxPerUser: 1,
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: 10}}}))
+	breakdown, err = quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "shoes"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.00", breakdown.Discount.Decimal())
+	assert.Equal(t, "35.00", breakdown.Total.Decimal())
+	assert.Equal(t, "0.00", breakdown.Lines[1].Discount.Decimal())
+	assert.Equal(t, &pricing.AppliedCoupon{Code: "SHOES", Kind: coupon.KindPercentage, PercentOff: 25}, breakdown.Coupon)
+
+	// Rejections say why
+	_, err = quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "NOPE"})
+	assert.ErrorIs(t, err, coupon.ErrCouponRejected)
+	assert.ErrorIs(t, err, coupon.ErrUnknownCode)
+
+	require.NoError(t, couponRepo.CreateRedemption(&coupon.Redemption{CouponID: 1, UserID: 7, OrderID: 1, Discount: *usd(1000)}))
+	_, err = quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "SHOES"})
+	assert.ErrorIs(t, err, coupon.ErrCouponUserLimit)
+	_, err = quotes.QuoteCart(8, &coupon.QuoteRequest{CouponCode: "SHOES"})
+	assert.NoError(t, err)
+
+	expired := time.Now().Add(-time.Hour)
+	require.NoError(t, couponRepo.CreateCoupon(&coupon.Coupon{Code: "OLD", Kind: coupon.KindFixed, AmountOff: *usd(100), Active: true, EndsAt: &expired}))
+	_, err = quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "OLD"})
+	assert.ErrorIs(t, err, coupon.ErrCouponInactive)
+
+	require.NoError(t, couponRepo.CreateCoupon(&coupon.Coupon{Code: "BIGSPEND", Kind: coupon.KindFixed, AmountOff: *usd(100), Active: true, MinOrder: *usd(10000)}))
+	_, err = quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "BIGSPEND"})
+	assert.ErrorIs(t, err, coupon.ErrCouponRejected)
+	assert.ErrorIs(t, err, pricing.ErrMinimumNotMet)
+}
+
+func TestQuoteCartCoversSubcategories(t *testing.T) {
+	couponRepo := newTestCouponRepository(t)
+	carts := &cartStub{current: &cart.Cart{ID: 1, UserID: 7, Items: []cart.CartItem{
+		{ProductID: 1, ProductName: "Trail shoe", UnitPrice: *usd(2000), Quantity: 1, Product: &user.Product{CategoryID: 12}},
+		{ProductID: 2, ProductName: "Sock", UnitPrice: *usd(500), Quantity: 1, Product: &user.Product{CategoryID: 20}},
+	}}}
+	// Shoes (10) holds Running (11), which holds Trail (12)
+	quotes := NewPricingUseCase(couponRepo, carts, &catalogStub{children: map[uint][]uint{10: {11}, 11: {12}}})
+
+	require.NoError(t, couponRepo.CreateCoupon(&coupon.Coupon{Code: "SHOES", Kind: coupon.KindPercentage, PercentOff: 25, Active: true,
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: 10}}}))
+	breakdown, err := quotes.QuoteCart(7, &coupon.QuoteRequest{CouponCode: "SHOES"})
+	require.NoError(t, err)
+	assert.Equal(t, "5.00", breakdown.Lines[0].Discount.Decimal())
+	assert.Equal(t, "0.00", breakdown.Lines[1].Discount.Decimal())
+
+	// The stored coupon keeps the one target it was given
+	stored, err := couponRepo.FindByCode("SHOES")
+	require.NoError(t, err)
+	assert.Len(t, stored.Targets, 1)
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	cartRepository "github.com/ratheeshkumar25/pkg/cart/repository"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/coupon/repository"
+	"github.com/ratheeshkumar25/pkg/pricing"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var ErrEmptyCart = errors.New("the cart is empty")
+
+type PricingUseCase interface {
+	QuoteCart(userID uint, request *coupon.QuoteRequest) (*pricing.Breakdown, error)
+}
+
+type pricingInteraction struct {
+	couponRepo   repository.CouponRepository
+	cartRepo     cartRepository.CartRepository
+	categoryRepo userRepository.CategoryRepository
+}
+
+// QuoteCart prices the caller's cart as checkout would, with the coupon applied
+// when one is given. Nothing is reserved, the coupon is only redeemed by checkout
+func (p *pricingInteraction) QuoteCart(userID uint, request *coupon.QuoteRequest) (*pricing.Breakdown, error) {
+	current, err := p.cartRepo.GetCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(current.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	var applied *coupon.Coupon
+	if request.CouponCode != "" {
+		if applied, err = p.redeemable(userID, request.CouponCode); err != nil {
+			return nil, err
+		}
+	}
+
+	//**A coupon on a category covers its subcategories too
+	priced, err := pricing.ExpandCategories(applied, p.categoryRepo.SubtreeIDs)
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := pricing.Price(cartLines(current), priced)
+	if errors.Is(err, pricing.ErrCouponNotApplicable) || errors.Is(err, pricing.ErrMinimumNotMet) {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, err)
+	}
+	return breakdown, err
+}
+
+// redeemable finds the coupon behind code and checks that userID may use it now
+func (p *pricingInteraction) redeemable(userID uint, code string) (*coupon.Coupon, error) {
+	found, err := p.couponRepo.FindByCode(coupon.NormalizeCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, coupon.ErrUnknownCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	redeemed, redeemedByUser, err := p.couponRepo.CountRedemptions(found.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := found.CheckRedeemable(time.Now(), redeemed, redeemedByUser); err != nil {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, err)
+	}
+	return found, nil
+}
+
+// cartLines prices cart lines at their snapshot, checkout refuses the cart while
+// a snapshot differs from the product's current price
+func cartLines(current *cart.Cart) []pricing.Line {
+	lines := make([]pricing.Line, 0, len(current.Items))
+	for _, item := range current.Items {
+		line := pricing.Line{ProductID: item.ProductID, ProductName: item.ProductName, UnitPrice: item.UnitPrice, Quantity: item.Quantity}
+		if item.Product != nil {
+			line.CategoryID = item.Product.CategoryID
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func NewPricingUseCase(couponRepo repository.CouponRepository, cartRepo cartRepository.CartRepository, categoryRepo userRepository.CategoryRepository) PricingUseCase {
+	return &pricingInteraction{
+		couponRepo:   couponRepo,
+		cartRepo:     cartRepo,
+		categoryRepo: categoryRepo,
+	}
+}
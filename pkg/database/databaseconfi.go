@@ -3,9 +3,16 @@ package database
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
 
 	"github.com/joho/godotenv"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/config"
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -31,11 +38,101 @@ if err != nil {
 	log.Fatalf("Connection to the database failed: %v", err)
 }
 
-DB.AutoMigrate(&user.UserRegister{},&user.AdminRegister{},&user.Product{})
+// Products used to carry category IDs pointing at nothing, give every such ID a
+// placeholder category so the foreign key below can be created
+DB.AutoMigrate(&user.Category{})
+if DB.Migrator().HasTable(&user.Product{}) {
+	DB.Exec(`INSERT INTO categories (id, name, created_at, updated_at)
+		SELECT DISTINCT p.category_id, 'Uncategorized ' || p.category_id, NOW(), NOW() FROM products p
+		WHERE p.category_id <> 0 AND NOT EXISTS (SELECT 1 FROM categories c WHERE c.id = p.category_id)`)
+	DB.Exec("SELECT setval('categories_id_seq', GREATEST((SELECT MAX(id) FROM categories), 1))")
+
+	// Products without a category all move to one Uncategorized category with a real ID,
+	// replacing the category 0 earlier versions of this migration created for them
+	DB.Exec(`INSERT INTO categories (name, created_at, updated_at)
+		SELECT 'Uncategorized', NOW(), NOW() WHERE EXISTS (SELECT 1 FROM products WHERE category_id = 0)
+		AND NOT EXISTS (SELECT 1 FROM categories WHERE name = 'Uncategorized' AND parent_id IS NULL AND deleted_at IS NULL)`)
+	DB.Exec(`UPDATE products SET category_id = (SELECT MIN(id) FROM categories WHERE name = 'Uncategorized' AND parent_id IS NULL AND deleted_at IS NULL)
+		WHERE category_id = 0`)
+	DB.Exec("DELETE FROM categories WHERE id = 0 AND NOT EXISTS (SELECT 1 FROM categories c WHERE c.parent_id = 0)")
+}
+
+// Retried payments used to share the provider reference of the first attempt, keep
+// it on the newest one so references can be unique and drop the old plain index
+if DB.Migrator().HasTable(&payment.PaymentIntent{}) {
+	DB.Exec(`UPDATE payment_intents p SET provider_ref = '' WHERE p.provider_ref <> '' AND EXISTS (
+		SELECT 1 FROM payment_intents n WHERE n.provider = p.provider AND n.provider_ref = p.provider_ref AND n.id > p.id)`)
+	DB.Exec("DROP INDEX IF EXISTS idx_payment_intents_provider_ref")
+}
+
+// Admins from before roles existed were all super-admins, backfill them once when the
+// column is added. The column has no default so a row without a role gets no power
+if DB.Migrator().HasTable(&user.AdminRegister{}) {
+	if !DB.Migrator().HasColumn(&user.AdminRegister{}, "role") {
+		DB.Exec("ALTER TABLE admin_registers ADD COLUMN role varchar(32)")
+		DB.Exec("UPDATE admin_registers SET role = 'super-admin'")
+	}
+	DB.Exec("ALTER TABLE admin_registers ALTER COLUMN role DROP DEFAULT")
+
+	// Usernames were not unique and a login only ever found the oldest admin of a name,
+	// later ones are renamed after their ID so the unique index can be created
+	DB.Exec(`UPDATE admin_registers a SET username = a.username || '-' || a.id WHERE EXISTS (
+		SELECT 1 FROM admin_registers o WHERE o.username = a.username AND o.id < a.id)`)
+}
+
+DB.AutoMigrate(&user.Category{},&user.UserRegister{},&user.AdminRegister{},&user.Product{},&user.Session{},&user.RefreshToken{},&user.AdminInvite{},&user.ActionToken{},&user.LoginThrottle{},&user.MFAFactor{},&user.RecoveryCode{},&user.MFAChallenge{},&user.StockMovement{},&cart.Cart{},&cart.CartItem{},&order.Order{},&order.OrderItem{},&order.OrderStatusChange{},&payment.PaymentIntent{},&payment.WebhookEvent{},&coupon.Coupon{},&coupon.CouponTarget{},&coupon.Redemption{})
+
+// Prices moved from a decimal column to integer minor units with a currency
+migrateProductPrices(DB, config.String("DEFAULT_CURRENCY", "USD"))
+
+// Stock from before the ledger existed is booked as one opening adjustment per product
+DB.Exec(`INSERT INTO stock_movements (product_id, type, quantity, balance, reason, actor, created_at)
+	SELECT p.id, 'adjustment', p.quantity, p.quantity, 'opening balance from before the stock ledger', 'system', NOW() FROM products p
+	WHERE p.quantity <> 0 AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = p.id)`)
+
+// Composite (sort key, id) indexes let cursor pagination seek instead of scanning
+DB.Exec("CREATE INDEX IF NOT EXISTS idx_products_price_id ON products (price_amount, id)")
+DB.Exec("CREATE INDEX IF NOT EXISTS idx_products_name_id ON products (product_name, id)")
+DB.Exec("CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products (created_at, id)")
+DB.Exec("CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON user_registers (created_at, id)")
+
+// Full-text search over products, the name weighs more than the description
+DB.Exec(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('english', coalesce(product_name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED`)
+DB.Exec("CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)")
+
+// Sessions from before roles existed carry the old "admin"/"user" role, force those to log in again
+DB.Exec("UPDATE sessions SET revoked_at = NOW() WHERE role IN ('admin', 'user') AND revoked_at IS NULL")
 return DB
 
 }
 
+// migrateProductPrices copies the old decimal price column into price_amount and
+// price_currency and drops it, existing prices are taken to be in currency
+func migrateProductPrices(db *gorm.DB, currency string) {
+	if !db.Migrator().HasColumn("products", "price") {
+		return
+	}
+	exponent, err := money.Exponent(currency)
+	if err != nil {
+		log.Fatalf("DEFAULT_CURRENCY: %v", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE products SET price_amount = ROUND(COALESCE(price, 0) * ?), price_currency = ?",
+			int64(math.Pow10(exponent)), currency).Error; err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE products DROP COLUMN price").Error
+	})
+	if err != nil {
+		log.Fatalf("migrating product prices failed: %v", err)
+	}
+	log.Printf("product prices migrated to minor units of %s", currency)
+}
+
 // func getEnv(key,fallback string)string{
 // 	if value,exists := os.LookupEnv(key);exists{
 // 		return value
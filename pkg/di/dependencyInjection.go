@@ -23,8 +23,31 @@
 package di
 
 import (
+    "log"
+    "time"
+
+    "github.com/ratheeshkumar25/pkg/audit"
+    cartDelivery "github.com/ratheeshkumar25/pkg/cart/delivery"
+    cartRepository "github.com/ratheeshkumar25/pkg/cart/repository"
+    cartUseCase "github.com/ratheeshkumar25/pkg/cart/usecase"
+    "github.com/ratheeshkumar25/pkg/config"
+    couponDelivery "github.com/ratheeshkumar25/pkg/coupon/delivery"
+    couponRepository "github.com/ratheeshkumar25/pkg/coupon/repository"
+    couponUseCase "github.com/ratheeshkumar25/pkg/coupon/usecase"
+    "github.com/ratheeshkumar25/pkg/middleware"
+    "github.com/ratheeshkumar25/pkg/notify"
+    orderDelivery "github.com/ratheeshkumar25/pkg/order/delivery"
+    orderRepository "github.com/ratheeshkumar25/pkg/order/repository"
+    orderUseCase "github.com/ratheeshkumar25/pkg/order/usecase"
+    "github.com/ratheeshkumar25/pkg/pagination"
+    paymentDelivery "github.com/ratheeshkumar25/pkg/payment/delivery"
+    "github.com/ratheeshkumar25/pkg/payment/gateway"
+    paymentUseCase "github.com/ratheeshkumar25/pkg/payment/usecase"
     "github.com/ratheeshkumar25/pkg/server"
+    "github.com/ratheeshkumar25/pkg/token"
+    "github.com/ratheeshkumar25/pkg/uow"
     "github.com/ratheeshkumar25/pkg/user/delivery"
+    user "github.com/ratheeshkumar25/pkg/user/entity"
     "github.com/ratheeshkumar25/pkg/user/repository"
     "github.com/ratheeshkumar25/pkg/user/usecase"
     "github.com/ratheeshkumar25/pkg/routes"
@@ -38,36 +61,183 @@ func Init() *server.Server {
     // Connect to the database
     db := database.ConnectDatabase()
 
-    // Create a new repository instance for Admin
-    adminRepo := repository.NewAdminUserRepository(db)
+    // Create the token maker used to sign access tokens
+    tokenMaker := token.NewMakerFromEnv()
+
+    // Create the login guard that throttles failed logins per account and client IP
+    loginGuard := usecase.NewLoginGuard(repository.NewLoginAttemptRepository(db), audit.LogLogger{}, usecase.LockoutConfig{
+        MaxAccountFailures: config.Int("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+        MaxIPFailures:      config.Int("LOGIN_MAX_IP_FAILURES", 20),
+        FailureWindow:      config.Duration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+        BaseLockout:        config.Duration("LOGIN_BASE_LOCKOUT", time.Minute),
+        MaxLockout:         config.Duration("LOGIN_MAX_LOCKOUT", time.Hour),
+    })
+
+    // Create a new repository instance for Admin, list cursors are signed so clients cannot forge them
+    adminRepo := repository.NewAdminUserRepository(db, pagination.NewCursorSignerFromEnv())
+
+    // Create the category repository, products must point at an existing category
+    categoryRepo := repository.NewCategoryRepository(db)
 
     // Create a new use case instance for Admin
-    adminUseCase := usecase.NewAdminUseCase(adminRepo)
+    adminUseCase := usecase.NewAdminUseCase(adminRepo, categoryRepo, loginGuard, config.Duration("ADMIN_INVITE_TTL", 72*time.Hour))
 
-    // Create a new handler instance for Admin
-    adminHandler := delivery.NewAdminHandler(adminUseCase)
+    // Seed the first super-admin from the environment, later admins join by invite
+    bootstrapSuperAdmin(adminUseCase)
 
-    // Create new routes for Admin and pass in the handler
-    adminRoutes := routes.NewAdminInit(server, adminHandler)
+    // Create the session repository and use case backing refresh tokens
+    sessionRepo := repository.NewSessionRepository(db)
+    sessionUseCase := usecase.NewSessionUseCase(sessionRepo, tokenMaker, config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour))
 
-    // Setup Admin routes
-    adminRoutes.AdminRoutes()
+    // Create the notifier used for password reset and verification mails
+    notifier := notify.NewNotifierFromEnv()
 
     // Create a new repository instance for User
     userRepo := repository.NewUserRepository(db)
+    actionTokenRepo := repository.NewActionTokenRepository(db)
 
     // Create a new use case instance for User
-    userUseCase := usecase.NewUserUsecase(userRepo)
+    userUseCase := usecase.NewUserUsecase(userRepo, actionTokenRepo, sessionUseCase, loginGuard, notifier, usecase.UserConfig{
+        ResetTTL:             config.Duration("PASSWORD_RESET_TTL", 30*time.Minute),
+        VerificationTTL:      config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+        RequireVerifiedEmail: config.Bool("REQUIRE_EMAIL_VERIFICATION", false),
+        PasswordPolicy: usecase.PasswordPolicy{
+            MinLength:     config.Int("PASSWORD_MIN_LENGTH", 8),
+            RequireUpper:  config.Bool("PASSWORD_REQUIRE_UPPER", false),
+            RequireLower:  config.Bool("PASSWORD_REQUIRE_LOWER", false),
+            RequireDigit:  config.Bool("PASSWORD_REQUIRE_DIGIT", false),
+            RequireSymbol: config.Bool("PASSWORD_REQUIRE_SYMBOL", false),
+        },
+    })
+
+    // Create the two-factor use case, REQUIRE_ADMIN_MFA makes 2FA mandatory for admins
+    mfaUseCase := usecase.NewMFAUseCase(repository.NewMFARepository(db), loginGuard, usecase.MFAConfig{
+        Issuer:            config.String("MFA_ISSUER", "newCrudClean"),
+        ChallengeTTL:      config.Duration("MFA_CHALLENGE_TTL", 5*time.Minute),
+        MaxAttempts:       config.Int("MFA_MAX_ATTEMPTS", 5),
+        RecoveryCodeCount: config.Int("MFA_RECOVERY_CODES", 10),
+        RequireForAdmins:  config.Bool("REQUIRE_ADMIN_MFA", false),
+    })
+
+    // Create the authentication middleware shared by the protected routes
+    auth := middleware.NewAuth(tokenMaker, sessionUseCase, userUseCase, adminUseCase, mfaUseCase)
+
+    // Create a new handler instance for Admin
+    adminHandler := delivery.NewAdminHandler(adminUseCase, sessionUseCase, mfaUseCase)
+
+    // Create new routes for Admin and pass in the handler
+    adminRoutes := routes.NewAdminInit(server, adminHandler, auth)
+
+    // Setup Admin routes
+    adminRoutes.AdminRoutes()
 
     // Create a new handler instance for User
-    userHandler := delivery.NewUserHandler(userUseCase)
+    userHandler := delivery.NewUserHandler(userUseCase, sessionUseCase, mfaUseCase)
 
     // Create new routes for User and pass in the handler
-    userRoutes := routes.NewUserInit(server, userHandler)
+    userRoutes := routes.NewUserInit(server, userHandler, auth)
 
     // Setup User routes
     userRoutes.UsersRoutes()
 
+    // Create the session handler and routes for refresh and logout
+    sessionHandler := delivery.NewSessionHandler(sessionUseCase)
+    sessionRoutes := routes.NewSessionInit(server, sessionHandler, auth)
+    sessionRoutes.SessionRoutes()
+
+    // Create the two-factor handler and routes for enrollment and the second login step
+    mfaHandler := delivery.NewMFAHandler(mfaUseCase, sessionUseCase)
+    mfaRoutes := routes.NewMFAInit(server, mfaHandler, auth)
+    mfaRoutes.MFARoutes()
+
+    // Create the category handler and routes for browsing and managing the catalog tree
+    categoryHandler := delivery.NewCategoryHandler(usecase.NewCategoryUseCase(categoryRepo))
+    categoryRoutes := routes.NewCategoryInit(server, categoryHandler, auth)
+    categoryRoutes.CategoryRoutes()
+
+    // Create the inventory handler and routes, stock only changes through the movement ledger
+    inventoryHandler := delivery.NewInventoryHandler(usecase.NewInventoryUseCase(repository.NewInventoryRepository(db), adminRepo))
+    inventoryRoutes := routes.NewInventoryInit(server, inventoryHandler, auth)
+    inventoryRoutes.InventoryRoutes()
+
+    // Create the product search backed by the products.search_vector full-text index
+    searchHandler := delivery.NewSearchHandler(usecase.NewSearchUseCase(repository.NewProductSearcher(db)))
+    searchRoutes := routes.NewSearchInit(server, searchHandler)
+    searchRoutes.SearchRoutes()
+
+    // Create the trash handler and routes, soft deleted products and users can be restored or purged
+    trashUseCase := usecase.NewTrashUseCase(repository.NewTrashRepository(db), categoryRepo, time.Duration(config.Int("TRASH_RETENTION_DAYS", 30))*24*time.Hour)
+    trashHandler := delivery.NewTrashHandler(trashUseCase)
+    trashRoutes := routes.NewTrashInit(server, trashHandler, auth)
+    trashRoutes.TrashRoutes()
+
+    // Purge what stayed in the trash past its retention in the background
+    go runTrashRetention(trashUseCase, config.Duration("TRASH_PURGE_INTERVAL", time.Hour))
+
+    // Create the cart handler and routes, carts are stored per user and checked against live stock
+    cartRepo := cartRepository.NewCartRepository(db)
+    cartHandler := cartDelivery.NewCartHandler(cartUseCase.NewCartUseCase(cartRepo, adminRepo))
+    cartRoutes := routes.NewCartInit(server, cartHandler, auth)
+    cartRoutes.CartRoutes()
+
+    // Create the coupon handler and routes, admins manage promo codes and users price their cart with them
+    couponRepo := couponRepository.NewCouponRepository(db)
+    couponHandler := couponDelivery.NewCouponHandler(couponUseCase.NewCouponUseCase(couponRepo, adminRepo, categoryRepo), couponUseCase.NewPricingUseCase(couponRepo, cartRepo, categoryRepo))
+    couponRoutes := routes.NewCouponInit(server, couponHandler, auth)
+    couponRoutes.CouponRoutes()
+
+    // Create the unit of work shared by flows that span several repositories
+    unit := uow.NewUnitOfWork(db)
+
+    // Create the order handler and routes, checkout takes stock and clears the cart in one unit of work
+    orderHandler := orderDelivery.NewOrderHandler(orderUseCase.NewOrderUseCase(orderRepository.NewOrderRepository(db), unit))
+    orderRoutes := routes.NewOrderInit(server, orderHandler, auth)
+    orderRoutes.OrderRoutes()
+
+    // Create the payment handler and routes, PAYMENT_GATEWAY picks the provider and orders become paid on its verified webhook
+    paymentHandler := paymentDelivery.NewPaymentHandler(paymentUseCase.NewPaymentUseCase(gateway.NewGatewayFromEnv(), unit))
+    paymentRoutes := routes.NewPaymentInit(server, paymentHandler, auth)
+    paymentRoutes.PaymentRoutes()
+
     // Return the initialized server
     return server
 }
+
+// bootstrapSuperAdmin creates the first super-admin when BOOTSTRAP_ADMIN_* is set and no admin exists yet
+func bootstrapSuperAdmin(adminUseCase usecase.AdminUseCase) {
+    username := config.String("BOOTSTRAP_ADMIN_USERNAME", "")
+    password := config.String("BOOTSTRAP_ADMIN_PASSWORD", "")
+    if username == "" || password == "" {
+        return
+    }
+
+    created, err := adminUseCase.BootstrapSuperAdmin(&user.AdminRegister{
+        Username: username,
+        Email:    config.String("BOOTSTRAP_ADMIN_EMAIL", ""),
+        Password: password,
+    })
+    if err != nil {
+        log.Fatalf("bootstrapping super-admin failed: %v", err)
+    }
+    if created {
+        log.Printf("bootstrap super-admin %q created", username)
+    }
+}
+
+// runTrashRetention purges expired trash once at startup and then every interval
+func runTrashRetention(trashUseCase usecase.TrashUseCase, interval time.Duration) {
+    if interval <= 0 {
+        return
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        purged, err := trashUseCase.PurgeExpired()
+        if err != nil {
+            log.Printf("purging expired trash failed: %v", err)
+        } else if purged.Products > 0 || purged.Users > 0 {
+            log.Printf("purged expired trash: %d products, %d users", purged.Products, purged.Users)
+        }
+        <-ticker.C
+    }
+}
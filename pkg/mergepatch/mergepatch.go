@@ -0,0 +1,80 @@
+// Package mergepatch decodes JSON Merge Patch documents (RFC 7396) into typed
+// patch structs whose members remember whether they were absent, null or set
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ContentType is the media type of merge patch documents
+const ContentType = "application/merge-patch+json"
+
+// ErrInvalidPatch is returned for bodies that are not a merge patch of the target
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// Field is one member of a merge patch. Members missing from the document
+// leave Set false, an explicit null sets Set and Null
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// Value returns a field set to v
+func Value[T any](v T) Field[T] {
+	return Field[T]{Set: true, Value: v}
+}
+
+// Null returns a field that was sent as null
+func Null[T any]() Field[T] {
+	return Field[T]{Set: true, Null: true}
+}
+
+// UnmarshalJSON only runs for members present in the document
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		f.Null = true
+		f.Value = zero
+		return nil
+	}
+	f.Null = false
+	return json.Unmarshal(data, &f.Value)
+}
+
+// Apply writes the patched value into target, null resets it to the zero value
+func (f Field[T]) Apply(target *T) {
+	if f.Set {
+		*target = f.Value
+	}
+}
+
+// Decode reads a merge patch document into patch, which should be a pointer to
+// a struct of Fields. Members patch does not declare are rejected so typos and
+// read-only fields fail loudly instead of being ignored
+func Decode(body io.Reader, patch any) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	//**A patch that is not an object would replace the whole resource, which PATCH does not allow here
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return fmt.Errorf("%w: the document must be a JSON object", ErrInvalidPatch)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("%w: trailing data after the document", ErrInvalidPatch)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package mergepatch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPatch struct {
+	Name  Field[string] `json:"name"`
+	Note  Field[string] `json:"note"`
+	Count Field[int]    `json:"count"`
+}
+
+func TestDecodeTellsAbsentFromNull(t *testing.T) {
+	var patch testPatch
+	require.NoError(t, Decode(strings.NewReader(`{"name":"shoe","note":null}`), &patch))
+
+	assert.Equal(t, Value("shoe"), patch.Name)
+	assert.Equal(t, Null[string](), patch.Note)
+	assert.False(t, patch.Count.Set)
+
+	name, note, count := "boot", "keep me", 7
+	patch.Name.Apply(&name)
+	patch.Note.Apply(&note)
+	patch.Count.Apply(&count)
+	assert.Equal(t, "shoe", name)
+	assert.Equal(t, "", note)
+	assert.Equal(t, 7, count)
+}
+
+func TestDecodeRejects(t *testing.T) {
+	for name, body := range map[string]string{
+		"unknown member": `{"quantity":3}`,
+		"wrong type":     `{"count":"three"}`,
+		"not an object":  `["name"]`,
+		"empty body":     ``,
+		"trailing data":  `{"name":"shoe"}{"name":"boot"}`,
+	} {
+		var patch testPatch
+		err := Decode(strings.NewReader(body), &patch)
+		assert.True(t, errors.Is(err, ErrInvalidPatch), name)
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+// Keys under which the authenticated caller is stored in the gin.Context
+const (
+	ClaimsKey  = "auth_claims"
+	RoleKey    = "auth_role"
+	UserKey    = "auth_user"
+	AdminKey   = "auth_admin"
+	SessionKey = "auth_session"
+)
+
+type Auth struct {
+	tokenMaker     token.Maker
+	sessionUseCase usecase.SessionUseCase
+	userUseCase    usecase.UserUseCase
+	adminUseCase   usecase.AdminUseCase
+	mfaUseCase     usecase.MFAUseCase
+}
+
+// RequireAuth validates the bearer token and loads the caller into the context
+func (a *Auth) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		scheme, accessToken, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || accessToken == "" {
+			c.AbortWithStatusJSON(401, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims, err := a.tokenMaker.VerifyToken(strings.TrimSpace(accessToken))
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
+			return
+		}
+
+		//**Tokens of logged out sessions stop working before they expire
+		session, err := a.sessionUseCase.ValidateSession(claims.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
+			return
+		}
+
+		//**Load the account the token was issued for, deleted accounts lose access
+		//**and the stored role wins over the one in the token so demotions apply at once
+		switch claims.Account {
+		case user.AccountAdmin:
+			admin, err := a.adminUseCase.GetAdminDetail(claims.SubjectID)
+			if err != nil {
+				c.AbortWithStatusJSON(401, gin.H{"error": "account not found"})
+				return
+			}
+			c.Set(AdminKey, admin)
+			c.Set(RoleKey, admin.Role)
+		case user.AccountUser:
+			caller, err := a.userUseCase.GetUserDetail(claims.SubjectID)
+			if err != nil {
+				c.AbortWithStatusJSON(401, gin.H{"error": "account not found"})
+				return
+			}
+			c.Set(UserKey, caller)
+			c.Set(RoleKey, caller.Role)
+		default:
+			c.AbortWithStatusJSON(401, gin.H{"error": "unknown account type"})
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Set(SessionKey, session)
+		c.Next()
+	}
+}
+
+// RequirePermission must run after RequireAuth and rejects callers whose role lacks perm
+func (a *Auth) RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := CurrentRole(c)
+		if !ok {
+			c.AbortWithStatusJSON(401, gin.H{"error": "authentication required"})
+			return
+		}
+		if !rbac.HasPermission(role, perm) {
+			c.AbortWithStatusJSON(403, gin.H{"error": "insufficient permissions", "required": perm})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireMFA must run after RequireAuth and rejects sessions that skipped a mandatory second factor
+func (a *Auth) RequireMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := CurrentSession(c)
+		if !ok {
+			c.AbortWithStatusJSON(401, gin.H{"error": "authentication required"})
+			return
+		}
+		if a.mfaUseCase.Required(session.Account) && !session.MFAVerified {
+			c.AbortWithStatusJSON(403, gin.H{"error": "two-factor authentication required, enroll and sign in again", "mfa_required": true})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSelf rejects requests whose :param does not match the calling user's ID
+func (a *Auth) RequireSelf(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(403, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		id, err := strconv.ParseUint(c.Param(param), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": "invalid " + param})
+			return
+		}
+		if uint(id) != caller.ID {
+			c.AbortWithStatusJSON(403, gin.H{"error": "you can only manage your own account"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentClaims returns the verified token claims of the caller
+func CurrentClaims(c *gin.Context) (*token.Claims, bool) {
+	value, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*token.Claims)
+	return claims, ok
+}
+
+// CurrentSession returns the session the caller's token belongs to
+func CurrentSession(c *gin.Context) (*user.Session, bool) {
+	value, ok := c.Get(SessionKey)
+	if !ok {
+		return nil, false
+	}
+	session, ok := value.(*user.Session)
+	return session, ok
+}
+
+// CurrentRole returns the role of the authenticated account
+func CurrentRole(c *gin.Context) (string, bool) {
+	role := c.GetString(RoleKey)
+	return role, role != ""
+}
+
+// CurrentUser returns the authenticated user, if the caller is one
+func CurrentUser(c *gin.Context) (*user.UserRegister, bool) {
+	value, ok := c.Get(UserKey)
+	if !ok {
+		return nil, false
+	}
+	caller, ok := value.(*user.UserRegister)
+	return caller, ok
+}
+
+// CurrentAdmin returns the authenticated admin, if the caller is one
+func CurrentAdmin(c *gin.Context) (*user.AdminRegister, bool) {
+	value, ok := c.Get(AdminKey)
+	if !ok {
+		return nil, false
+	}
+	admin, ok := value.(*user.AdminRegister)
+	return admin, ok
+}
+
+func NewAuth(tokenMaker token.Maker, sessionUseCase usecase.SessionUseCase, userUseCase usecase.UserUseCase, adminUseCase usecase.AdminUseCase, mfaUseCase usecase.MFAUseCase) *Auth {
+	return &Auth{
+		tokenMaker:     tokenMaker,
+		sessionUseCase: sessionUseCase,
+		userUseCase:    userUseCase,
+		adminUseCase:   adminUseCase,
+		mfaUseCase:     mfaUseCase,
+	}
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeUserUseCase only implements the lookup used by the middleware
+type fakeUserUseCase struct {
+	usecase.UserUseCase
+	users map[uint]*user.UserRegister
+}
+
+func (f *fakeUserUseCase) GetUserDetail(id uint) (*user.UserRegister, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// fakeAdminUseCase only implements the lookup used by the middleware
+type fakeAdminUseCase struct {
+	usecase.AdminUseCase
+	admins map[uint]*user.AdminRegister
+}
+
+func (f *fakeAdminUseCase) GetAdminDetail(id uint) (*user.AdminRegister, error) {
+	if a, ok := f.admins[id]; ok {
+		return a, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// fakeSessionUseCase treats every session as an active admin session unless revoked
+type fakeSessionUseCase struct {
+	usecase.SessionUseCase
+	revoked     map[uint]bool
+	mfaVerified map[uint]bool
+}
+
+func (f *fakeSessionUseCase) ValidateSession(sessionID uint) (*user.Session, error) {
+	if f.revoked[sessionID] {
+		return nil, usecase.ErrSessionRevoked
+	}
+	return &user.Session{Model: gorm.Model{ID: sessionID}, Account: user.AccountAdmin, MFAVerified: f.mfaVerified[sessionID]}, nil
+}
+
+// fakeMFAUseCase only implements the admin 2FA policy
+type fakeMFAUseCase struct {
+	usecase.MFAUseCase
+	requireForAdmins bool
+}
+
+func (f *fakeMFAUseCase) Required(account string) bool {
+	return f.requireForAdmins && account == user.AccountAdmin
+}
+
+func newTestAuth(t *testing.T) (*Auth, token.Maker) {
+	maker, err := token.NewHS256Maker([]byte("test-secret-that-is-32-bytes-long"), time.Minute, "")
+	assert.NoError(t, err)
+
+	users := &fakeUserUseCase{users: map[uint]*user.UserRegister{
+		1: {Model: gorm.Model{ID: 1}, UserName: "alice", Role: rbac.RoleCustomer},
+		2: {Model: gorm.Model{ID: 2}, UserName: "bob", Role: rbac.RoleCustomer},
+	}}
+	admins := &fakeAdminUseCase{admins: map[uint]*user.AdminRegister{
+		1: {Model: gorm.Model{ID: 1}, Username: "root", Role: rbac.RoleSuperAdmin},
+		2: {Model: gorm.Model{ID: 2}, Username: "catalog", Role: rbac.RoleCatalogManager},
+		3: {Model: gorm.Model{ID: 3}, Username: "helpdesk", Role: rbac.RoleSupport},
+	}}
+	sessions := &fakeSessionUseCase{revoked: map[uint]bool{13: true}, mfaVerified: map[uint]bool{21: true}}
+	return NewAuth(maker, sessions, users, admins, &fakeMFAUseCase{requireForAdmins: true}), maker
+}
+
+// bearer always claims super-admin, the middleware must trust the stored role instead
+func bearer(t *testing.T, maker token.Maker, id uint, account string) string {
+	return bearerForSession(t, maker, id, account, 1)
+}
+
+func bearerForSession(t *testing.T, maker token.Maker, id uint, account string, sessionID uint) string {
+	signed, _, err := maker.CreateToken(id, account, rbac.RoleSuperAdmin, sessionID)
+	assert.NoError(t, err)
+	return "Bearer " + signed
+}
+
+func TestRequirePermission(t *testing.T) {
+	auth, maker := newTestAuth(t)
+
+	router := gin.New()
+	router.GET("/userlist", auth.RequireAuth(), auth.RequirePermission(rbac.PermUserRead), func(c *gin.Context) {
+		admin, ok := CurrentAdmin(c)
+		assert.True(t, ok)
+		c.JSON(200, gin.H{"admin": admin.Username})
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"malformed header", "Token abc", http.StatusUnauthorized},
+		{"invalid token", "Bearer abc", http.StatusUnauthorized},
+		{"revoked session", bearerForSession(t, maker, 1, user.AccountAdmin, 13), http.StatusUnauthorized},
+		{"unknown admin", bearer(t, maker, 99, user.AccountAdmin), http.StatusUnauthorized},
+		{"customer", bearer(t, maker, 1, user.AccountUser), http.StatusForbidden},
+		{"catalog manager", bearer(t, maker, 2, user.AccountAdmin), http.StatusForbidden},
+		{"support", bearer(t, maker, 3, user.AccountAdmin), http.StatusOK},
+		{"super admin", bearer(t, maker, 1, user.AccountAdmin), http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/userlist", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+}
+
+func TestRequireSelf(t *testing.T) {
+	auth, maker := newTestAuth(t)
+
+	router := gin.New()
+	router.DELETE("/userdelete/:id", auth.RequireAuth(), auth.RequireSelf("id"), func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	req, _ := http.NewRequest("DELETE", "/userdelete/1", nil)
+	req.Header.Set("Authorization", bearer(t, maker, 1, user.AccountUser))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/userdelete/2", nil)
+	req.Header.Set("Authorization", bearer(t, maker, 1, user.AccountUser))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRequireMFA(t *testing.T) {
+	auth, maker := newTestAuth(t)
+
+	router := gin.New()
+	router.GET("/userlist", auth.RequireAuth(), auth.RequireMFA(), func(c *gin.Context) {
+		c.Status(200)
+	})
+
+	// Admins must come through a session that passed the second factor
+	req, _ := http.NewRequest("GET", "/userlist", nil)
+	req.Header.Set("Authorization", bearer(t, maker, 1, user.AccountAdmin))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"two-factor authentication required, enroll and sign in again","mfa_required":true}`, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/userlist", nil)
+	req.Header.Set("Authorization", bearerForSession(t, maker, 1, user.AccountAdmin, 21))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,72 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	price, err := Parse("19.99", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, Money{Amount: 1999, Currency: "USD"}, price)
+	assert.Equal(t, "19.99", price.Decimal())
+
+	price, err = Parse("5", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), price.Amount)
+	assert.Equal(t, "5.00", price.Decimal())
+
+	price, err = Parse("1500", "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, "1500", price.Decimal())
+
+	price, err = Parse("1.005", "KWD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), price.Amount)
+
+	price, err = Parse("-0.05", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "-0.05", price.Decimal())
+
+	// Sub-unit precision is refused rather than rounded away
+	for _, bad := range []string{"19.999", "", ".5", "1e3", "12,50", "abc"} {
+		_, err = Parse(bad, "USD")
+		assert.True(t, errors.Is(err, ErrInvalidAmount), bad)
+	}
+	_, err = Parse("1", "XXX")
+	assert.True(t, errors.Is(err, ErrUnknownCurrency))
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is exact in minor units
+	a, _ := Parse("0.10", "USD")
+	b, _ := Parse("0.20", "USD")
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.Equal(t, "0.30", sum.Decimal())
+	assert.Equal(t, "59.97 USD", Money{Amount: 1999, Currency: "USD"}.Mul(3).String())
+
+	_, err = a.Add(Money{Amount: 1, Currency: "EUR"})
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	assert.Error(t, Money{Amount: -1, Currency: "USD"}.Validate())
+	assert.Error(t, Money{Amount: 1}.Validate())
+}
+
+func TestJSON(t *testing.T) {
+	encoded, err := json.Marshal(Money{Amount: 1999, Currency: "USD"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"19.99","currency":"USD"}`, string(encoded))
+
+	var decoded Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":19.99,"currency":"usd"}`), &decoded))
+	assert.Equal(t, Money{Amount: 1999, Currency: "USD"}, decoded)
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"0.07","currency":"EUR"}`), &decoded))
+	assert.Equal(t, Money{Amount: 7, Currency: "EUR"}, decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"19.999","currency":"USD"}`), &decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1","currency":"ABC"}`), &decoded))
+}
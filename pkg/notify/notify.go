@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/config"
+)
+
+// Message is a single notification for one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users, swap the implementation per environment
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier writes messages to the application log, meant for local development
+type LogNotifier struct{}
+
+func (LogNotifier) Send(msg Message) error {
+	log.Printf("notify: to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier appends messages to a file so they can be read back during development
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *FileNotifier) Send(msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("writing notification: %w", err)
+	}
+	return nil
+}
+
+// NewNotifierFromEnv picks the notifier configured by NOTIFIER (log or file)
+func NewNotifierFromEnv() Notifier {
+	switch kind := config.String("NOTIFIER", "log"); kind {
+	case "log":
+		return LogNotifier{}
+	case "file":
+		return &FileNotifier{Path: config.String("NOTIFIER_FILE", "notifications.log")}
+	default:
+		log.Fatalf("unsupported NOTIFIER %q", kind)
+		return nil
+	}
+}
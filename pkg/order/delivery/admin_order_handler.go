@@ -0,0 +1,82 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/order/usecase"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	userDelivery "github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+// AdminListOrdersHandler pages through every user's orders, filtered by status, user and creation date
+func (h *OrderHandler) AdminListOrdersHandler(c *gin.Context) {
+	var query order.AdminOrderQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	orders, total, err := h.orderUseCase.ListAllOrders(&query)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUnknownStatus) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to list orders"})
+		return
+	}
+	c.JSON(200, userDelivery.NewPageResponse(c.Request.URL, NewOrderListResponse(orders), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+func (h *OrderHandler) AdminGetOrderHandler(c *gin.Context) {
+	id, ok := orderID(c)
+	if !ok {
+		return
+	}
+	found, err := h.orderUseCase.FindOrder(id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to load order"})
+		return
+	}
+	c.JSON(200, NewOrderResponse(found))
+}
+
+// TransitionOrderHandler moves an order to the posted status, booked against the calling admin
+func (h *OrderHandler) TransitionOrderHandler(c *gin.Context) {
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "insufficient permissions"})
+		return
+	}
+	id, ok := orderID(c)
+	if !ok {
+		return
+	}
+	var request order.TransitionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved, err := h.orderUseCase.Transition(actor.Username, id, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrOrderNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrUnknownStatus):
+			c.JSON(400, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrIllegalTransition):
+			c.JSON(409, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to update order"})
+		}
+		return
+	}
+	c.JSON(200, NewOrderResponse(moved))
+}
@@ -0,0 +1,125 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/order/usecase"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	userDelivery "github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type OrderHandler struct {
+	orderUseCase usecase.OrderUseCase
+}
+
+type OrderUseCases interface {
+	CheckoutHandler(c *gin.Context)
+	GetOrderHandler(c *gin.Context)
+	ListOrdersHandler(c *gin.Context)
+	AdminListOrdersHandler(c *gin.Context)
+	AdminGetOrderHandler(c *gin.Context)
+	TransitionOrderHandler(c *gin.Context)
+}
+
+// CheckoutHandler places an order for the posted items, or for the cart when no items are posted
+func (h *OrderHandler) CheckoutHandler(c *gin.Context) {
+	userID, ok := orderOwner(c)
+	if !ok {
+		return
+	}
+	var request order.CheckoutRequest
+	//**An empty body checks out the cart
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	placed, err := h.orderUseCase.Checkout(userID, &request)
+	if err != nil {
+		var rejected *order.CheckoutError
+		switch {
+		case errors.As(err, &rejected):
+			c.JSON(409, gin.H{"error": "some items cannot be ordered", "items": rejected.Items})
+		case errors.Is(err, usecase.ErrEmptyOrder):
+			c.JSON(400, gin.H{"error": err.Error()})
+		case errors.Is(err, coupon.ErrCouponRejected):
+			c.JSON(422, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to place order"})
+		}
+		return
+	}
+	c.JSON(201, NewOrderResponse(placed))
+}
+
+func (h *OrderHandler) GetOrderHandler(c *gin.Context) {
+	userID, ok := orderOwner(c)
+	if !ok {
+		return
+	}
+	id, ok := orderID(c)
+	if !ok {
+		return
+	}
+	found, err := h.orderUseCase.GetOrder(userID, id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrOrderNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to load order"})
+		return
+	}
+	c.JSON(200, NewOrderResponse(found))
+}
+
+// ListOrdersHandler pages through the caller's orders, newest first
+func (h *OrderHandler) ListOrdersHandler(c *gin.Context) {
+	userID, ok := orderOwner(c)
+	if !ok {
+		return
+	}
+	var query order.OrderListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	orders, total, err := h.orderUseCase.ListOrders(userID, &query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list orders"})
+		return
+	}
+	c.JSON(200, userDelivery.NewPageResponse(c.Request.URL, NewOrderListResponse(orders), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+// orderOwner returns the calling user, only user accounts place orders
+func orderOwner(c *gin.Context) (uint, bool) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only users can place orders"})
+		return 0, false
+	}
+	return caller.ID, true
+}
+
+func orderID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid order ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func NewOrderHandler(orderUseCase usecase.OrderUseCase) *OrderHandler {
+	return &OrderHandler{
+		orderUseCase: orderUseCase,
+	}
+}
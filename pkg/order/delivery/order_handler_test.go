@@ -0,0 +1,190 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/order/usecase"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockOrderUseCase is a mock implementation of the OrderUseCase interface
+type MockOrderUseCase struct {
+	mock.Mock
+}
+
+func (m *MockOrderUseCase) Checkout(userID uint, request *order.CheckoutRequest) (*order.Order, error) {
+	args := m.Called(userID, request)
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderUseCase) GetOrder(userID, id uint) (*order.Order, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderUseCase) ListOrders(userID uint, query *order.OrderListQuery) ([]order.Order, int64, error) {
+	args := m.Called(userID, query)
+	return args.Get(0).([]order.Order), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockOrderUseCase) FindOrder(id uint) (*order.Order, error) {
+	args := m.Called(id)
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderUseCase) ListAllOrders(query *order.AdminOrderQuery) ([]order.Order, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]order.Order), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockOrderUseCase) Transition(actor string, id uint, request *order.TransitionRequest) (*order.Order, error) {
+	args := m.Called(actor, id, request)
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func TestCheckoutHandler(t *testing.T) {
+	mockOrderUseCase := new(MockOrderUseCase)
+	handler := NewOrderHandler(mockOrderUseCase)
+
+	// Simulate the authentication middleware loading the caller
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	withUser := func(c *gin.Context) { c.Set(middleware.UserKey, caller) }
+
+	r := gin.Default()
+	r.POST("/checkout", withUser, handler.CheckoutHandler)
+	r.POST("/admin/checkout", handler.CheckoutHandler)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := money.Money{Amount: 1999, Currency: "USD"}
+	off := money.Money{Amount: 400, Currency: "USD"}
+	placed := &order.Order{ID: 4, UserID: 7, Status: order.StatusPending, CouponCode: "SAVE10", Subtotal: price.Mul(2), Discount: off,
+		Total: money.Money{Amount: 3598, Currency: "USD"}, CreatedAt: created, UpdatedAt: created,
+		Items: []order.OrderItem{{ProductID: 1, ProductName: "Shoe", UnitPrice: price, Quantity: 2, LineTotal: price.Mul(2), Discount: off}}}
+
+	// Without items the cart is checked out
+	mockOrderUseCase.On("Checkout", uint(7), &order.CheckoutRequest{CouponCode: "save10"}).Return(placed, nil)
+	mockOrderUseCase.On("Checkout", uint(7), &order.CheckoutRequest{CouponCode: "GONE"}).
+		Return((*order.Order)(nil), fmt.Errorf("%w: %w", coupon.ErrCouponRejected, coupon.ErrUnknownCode))
+	mockOrderUseCase.On("Checkout", uint(7), &order.CheckoutRequest{Items: []order.CheckoutItem{{ProductID: 2, Quantity: 3}}}).
+		Return((*order.Order)(nil), &order.CheckoutError{Items: []order.ItemError{
+			{ProductID: 2, Code: order.ItemInsufficientStock, Message: "only 1 left in stock", Requested: 3, Available: 1},
+		}})
+
+	req, _ := http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"coupon_code":"save10"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{
+		"id":4,"user_id":7,"status":"pending","history":[],"coupon_code":"SAVE10",
+		"subtotal":{"amount":"39.98","currency":"USD"},"discount":{"amount":"4.00","currency":"USD"},"total":{"amount":"35.98","currency":"USD"},
+		"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z",
+		"items":[{"product_id":1,"product_name":"Shoe","unit_price":{"amount":"19.99","currency":"USD"},"quantity":2,
+			"line_total":{"amount":"39.98","currency":"USD"},"discount":{"amount":"4.00","currency":"USD"}}]
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"coupon_code":"GONE"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error":"coupon cannot be used: coupon code does not exist"}`, w.Body.String())
+
+	// Lines that cannot be ordered are listed one by one
+	req, _ = http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"items":[{"product_id":2,"quantity":3}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"some items cannot be ordered","items":[
+		{"product_id":2,"code":"insufficient_stock","message":"only 1 left in stock","requested":3,"available":1}
+	]}`, w.Body.String())
+
+	// Quantities below one are refused before the use case runs
+	req, _ = http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"items":[{"product_id":2,"quantity":0}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Admin accounts do not place orders
+	req, _ = http.NewRequest("POST", "/admin/checkout", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockOrderUseCase.AssertExpectations(t)
+}
+
+func TestGetOrderHandlerNotFound(t *testing.T) {
+	mockOrderUseCase := new(MockOrderUseCase)
+	handler := NewOrderHandler(mockOrderUseCase)
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	r := gin.Default()
+	r.GET("/orders/:id", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.GetOrderHandler)
+
+	mockOrderUseCase.On("GetOrder", uint(7), uint(9)).Return((*order.Order)(nil), usecase.ErrOrderNotFound)
+
+	req, _ := http.NewRequest("GET", "/orders/9", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"order not found"}`, w.Body.String())
+}
+
+func TestTransitionOrderHandler(t *testing.T) {
+	mockOrderUseCase := new(MockOrderUseCase)
+	handler := NewOrderHandler(mockOrderUseCase)
+
+	admin := &user.AdminRegister{Username: "packer"}
+	r := gin.Default()
+	r.POST("/admin/orders/:id/transitions", func(c *gin.Context) { c.Set(middleware.AdminKey, admin) }, handler.TransitionOrderHandler)
+
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	zero := money.Money{Currency: "USD"}
+	shipped := &order.Order{ID: 4, UserID: 7, Status: order.StatusShipped, Subtotal: zero, Discount: zero, Total: zero, CreatedAt: at, UpdatedAt: at, History: []order.OrderStatusChange{
+		{To: order.StatusPending, Actor: "user:7", CreatedAt: at},
+		{From: order.StatusPacked, To: order.StatusShipped, Actor: "packer", Note: "tracking 123", CreatedAt: at},
+	}}
+	mockOrderUseCase.On("Transition", "packer", uint(4), &order.TransitionRequest{Status: order.StatusShipped, Note: "tracking 123"}).Return(shipped, nil)
+	mockOrderUseCase.On("Transition", "packer", uint(5), &order.TransitionRequest{Status: order.StatusPaid}).
+		Return((*order.Order)(nil), fmt.Errorf("%w: delivered order cannot become paid", usecase.ErrIllegalTransition))
+
+	req, _ := http.NewRequest("POST", "/admin/orders/4/transitions", bytes.NewBufferString(`{"status":"shipped","note":"tracking 123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"id":4,"user_id":7,"status":"shipped","items":[],
+		"subtotal":{"amount":"0.00","currency":"USD"},"discount":{"amount":"0.00","currency":"USD"},"total":{"amount":"0.00","currency":"USD"},
+		"created_at":"2024-01-02T00:00:00Z","updated_at":"2024-01-02T00:00:00Z",
+		"history":[
+			{"to":"pending","actor":"user:7","created_at":"2024-01-02T00:00:00Z"},
+			{"from":"packed","to":"shipped","actor":"packer","note":"tracking 123","created_at":"2024-01-02T00:00:00Z"}
+		]
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/admin/orders/5/transitions", bytes.NewBufferString(`{"status":"paid"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"order cannot move to that status: delivered order cannot become paid"}`, w.Body.String())
+
+	mockOrderUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,83 @@
+package delivery
+
+import (
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+)
+
+type OrderResponse struct {
+	ID         uint                   `json:"id"`
+	UserID     uint                   `json:"user_id"`
+	Status     string                 `json:"status"`
+	Items      []OrderItemResponse    `json:"items"`
+	History    []StatusChangeResponse `json:"history"`
+	CouponCode string                 `json:"coupon_code,omitempty"`
+	Subtotal   money.Money            `json:"subtotal"`
+	Discount   money.Money            `json:"discount"`
+	Total      money.Money            `json:"total"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+type OrderItemResponse struct {
+	ProductID   uint        `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Quantity    int         `json:"quantity"`
+	LineTotal   money.Money `json:"line_total"`
+	Discount    money.Money `json:"discount"`
+}
+
+type StatusChangeResponse struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Actor     string    `json:"actor"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewOrderResponse(o *order.Order) OrderResponse {
+	response := OrderResponse{
+		ID:         o.ID,
+		UserID:     o.UserID,
+		Status:     o.Status,
+		Items:      make([]OrderItemResponse, 0, len(o.Items)),
+		History:    make([]StatusChangeResponse, 0, len(o.History)),
+		CouponCode: o.CouponCode,
+		Subtotal:   o.Subtotal,
+		Discount:   o.Discount,
+		Total:      o.Total,
+		CreatedAt:  o.CreatedAt,
+		UpdatedAt:  o.UpdatedAt,
+	}
+	for _, item := range o.Items {
+		response.Items = append(response.Items, OrderItemResponse{
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			UnitPrice:   item.UnitPrice,
+			Quantity:    item.Quantity,
+			LineTotal:   item.LineTotal,
+			Discount:    item.Discount,
+		})
+	}
+	for _, change := range o.History {
+		response.History = append(response.History, StatusChangeResponse{
+			From:      change.From,
+			To:        change.To,
+			Actor:     change.Actor,
+			Note:      change.Note,
+			CreatedAt: change.CreatedAt,
+		})
+	}
+	return response
+}
+
+func NewOrderListResponse(orders []order.Order) []OrderResponse {
+	responses := make([]OrderResponse, 0, len(orders))
+	for i := range orders {
+		responses = append(responses, NewOrderResponse(&orders[i]))
+	}
+	return responses
+}
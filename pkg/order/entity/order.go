@@ -0,0 +1,87 @@
+package order
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+// Order is placed by checkout. Users and products get no foreign keys so the
+// trash can still purge them, the order keeps its own snapshot of what was bought.
+// Total is Subtotal less the Discount of CouponCode
+type Order struct {
+	ID         uint                `gorm:"primarykey" json:"id"`
+	UserID     uint                `gorm:"not null;index" json:"user_id"`
+	Status     string              `gorm:"type:varchar(32);not null;default:'pending'" json:"status"`
+	CouponCode string              `gorm:"type:varchar(64)" json:"coupon_code"`
+	Subtotal   money.Money         `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	Discount   money.Money         `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
+	Total      money.Money         `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+	Items      []OrderItem         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"items"`
+	History    []OrderStatusChange `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"history"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// OrderItem is one ordered product with the name and price it had at checkout.
+// LineTotal is before Discount, the item's share of the order's discount
+type OrderItem struct {
+	ID          uint        `gorm:"primarykey" json:"id"`
+	OrderID     uint        `gorm:"not null;index" json:"order_id"`
+	ProductID   uint        `gorm:"not null;index" json:"product_id"`
+	ProductName string      `gorm:"type:varchar(255);not null" json:"product_name"`
+	UnitPrice   money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Quantity    int         `gorm:"not null" json:"quantity"`
+	LineTotal   money.Money `gorm:"embedded;embeddedPrefix:line_total_" json:"line_total"`
+	Discount    money.Money `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
+}
+
+// CheckoutRequest orders Items directly, without items the caller's cart is
+// checked out. CouponCode is redeemed by the order when set
+type CheckoutRequest struct {
+	Items      []CheckoutItem `json:"items" binding:"omitempty,dive"`
+	CouponCode string         `json:"coupon_code"`
+}
+
+type CheckoutItem struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"required,min=1"`
+}
+
+// Reasons a checkout line was refused
+const (
+	ItemNotFound          = "not_found"
+	ItemInsufficientStock = "insufficient_stock"
+	ItemPriceChanged      = "price_changed"
+	ItemCurrencyMismatch  = "currency_mismatch"
+)
+
+// ItemError explains why one line of a checkout cannot be ordered
+type ItemError struct {
+	ProductID uint   `json:"product_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+// CheckoutError rejects a whole checkout and lists every line that caused it
+type CheckoutError struct {
+	Items []ItemError
+}
+
+func (e *CheckoutError) Error() string {
+	reasons := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		reasons = append(reasons, fmt.Sprintf("product %d: %s", item.ProductID, item.Message))
+	}
+	return "order rejected: " + strings.Join(reasons, "; ")
+}
+
+// OrderListQuery pages through the caller's orders, newest first
+type OrderListQuery struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
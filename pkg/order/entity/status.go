@@ -0,0 +1,69 @@
+package order
+
+import "time"
+
+// Statuses an order moves through, cancelled and refunded end the order early
+const (
+	StatusPending   = "pending"
+	StatusPaid      = "paid"
+	StatusPacked    = "packed"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusRefunded  = "refunded"
+)
+
+// transitions lists where an order may go from each status. Orders can be
+// cancelled until they leave the warehouse, after that only a refund undoes them
+var transitions = map[string][]string{
+	StatusPending:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusPacked, StatusCancelled},
+	StatusPacked:    {StatusShipped, StatusCancelled},
+	StatusShipped:   {StatusDelivered, StatusRefunded},
+	StatusDelivered: {StatusRefunded},
+}
+
+// CanTransition reports whether an order in status from may move to status to
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStatus reports whether status is one of the known order statuses
+func IsStatus(status string) bool {
+	if _, ok := transitions[status]; ok {
+		return true
+	}
+	return status == StatusCancelled || status == StatusRefunded
+}
+
+// OrderStatusChange records one transition of an order, the first one has an empty From
+type OrderStatusChange struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	OrderID   uint      `gorm:"not null;index" json:"order_id"`
+	From      string    `gorm:"type:varchar(32)" json:"from"`
+	To        string    `gorm:"type:varchar(32);not null" json:"to"`
+	Actor     string    `gorm:"type:varchar(255);not null" json:"actor"`
+	Note      string    `gorm:"type:text" json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransitionRequest moves an order to Status, Note is kept in the history
+type TransitionRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+// AdminOrderQuery filters every user's orders, newest first
+type AdminOrderQuery struct {
+	Status      string     `form:"status"`
+	UserID      uint       `form:"user_id"`
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02" time_utc:"1"`
+	CreatedTo   *time.Time `form:"created_to" time_format:"2006-01-02" time_utc:"1"`
+	Page        int        `form:"page" binding:"omitempty,min=1"`
+	Limit       int        `form:"limit" binding:"omitempty,min=1"`
+}
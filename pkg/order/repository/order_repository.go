@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"fmt"
+
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderRepository interface {
+	CreateOrder(placed *order.Order) error
+	GetOrder(userID, id uint) (*order.Order, error)
+	ListOrders(userID uint, page, limit int) ([]order.Order, int64, error)
+	FindOrder(id uint) (*order.Order, error)
+	ListAllOrders(query *order.AdminOrderQuery) ([]order.Order, int64, error)
+	LockOrder(id uint) (*order.Order, error)
+	UpdateStatus(placed *order.Order, change *order.OrderStatusChange) error
+}
+
+type OrderDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+// CreateOrder inserts the order together with its items
+func (o *OrderDataBaseInteraction) CreateOrder(placed *order.Order) error {
+	if err := o.DB.Create(placed).Error; err != nil {
+		return fmt.Errorf("creating order: %w", err)
+	}
+	return nil
+}
+
+// GetOrder only finds orders of userID, anything else is gorm.ErrRecordNotFound
+func (o *OrderDataBaseInteraction) GetOrder(userID, id uint) (*order.Order, error) {
+	var found order.Order
+	err := withDetails(o.DB).Where("user_id = ?", userID).First(&found, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("finding order %d: %w", id, err)
+	}
+	return &found, nil
+}
+
+func (o *OrderDataBaseInteraction) ListOrders(userID uint, page, limit int) ([]order.Order, int64, error) {
+	return listOrders(o.DB.Model(&order.Order{}).Where("user_id = ?", userID), page, limit)
+}
+
+// FindOrder finds an order of any user, for staff
+func (o *OrderDataBaseInteraction) FindOrder(id uint) (*order.Order, error) {
+	var found order.Order
+	if err := withDetails(o.DB).First(&found, id).Error; err != nil {
+		return nil, fmt.Errorf("finding order %d: %w", id, err)
+	}
+	return &found, nil
+}
+
+func (o *OrderDataBaseInteraction) ListAllOrders(query *order.AdminOrderQuery) ([]order.Order, int64, error) {
+	scope := o.DB.Model(&order.Order{})
+	if query.Status != "" {
+		scope = scope.Where("status = ?", query.Status)
+	}
+	if query.UserID != 0 {
+		scope = scope.Where("user_id = ?", query.UserID)
+	}
+	if query.CreatedFrom != nil {
+		scope = scope.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		//**The end date is inclusive
+		scope = scope.Where("created_at < ?", query.CreatedTo.AddDate(0, 0, 1))
+	}
+	return listOrders(scope, query.Page, query.Limit)
+}
+
+// LockOrder loads the order with its items and holds its row until the
+// surrounding transaction ends, so it only makes sense inside a unit of work
+func (o *OrderDataBaseInteraction) LockOrder(id uint) (*order.Order, error) {
+	var found order.Order
+	err := o.DB.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&found, id).Error
+	if err != nil {
+		return nil, fmt.Errorf("locking order %d: %w", id, err)
+	}
+	return &found, nil
+}
+
+// UpdateStatus moves placed to change.To and appends change to its history.
+// The update only applies while the order is still in change.From
+func (o *OrderDataBaseInteraction) UpdateStatus(placed *order.Order, change *order.OrderStatusChange) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(placed).Where("status = ?", change.From).Update("status", change.To)
+		if result.Error != nil {
+			return fmt.Errorf("updating order status: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("updating order status: %w", gorm.ErrRecordNotFound)
+		}
+		change.OrderID = placed.ID
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("recording order status change: %w", err)
+		}
+		placed.History = append(placed.History, *change)
+		return nil
+	})
+}
+
+// withDetails loads the items and the status history of the orders in order
+func withDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("order_items.id ASC") }).
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("order_status_changes.id ASC") })
+}
+
+func listOrders(scope *gorm.DB, page, limit int) ([]order.Order, int64, error) {
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting orders: %w", err)
+	}
+	var orders []order.Order
+	err := withDetails(scope).Order("created_at DESC, id DESC").Limit(limit).Offset(pagination.Offset(page, limit)).Find(&orders).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing orders: %w", err)
+	}
+	return orders, total, nil
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &OrderDataBaseInteraction{
+		DB: db,
+	}
+}
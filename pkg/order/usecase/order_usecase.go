@@ -0,0 +1,356 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/order/repository"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	"github.com/ratheeshkumar25/pkg/pricing"
+	"github.com/ratheeshkumar25/pkg/uow"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmptyOrder        = errors.New("nothing to order, the cart is empty")
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrUnknownStatus     = errors.New("unknown order status")
+	ErrIllegalTransition = errors.New("order cannot move to that status")
+)
+
+type OrderUseCase interface {
+	Checkout(userID uint, request *order.CheckoutRequest) (*order.Order, error)
+	GetOrder(userID, id uint) (*order.Order, error)
+	ListOrders(userID uint, query *order.OrderListQuery) ([]order.Order, int64, error)
+	FindOrder(id uint) (*order.Order, error)
+	ListAllOrders(query *order.AdminOrderQuery) ([]order.Order, int64, error)
+	Transition(actor string, id uint, request *order.TransitionRequest) (*order.Order, error)
+}
+
+type orderInteraction struct {
+	orderRepo repository.OrderRepository
+	unit      uow.UnitOfWork
+}
+
+// checkoutLine is one product to order, price is the cart's snapshot when it came from the cart
+type checkoutLine struct {
+	productID uint
+	quantity  int
+	price     *money.Money
+}
+
+// Checkout places an order for the request's items or the caller's cart. Stock is
+// taken and the coupon redeemed in the same transaction that creates the order,
+// either every line is ordered or nothing is and a CheckoutError lists the lines that failed
+func (o *orderInteraction) Checkout(userID uint, request *order.CheckoutRequest) (*order.Order, error) {
+	actor := fmt.Sprintf("user:%d", userID)
+	var placed *order.Order
+	err := o.unit.Do(func(repos *uow.Repositories) error {
+		lines := requestLines(request.Items)
+		var current *cart.Cart
+		if len(lines) == 0 {
+			var err error
+			if current, err = repos.Carts.GetCart(userID); err != nil {
+				return err
+			}
+			lines = cartLines(current)
+		}
+		if len(lines) == 0 {
+			return ErrEmptyOrder
+		}
+
+		ids := make([]uint, 0, len(lines))
+		for _, line := range lines {
+			ids = append(ids, line.productID)
+		}
+		locked, err := repos.Inventory.LockProducts(ids)
+		if err != nil {
+			return err
+		}
+
+		placed, err = buildOrder(userID, lines, locked)
+		if err != nil {
+			return err
+		}
+		var applied *coupon.Coupon
+		if request.CouponCode != "" {
+			if applied, err = applyCoupon(repos, placed, locked, request.CouponCode); err != nil {
+				return err
+			}
+		}
+		placed.History = []order.OrderStatusChange{{To: order.StatusPending, Actor: actor}}
+		if err := repos.Orders.CreateOrder(placed); err != nil {
+			return err
+		}
+		if applied != nil {
+			err := repos.Coupons.CreateRedemption(&coupon.Redemption{CouponID: applied.ID, UserID: userID, OrderID: placed.ID, Discount: placed.Discount})
+			if err != nil {
+				return err
+			}
+		}
+
+		//**The rows are locked, so the stock checked above is still there to take
+		for _, item := range placed.Items {
+			_, err := repos.Inventory.RecordMovement(&user.StockMovement{
+				ProductID: item.ProductID,
+				Type:      user.MovementSale,
+				Quantity:  -item.Quantity,
+				Reason:    fmt.Sprintf("order %d", placed.ID),
+				Actor:     actor,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if current != nil {
+			return repos.Carts.ClearCart(current.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return placed, nil
+}
+
+// requestLines merges repeated products of a direct checkout into one line each
+func requestLines(items []order.CheckoutItem) []checkoutLine {
+	var lines []checkoutLine
+	index := map[uint]int{}
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			lines[i].quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(lines)
+		lines = append(lines, checkoutLine{productID: item.ProductID, quantity: item.Quantity})
+	}
+	return lines
+}
+
+func cartLines(current *cart.Cart) []checkoutLine {
+	lines := make([]checkoutLine, 0, len(current.Items))
+	for i := range current.Items {
+		item := &current.Items[i]
+		lines = append(lines, checkoutLine{productID: item.ProductID, quantity: item.Quantity, price: &item.UnitPrice})
+	}
+	return lines
+}
+
+// buildOrder checks every line against its locked product and prices the order
+// at the products' current prices. It reports all failing lines at once
+func buildOrder(userID uint, lines []checkoutLine, locked []user.Product) (*order.Order, error) {
+	products := make(map[uint]*user.Product, len(locked))
+	for i := range locked {
+		products[locked[i].ID] = &locked[i]
+	}
+
+	placed := &order.Order{UserID: userID, Status: order.StatusPending}
+	var failed []order.ItemError
+	for _, line := range lines {
+		product, ok := products[line.productID]
+		switch {
+		case !ok:
+			failed = append(failed, order.ItemError{ProductID: line.productID, Code: order.ItemNotFound, Message: "product is no longer sold", Requested: line.quantity})
+			continue
+		case product.Quantity < line.quantity:
+			failed = append(failed, order.ItemError{ProductID: line.productID, Code: order.ItemInsufficientStock,
+				Message: fmt.Sprintf("only %d left in stock", product.Quantity), Requested: line.quantity, Available: product.Quantity})
+			continue
+		case line.price != nil && *line.price != product.Price:
+			failed = append(failed, order.ItemError{ProductID: line.productID, Code: order.ItemPriceChanged,
+				Message: fmt.Sprintf("price changed from %s to %s, review the cart", line.price, product.Price), Requested: line.quantity, Available: product.Quantity})
+			continue
+		case len(placed.Items) > 0 && product.Price.Currency != placed.Total.Currency:
+			failed = append(failed, order.ItemError{ProductID: line.productID, Code: order.ItemCurrencyMismatch,
+				Message: fmt.Sprintf("priced in %s, the order is in %s", product.Price.Currency, placed.Total.Currency), Requested: line.quantity, Available: product.Quantity})
+			continue
+		}
+
+		item := order.OrderItem{
+			ProductID:   product.ID,
+			ProductName: product.ProductName,
+			UnitPrice:   product.Price,
+			Quantity:    line.quantity,
+			LineTotal:   product.Price.Mul(int64(line.quantity)),
+			Discount:    money.Money{Currency: product.Price.Currency},
+		}
+		if len(placed.Items) == 0 {
+			placed.Total = money.Money{Currency: product.Price.Currency}
+			placed.Discount = placed.Total
+		}
+		total, err := placed.Total.Add(item.LineTotal)
+		if err != nil {
+			return nil, err
+		}
+		placed.Total = total
+		placed.Items = append(placed.Items, item)
+	}
+
+	if len(failed) > 0 {
+		return nil, &order.CheckoutError{Items: failed}
+	}
+	placed.Subtotal = placed.Total
+	return placed, nil
+}
+
+// applyCoupon discounts placed with the coupon behind code after checking the
+// user may still use it. The coupon stays locked until the order and its
+// redemption are written, so two checkouts cannot both take its last use
+func applyCoupon(repos *uow.Repositories, placed *order.Order, locked []user.Product, code string) (*coupon.Coupon, error) {
+	applied, err := repos.Coupons.LockByCode(coupon.NormalizeCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, coupon.ErrUnknownCode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	redeemed, redeemedByUser, err := repos.Coupons.CountRedemptions(applied.ID, placed.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applied.CheckRedeemable(time.Now(), redeemed, redeemedByUser); err != nil {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, err)
+	}
+
+	categories := make(map[uint]uint, len(locked))
+	for _, product := range locked {
+		categories[product.ID] = product.CategoryID
+	}
+	lines := make([]pricing.Line, 0, len(placed.Items))
+	for _, item := range placed.Items {
+		lines = append(lines, pricing.Line{ProductID: item.ProductID, CategoryID: categories[item.ProductID],
+			ProductName: item.ProductName, UnitPrice: item.UnitPrice, Quantity: item.Quantity})
+	}
+	//**A coupon on a category covers its subcategories too
+	priced, err := pricing.ExpandCategories(applied, repos.Categories.SubtreeIDs)
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := pricing.Price(lines, priced)
+	if errors.Is(err, pricing.ErrCouponNotApplicable) || errors.Is(err, pricing.ErrMinimumNotMet) {
+		return nil, fmt.Errorf("%w: %w", coupon.ErrCouponRejected, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range placed.Items {
+		placed.Items[i].Discount = breakdown.Lines[i].Discount
+	}
+	placed.CouponCode, placed.Discount, placed.Total = applied.Code, breakdown.Discount, breakdown.Total
+	return applied, nil
+}
+
+func (o *orderInteraction) GetOrder(userID, id uint) (*order.Order, error) {
+	found, err := o.orderRepo.GetOrder(userID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return found, err
+}
+
+func (o *orderInteraction) ListOrders(userID uint, query *order.OrderListQuery) ([]order.Order, int64, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	return o.orderRepo.ListOrders(userID, query.Page, query.Limit)
+}
+
+func (o *orderInteraction) FindOrder(id uint) (*order.Order, error) {
+	found, err := o.orderRepo.FindOrder(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return found, err
+}
+
+func (o *orderInteraction) ListAllOrders(query *order.AdminOrderQuery) ([]order.Order, int64, error) {
+	if query.Status != "" && !order.IsStatus(query.Status) {
+		return nil, 0, ErrUnknownStatus
+	}
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	return o.orderRepo.ListAllOrders(query)
+}
+
+// Transition moves an order to request.Status when the state machine allows it
+// and records who did it. Paid is left to verified payment gateway callbacks and
+// a paid order is only cancelled by refunding it, so the money goes back too
+func (o *orderInteraction) Transition(actor string, id uint, request *order.TransitionRequest) (*order.Order, error) {
+	if !order.IsStatus(request.Status) {
+		return nil, ErrUnknownStatus
+	}
+	if request.Status == order.StatusPaid {
+		return nil, fmt.Errorf("%w: orders are marked paid by the payment gateway", ErrIllegalTransition)
+	}
+
+	var moved *order.Order
+	err := o.unit.Do(func(repos *uow.Repositories) error {
+		current, err := repos.Orders.LockOrder(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if request.Status == order.StatusCancelled && (current.Status == order.StatusPaid || current.Status == order.StatusPacked) {
+			return fmt.Errorf("%w: %s orders are cancelled by refunding their payment", ErrIllegalTransition, current.Status)
+		}
+		if err := ApplyTransition(repos, current, request.Status, actor, request.Note); err != nil {
+			return err
+		}
+		moved, err = repos.Orders.FindOrder(current.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// ApplyTransition moves current, locked inside the unit of work repos belong to,
+// to status and records it. Cancelling puts the ordered stock back. Payments use it
+// too, so an order only becomes paid through the same state machine
+func ApplyTransition(repos *uow.Repositories, current *order.Order, status, actor, note string) error {
+	if !order.CanTransition(current.Status, status) {
+		return fmt.Errorf("%w: %s order cannot become %s", ErrIllegalTransition, current.Status, status)
+	}
+
+	if status == order.StatusCancelled {
+		//**A cancelled order gives its coupon use back
+		if err := repos.Coupons.DeleteOrderRedemption(current.ID); err != nil {
+			return err
+		}
+		for _, item := range current.Items {
+			_, err := repos.Inventory.RecordMovement(&user.StockMovement{
+				ProductID: item.ProductID,
+				Type:      user.MovementReturn,
+				Quantity:  item.Quantity,
+				Reason:    fmt.Sprintf("order %d cancelled", current.ID),
+				Actor:     actor,
+			})
+			//**Products in the trash or purged for good take no stock back
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+	}
+
+	change := &order.OrderStatusChange{From: current.Status, To: status, Actor: actor, Note: note}
+	if err := repos.Orders.UpdateStatus(current, change); err != nil {
+		return err
+	}
+	current.Status = status
+	return nil
+}
+
+func NewOrderUseCase(orderRepo repository.OrderRepository, unit uow.UnitOfWork) OrderUseCase {
+	return &orderInteraction{
+		orderRepo: orderRepo,
+		unit:      unit,
+	}
+}
@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	"github.com/ratheeshkumar25/pkg/order/repository"
+	"github.com/ratheeshkumar25/pkg/uow"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestOrderUseCase wires checkout to an in-memory SQLite database through a real unit of work
+func newTestOrderUseCase(tb testing.TB) (OrderUseCase, *gorm.DB) {
+	tb.Helper()
+	db := testdb.Open(tb, &user.Category{}, &user.Product{}, &user.StockMovement{}, &user.UserRegister{},
+		&cart.Cart{}, &cart.CartItem{}, &order.Order{}, &order.OrderItem{}, &order.OrderStatusChange{}, &coupon.Coupon{}, &coupon.CouponTarget{}, &coupon.Redemption{})
+	return NewOrderUseCase(repository.NewOrderRepository(db), uow.NewUnitOfWork(db)), db
+}
+
+func createProduct(tb testing.TB, db *gorm.DB, name string, quantity int, price money.Money) *user.Product {
+	tb.Helper()
+	product := &user.Product{ProductName: name, Quantity: quantity, Price: price, CategoryID: 1}
+	require.NoError(tb, db.Create(product).Error)
+	return product
+}
+
+func stockOf(tb testing.TB, db *gorm.DB, id uint) int {
+	tb.Helper()
+	var product user.Product
+	require.NoError(tb, db.First(&product, id).Error)
+	return product.Quantity
+}
+
+func TestCheckoutTakesStock(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 5, money.Money{Amount: 1999, Currency: "USD"})
+	sock := createProduct(t, db, "Sock", 10, money.Money{Amount: 250, Currency: "USD"})
+
+	// Repeated products are merged into one line
+	placed, err := orders.Checkout(7, &order.CheckoutRequest{Items: []order.CheckoutItem{
+		{ProductID: shoe.ID, Quantity: 1}, {ProductID: sock.ID, Quantity: 4}, {ProductID: shoe.ID, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusPending, placed.Status)
+	require.Len(t, placed.Items, 2)
+	assert.Equal(t, 2, placed.Items[0].Quantity)
+	assert.Equal(t, "49.98", placed.Total.Decimal())
+
+	assert.Equal(t, 3, stockOf(t, db, shoe.ID))
+	assert.Equal(t, 6, stockOf(t, db, sock.ID))
+	var sales int64
+	require.NoError(t, db.Model(&user.StockMovement{}).Where("type = ?", user.MovementSale).Count(&sales).Error)
+	assert.EqualValues(t, 2, sales)
+
+	found, err := orders.GetOrder(7, placed.ID)
+	require.NoError(t, err)
+	assert.Len(t, found.Items, 2)
+	// Other users cannot see the order
+	_, err = orders.GetOrder(8, placed.ID)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestOrderTransitions(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 5, money.Money{Amount: 1999, Currency: "USD"})
+	placed, err := orders.Checkout(7, &order.CheckoutRequest{Items: []order.CheckoutItem{{ProductID: shoe.ID, Quantity: 2}}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stockOf(t, db, shoe.ID))
+
+	// Orders cannot skip steps or leave a final status
+	_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusShipped})
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+	_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: "lost"})
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+	_, err = orders.Transition("packer", 99, &order.TransitionRequest{Status: order.StatusPacked})
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	// Only the payment gateway marks orders paid
+	_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusPaid})
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+
+	cancelled, err := orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusCancelled, Note: "customer called"})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, cancelled.Status)
+
+	// Cancelling put the stock back and every step is in the history
+	assert.Equal(t, 5, stockOf(t, db, shoe.ID))
+	require.Len(t, cancelled.History, 2)
+	assert.Equal(t, order.OrderStatusChange{ID: cancelled.History[0].ID, OrderID: placed.ID, To: order.StatusPending, Actor: "user:7", CreatedAt: cancelled.History[0].CreatedAt}, cancelled.History[0])
+	assert.Equal(t, order.OrderStatusChange{ID: cancelled.History[1].ID, OrderID: placed.ID, From: order.StatusPending, To: order.StatusCancelled,
+		Actor: "packer", Note: "customer called", CreatedAt: cancelled.History[1].CreatedAt}, cancelled.History[1])
+
+	_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusCancelled})
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+	assert.Equal(t, 5, stockOf(t, db, shoe.ID))
+
+	// Staff filter the orders of every user by status
+	listed, total, err := orders.ListAllOrders(&order.AdminOrderQuery{Status: order.StatusCancelled})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Len(t, listed[0].History, 2)
+	_, total, err = orders.ListAllOrders(&order.AdminOrderQuery{Status: order.StatusPending})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func TestCancellingPaidOrderNeedsRefund(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 5, money.Money{Amount: 1999, Currency: "USD"})
+
+	for _, status := range []string{order.StatusPaid, order.StatusPacked} {
+		placed, err := orders.Checkout(7, &order.CheckoutRequest{Items: []order.CheckoutItem{{ProductID: shoe.ID, Quantity: 1}}})
+		require.NoError(t, err)
+		require.NoError(t, db.Model(&order.Order{}).Where("id = ?", placed.ID).Update("status", status).Error)
+		stock := stockOf(t, db, shoe.ID)
+
+		// The captured payment has to go back through the refund, not stay with the shop
+		_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusCancelled})
+		assert.ErrorIs(t, err, ErrIllegalTransition, status)
+		found, err := orders.FindOrder(placed.ID)
+		require.NoError(t, err)
+		assert.Equal(t, status, found.Status)
+		assert.Equal(t, stock, stockOf(t, db, shoe.ID))
+	}
+}
+
+func TestCheckoutRejectsWholeOrder(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 5, money.Money{Amount: 1999, Currency: "USD"})
+	sock := createProduct(t, db, "Sock", 1, money.Money{Amount: 250, Currency: "USD"})
+	hat := createProduct(t, db, "Hat", 3, money.Money{Amount: 900, Currency: "EUR"})
+
+	// Every failing line is reported and nothing is taken from the line that would fit
+	_, err := orders.Checkout(7, &order.CheckoutRequest{Items: []order.CheckoutItem{
+		{ProductID: shoe.ID, Quantity: 1}, {ProductID: sock.ID, Quantity: 2}, {ProductID: 99, Quantity: 1}, {ProductID: hat.ID, Quantity: 1},
+	}})
+	var rejected *order.CheckoutError
+	require.True(t, errors.As(err, &rejected))
+	require.Len(t, rejected.Items, 3)
+	assert.Equal(t, order.ItemError{ProductID: sock.ID, Code: order.ItemInsufficientStock, Message: "only 1 left in stock", Requested: 2, Available: 1}, rejected.Items[0])
+	assert.Equal(t, order.ItemNotFound, rejected.Items[1].Code)
+	assert.Equal(t, order.ItemCurrencyMismatch, rejected.Items[2].Code)
+
+	assert.Equal(t, 5, stockOf(t, db, shoe.ID))
+	var count int64
+	require.NoError(t, db.Model(&order.Order{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestCheckoutFromCart(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 5, money.Money{Amount: 1999, Currency: "USD"})
+
+	_, err := orders.Checkout(7, &order.CheckoutRequest{})
+	assert.ErrorIs(t, err, ErrEmptyOrder)
+
+	basket := &cart.Cart{UserID: 7}
+	require.NoError(t, db.Create(basket).Error)
+	line := &cart.CartItem{CartID: basket.ID, ProductID: shoe.ID, ProductName: "Shoe", UnitPrice: money.Money{Amount: 1799, Currency: "USD"}, Quantity: 2}
+	require.NoError(t, db.Create(line).Error)
+
+	// The price went up since the item was added, the cart is kept for review
+	_, err = orders.Checkout(7, &order.CheckoutRequest{})
+	var rejected *order.CheckoutError
+	require.True(t, errors.As(err, &rejected))
+	assert.Equal(t, order.ItemPriceChanged, rejected.Items[0].Code)
+
+	require.NoError(t, db.Model(line).Update("unit_price_amount", 1999).Error)
+	placed, err := orders.Checkout(7, &order.CheckoutRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "39.98", placed.Total.Decimal())
+	assert.Equal(t, 3, stockOf(t, db, shoe.ID))
+
+	var left int64
+	require.NoError(t, db.Model(&cart.CartItem{}).Where("cart_id = ?", basket.ID).Count(&left).Error)
+	assert.Zero(t, left)
+}
+
+func TestConcurrentCheckoutsNeverOversell(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 3, money.Money{Amount: 1999, Currency: "USD"})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	placed, rejected := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			_, err := orders.Checkout(userID, &order.CheckoutRequest{Items: []order.CheckoutItem{{ProductID: shoe.ID, Quantity: 1}}})
+			mu.Lock()
+			defer mu.Unlock()
+			var checkoutErr *order.CheckoutError
+			switch {
+			case err == nil:
+				placed++
+			case errors.As(err, &checkoutErr):
+				rejected++
+			default:
+				t.Errorf("unexpected checkout error: %v", err)
+			}
+		}(uint(i + 1))
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, placed)
+	assert.Equal(t, 7, rejected)
+	assert.Equal(t, 0, stockOf(t, db, shoe.ID))
+}
+
+func TestCheckoutRedeemsCoupon(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	shoe := createProduct(t, db, "Shoe", 10, money.Money{Amount: 2000, Currency: "USD"})
+	sock := createProduct(t, db, "Sock", 10, money.Money{Amount: 500, Currency: "USD"})
+	onlyShoes := &coupon.Coupon{Code: "SHOES", Kind: coupon.KindFixed, AmountOff: money.Money{Amount: 1000, Currency: "USD"}, Active: true,
+		MaxRedemptions: 1, Targets: []coupon.CouponTarget{{Kind: coupon.TargetProduct, TargetID: shoe.ID}}}
+	require.NoError(t, db.Create(onlyShoes).Error)
+	items := []order.CheckoutItem{{ProductID: shoe.ID, Quantity: 1}, {ProductID: sock.ID, Quantity: 2}}
+
+	placed, err := orders.Checkout(7, &order.CheckoutRequest{Items: items, CouponCode: "shoes"})
+	require.NoError(t, err)
+	assert.Equal(t, "SHOES", placed.CouponCode)
+	assert.Equal(t, "30.00", placed.Subtotal.Decimal())
+	assert.Equal(t, "10.00", placed.Discount.Decimal())
+	assert.Equal(t, "20.00", placed.Total.Decimal())
+	assert.Equal(t, "10.00", placed.Items[0].Discount.Decimal())
+	assert.Equal(t, "0.00", placed.Items[1].Discount.Decimal())
+
+	// The only use is taken, the rejected checkout takes no stock
+	_, err = orders.Checkout(8, &order.CheckoutRequest{Items: items, CouponCode: "SHOES"})
+	assert.ErrorIs(t, err, coupon.ErrCouponExhausted)
+	assert.Equal(t, 9, stockOf(t, db, shoe.ID))
+
+	// Cancelling the order gives the use back
+	_, err = orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusCancelled})
+	require.NoError(t, err)
+	second, err := orders.Checkout(8, &order.CheckoutRequest{Items: items, CouponCode: "SHOES"})
+	require.NoError(t, err)
+	assert.Equal(t, "20.00", second.Total.Decimal())
+
+	var redemptions []coupon.Redemption
+	require.NoError(t, db.Find(&redemptions).Error)
+	require.Len(t, redemptions, 1)
+	assert.Equal(t, second.ID, redemptions[0].OrderID)
+
+	// Orders without a coupon still carry a zero discount in their currency
+	plain, err := orders.Checkout(7, &order.CheckoutRequest{Items: items[1:]})
+	require.NoError(t, err)
+	assert.Equal(t, "0.00 USD", plain.Discount.String())
+	assert.Equal(t, plain.Subtotal, plain.Total)
+}
+
+func TestCheckoutCategoryCouponCoversSubcategories(t *testing.T) {
+	orders, db := newTestOrderUseCase(t)
+	// createProduct puts products in category 1
+	require.NoError(t, db.Create(&user.Category{Name: "Accessories"}).Error)
+	shoes := &user.Category{Name: "Shoes"}
+	require.NoError(t, db.Create(shoes).Error)
+	running := &user.Category{Name: "Running", ParentID: &shoes.ID}
+	require.NoError(t, db.Create(running).Error)
+	shoe := createProduct(t, db, "Trail shoe", 10, money.Money{Amount: 2000, Currency: "USD"})
+	require.NoError(t, db.Model(shoe).Update("category_id", running.ID).Error)
+	sock := createProduct(t, db, "Sock", 10, money.Money{Amount: 500, Currency: "USD"})
+	require.NoError(t, db.Create(&coupon.Coupon{Code: "SHOES", Kind: coupon.KindPercentage, PercentOff: 25, Active: true,
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: shoes.ID}}}).Error)
+
+	placed, err := orders.Checkout(7, &order.CheckoutRequest{CouponCode: "SHOES", Items: []order.CheckoutItem{
+		{ProductID: shoe.ID, Quantity: 1}, {ProductID: sock.ID, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "5.00", placed.Items[0].Discount.Decimal())
+	assert.Equal(t, "0.00", placed.Items[1].Discount.Decimal())
+	assert.Equal(t, "20.00", placed.Total.Decimal())
+}
@@ -0,0 +1,54 @@
+package pagination
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	signer := NewCursorSigner([]byte("secret"))
+	createdAt := time.Date(2024, 1, 1, 8, 30, 0, 123456000, time.UTC)
+
+	values := []interface{}{createdAt, "Shoe", 42, float32(18.3)}
+	expected := []interface{}{createdAt, "Shoe", int64(42), 18.3}
+	for i, value := range values {
+		cursor, err := NewCursor("-price", "filters", value, 7)
+		assert.NoError(t, err)
+		token, err := signer.Encode(cursor)
+		assert.NoError(t, err)
+
+		decoded, err := signer.Decode(token)
+		assert.NoError(t, err)
+		assert.Equal(t, "-price", decoded.Sort)
+		assert.Equal(t, "filters", decoded.Filter)
+		assert.Equal(t, uint(7), decoded.ID)
+		key, err := decoded.KeyValue()
+		assert.NoError(t, err)
+		assert.Equal(t, expected[i], key)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	signer := NewCursorSigner([]byte("secret"))
+	cursor, _ := NewCursor("id", "", uint(10), 10)
+	token, _ := signer.Encode(cursor)
+
+	// A cursor signed with another key or edited by the client is refused
+	_, err := NewCursorSigner([]byte("other")).Decode(token)
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	forged, _ := NewCursorSigner([]byte("other")).Encode(Cursor{Sort: "id", Kind: "i", Value: "1", ID: 1})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(token, ".")
+	_, err = signer.Decode(payload + "." + signature)
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	for _, garbage := range []string{"", "abc", "abc.def", "."} {
+		_, err = signer.Decode(garbage)
+		assert.True(t, errors.Is(err, ErrInvalidCursor), garbage)
+	}
+}
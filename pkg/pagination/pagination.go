@@ -0,0 +1,172 @@
+// Package pagination holds the page, sort, cursor and link helpers shared by the list endpoints.
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidSort is returned for sort fields outside the allow-list
+var ErrInvalidSort = errors.New("invalid sort parameter")
+
+// Page describes where a page of results sits in the full list
+type Page struct {
+	// Total is the number of matching rows, -1 when it was not counted
+	Total int64
+	// NextCursor continues after the last row of the page, empty on the last page
+	NextCursor string
+}
+
+// SortKey is one field of a parsed sort parameter
+type SortKey struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Normalize clamps page and limit to usable values
+func Normalize(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return page, limit
+}
+
+// Offset is the number of rows to skip for page
+func Offset(page, limit int) int {
+	return (page - 1) * limit
+}
+
+// TotalPages is the number of pages needed for total rows
+func TotalPages(total int64, limit int) int {
+	if limit < 1 || total <= 0 {
+		return 0
+	}
+	return int((total + int64(limit) - 1) / int64(limit))
+}
+
+// ParseSort reads a sort parameter such as "-price,name". columns maps the public
+// field names to columns and a leading "-" sorts descending.
+func ParseSort(raw string, columns map[string]string, fallback string) ([]SortKey, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = fallback
+	}
+
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimLeft(field, "+-")
+
+		column, ok := columns[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSort, field)
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		keys = append(keys, SortKey{Field: field, Column: column, Desc: desc})
+	}
+	return keys, nil
+}
+
+// SortString is the normalized form of keys, cursors remember it
+func SortString(keys []SortKey) string {
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Desc {
+			fields = append(fields, "-"+key.Field)
+		} else {
+			fields = append(fields, key.Field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// OrderBy builds the ORDER BY clause for keys. tieBreaker follows the direction
+// of the last key so rows with equal values keep a stable order across pages.
+func OrderBy(keys []SortKey, tieBreaker string) string {
+	clauses := make([]string, 0, len(keys)+1)
+	desc := false
+	for _, key := range keys {
+		clauses = append(clauses, key.Column+direction(key.Desc))
+		desc = key.Desc
+		if key.Column == tieBreaker {
+			return strings.Join(clauses, ", ")
+		}
+	}
+	return strings.Join(append(clauses, tieBreaker+direction(desc)), ", ")
+}
+
+// KeysetCondition is the WHERE clause selecting the rows after a cursor for a
+// single sort key, it takes the cursor's key value and ID as arguments
+func KeysetCondition(key SortKey, tieBreaker string) (string, bool) {
+	op := ">"
+	if key.Desc {
+		op = "<"
+	}
+	if key.Column == tieBreaker {
+		return fmt.Sprintf("%s %s ?", tieBreaker, op), false
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", key.Column, tieBreaker, op), true
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// Links returns the next and previous page URLs relative to current, nil when there is none
+func Links(current *url.URL, page, limit int, total int64) (next, prev *string) {
+	build := func(target int) *string {
+		query := current.Query()
+		query.Del("cursor")
+		query.Set("page", strconv.Itoa(target))
+		query.Set("limit", strconv.Itoa(limit))
+		return relative(current, query)
+	}
+
+	if page < TotalPages(total, limit) {
+		next = build(page + 1)
+	}
+	if page > 1 {
+		prev = build(page - 1)
+	}
+	return next, prev
+}
+
+// CursorLink returns the URL continuing after cursor, nil when there is none
+func CursorLink(current *url.URL, limit int, cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	query := current.Query()
+	query.Del("page")
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+	return relative(current, query)
+}
+
+func relative(current *url.URL, query url.Values) *string {
+	link := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	value := link.String()
+	return &value
+}
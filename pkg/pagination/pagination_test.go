@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	page, limit := Normalize(0, 0)
+	assert.Equal(t, 1, page)
+	assert.Equal(t, DefaultLimit, limit)
+
+	page, limit = Normalize(3, 1000)
+	assert.Equal(t, 3, page)
+	assert.Equal(t, MaxLimit, limit)
+	assert.Equal(t, 200, Offset(3, MaxLimit))
+}
+
+func TestOrderBy(t *testing.T) {
+	allowed := map[string]string{"name": "product_name", "price": "price", "id": "id"}
+
+	keys, err := ParseSort("-price,name", allowed, "id")
+	assert.NoError(t, err)
+	assert.Equal(t, "price DESC, product_name ASC, id ASC", OrderBy(keys, "id"))
+	assert.Equal(t, "-price,name", SortString(keys))
+
+	// The tie-breaker follows a single key so (key, id) can seek through an index
+	keys, err = ParseSort("-price", allowed, "id")
+	assert.NoError(t, err)
+	assert.Equal(t, "price DESC, id DESC", OrderBy(keys, "id"))
+	condition, withKey := KeysetCondition(keys[0], "id")
+	assert.Equal(t, "(price, id) < (?, ?)", condition)
+	assert.True(t, withKey)
+
+	keys, err = ParseSort("", allowed, "-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "id DESC", OrderBy(keys, "id"))
+	condition, withKey = KeysetCondition(keys[0], "id")
+	assert.Equal(t, "id < ?", condition)
+	assert.False(t, withKey)
+
+	// Anything outside the allow-list is rejected instead of reaching the SQL
+	_, err = ParseSort("password", allowed, "id")
+	assert.True(t, errors.Is(err, ErrInvalidSort))
+	_, err = ParseSort("price;DROP TABLE products", allowed, "id")
+	assert.True(t, errors.Is(err, ErrInvalidSort))
+}
+
+func TestLinks(t *testing.T) {
+	current, _ := url.Parse("/getproduct?name=shoe&page=2&limit=10")
+
+	next, prev := Links(current, 2, 10, 35)
+	assert.Equal(t, "/getproduct?limit=10&name=shoe&page=3", *next)
+	assert.Equal(t, "/getproduct?limit=10&name=shoe&page=1", *prev)
+
+	next, prev = Links(current, 4, 10, 35)
+	assert.Nil(t, next)
+	assert.NotNil(t, prev)
+
+	next, prev = Links(current, 1, 10, 0)
+	assert.Nil(t, next)
+	assert.Nil(t, prev)
+	assert.Equal(t, 4, TotalPages(35, 10))
+}
+
+func TestCursorLink(t *testing.T) {
+	current, _ := url.Parse("/getproduct?name=shoe&page=2&limit=10")
+
+	next := CursorLink(current, 10, "abc.def")
+	assert.Equal(t, "/getproduct?cursor=abc.def&limit=10&name=shoe", *next)
+	assert.Nil(t, CursorLink(current, 10, ""))
+}
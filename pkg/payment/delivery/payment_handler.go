@@ -0,0 +1,124 @@
+package delivery
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/payment/usecase"
+)
+
+// SignatureHeader carries the gateway's signature of the raw webhook body
+const SignatureHeader = "Payment-Signature"
+
+// maxWebhookBytes bounds the webhook body, gateway events are small
+const maxWebhookBytes = 64 << 10
+
+type PaymentHandler struct {
+	paymentUseCase usecase.PaymentUseCase
+}
+
+type PaymentUseCases interface {
+	CreatePaymentHandler(c *gin.Context)
+	WebhookHandler(c *gin.Context)
+	RefundHandler(c *gin.Context)
+}
+
+// CreatePaymentHandler starts paying one of the caller's pending orders
+func (h *PaymentHandler) CreatePaymentHandler(c *gin.Context) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only users can pay orders"})
+		return
+	}
+	id, ok := orderID(c)
+	if !ok {
+		return
+	}
+
+	intent, err := h.paymentUseCase.CreateIntent(caller.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrOrderNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrOrderNotPayable):
+			c.JSON(409, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrPaymentDeclined):
+			c.JSON(402, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to start payment"})
+		}
+		return
+	}
+	c.JSON(201, gin.H{"payment": NewPaymentResponse(intent)})
+}
+
+// WebhookHandler receives gateway callbacks. Anything but a 2xx makes the
+// gateway retry, so callbacks that were already applied are still acknowledged
+func (h *PaymentHandler) WebhookHandler(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.JSON(413, gin.H{"error": "webhook body too large"})
+		return
+	}
+
+	duplicate, err := h.paymentUseCase.HandleWebhook(payload, c.GetHeader(SignatureHeader))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidSignature):
+			c.JSON(401, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrUnknownIntent):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrAmountMismatch):
+			c.JSON(422, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to process webhook"})
+		}
+		return
+	}
+	c.JSON(200, gin.H{"received": true, "duplicate": duplicate})
+}
+
+// RefundHandler pays back the order's successful payment, booked against the calling admin
+func (h *PaymentHandler) RefundHandler(c *gin.Context) {
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "insufficient permissions"})
+		return
+	}
+	id, ok := orderID(c)
+	if !ok {
+		return
+	}
+	intent, err := h.paymentUseCase.Refund(actor.Username, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrOrderNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrNothingToRefund):
+			c.JSON(409, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to refund payment"})
+		}
+		return
+	}
+	c.JSON(202, gin.H{"payment": NewPaymentResponse(intent)})
+}
+
+func orderID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid order ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func NewPaymentHandler(paymentUseCase usecase.PaymentUseCase) *PaymentHandler {
+	return &PaymentHandler{
+		paymentUseCase: paymentUseCase,
+	}
+}
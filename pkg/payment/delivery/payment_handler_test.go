@@ -0,0 +1,99 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
+	"github.com/ratheeshkumar25/pkg/payment/usecase"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockPaymentUseCase is a mock implementation of the PaymentUseCase interface
+type MockPaymentUseCase struct {
+	mock.Mock
+}
+
+func (m *MockPaymentUseCase) CreateIntent(userID, orderID uint) (*payment.PaymentIntent, error) {
+	args := m.Called(userID, orderID)
+	return args.Get(0).(*payment.PaymentIntent), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) HandleWebhook(payload []byte, signature string) (bool, error) {
+	args := m.Called(string(payload), signature)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentUseCase) Refund(actor string, orderID uint) (*payment.PaymentIntent, error) {
+	args := m.Called(actor, orderID)
+	return args.Get(0).(*payment.PaymentIntent), args.Error(1)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	mockPaymentUseCase := new(MockPaymentUseCase)
+	handler := NewPaymentHandler(mockPaymentUseCase)
+
+	r := gin.Default()
+	r.POST("/payments/webhook", handler.WebhookHandler)
+
+	mockPaymentUseCase.On("HandleWebhook", `{"id":"evt_1"}`, "sha256=good").Return(true, nil)
+	mockPaymentUseCase.On("HandleWebhook", `{"id":"evt_1"}`, "sha256=bad").Return(false, usecase.ErrInvalidSignature)
+
+	// The raw body and the signature header reach the use case untouched
+	req, _ := http.NewRequest("POST", "/payments/webhook", bytes.NewBufferString(`{"id":"evt_1"}`))
+	req.Header.Set(SignatureHeader, "sha256=good")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"received":true,"duplicate":true}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/payments/webhook", bytes.NewBufferString(`{"id":"evt_1"}`))
+	req.Header.Set(SignatureHeader, "sha256=bad")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"webhook signature is invalid"}`, w.Body.String())
+
+	mockPaymentUseCase.AssertExpectations(t)
+}
+
+func TestCreatePaymentHandler(t *testing.T) {
+	mockPaymentUseCase := new(MockPaymentUseCase)
+	handler := NewPaymentHandler(mockPaymentUseCase)
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 7}}
+	r := gin.Default()
+	r.POST("/orders/:id/payments", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.CreatePaymentHandler)
+
+	intent := &payment.PaymentIntent{ID: 2, OrderID: 4, Provider: "fake", ProviderRef: "fake_pi_1", ClientSecret: "fake_pi_1_secret",
+		Amount: money.Money{Amount: 2000, Currency: "USD"}, Status: payment.IntentProcessing}
+	mockPaymentUseCase.On("CreateIntent", uint(7), uint(4)).Return(intent, nil)
+	mockPaymentUseCase.On("CreateIntent", uint(7), uint(5)).
+		Return((*payment.PaymentIntent)(nil), fmt.Errorf("%w: payment declined: test amount 20.02 USD", usecase.ErrPaymentDeclined))
+
+	req, _ := http.NewRequest("POST", "/orders/4/payments", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"payment":{
+		"id":2,"order_id":4,"provider":"fake","provider_ref":"fake_pi_1","client_secret":"fake_pi_1_secret",
+		"amount":{"amount":"20.00","currency":"USD"},"status":"processing",
+		"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"
+	}}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/orders/5/payments", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+
+	mockPaymentUseCase.AssertExpectations(t)
+}
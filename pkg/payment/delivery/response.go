@@ -0,0 +1,38 @@
+package delivery
+
+import (
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
+)
+
+// PaymentResponse is a payment intent, client_secret is only present in the
+// response that started the payment
+type PaymentResponse struct {
+	ID            uint        `json:"id"`
+	OrderID       uint        `json:"order_id"`
+	Provider      string      `json:"provider"`
+	ProviderRef   string      `json:"provider_ref"`
+	Amount        money.Money `json:"amount"`
+	Status        string      `json:"status"`
+	FailureReason string      `json:"failure_reason,omitempty"`
+	ClientSecret  string      `json:"client_secret,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+func NewPaymentResponse(p *payment.PaymentIntent) PaymentResponse {
+	return PaymentResponse{
+		ID:            p.ID,
+		OrderID:       p.OrderID,
+		Provider:      p.Provider,
+		ProviderRef:   p.ProviderRef,
+		Amount:        p.Amount,
+		Status:        p.Status,
+		FailureReason: p.FailureReason,
+		ClientSecret:  p.ClientSecret,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+}
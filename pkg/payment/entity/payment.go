@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+// Statuses of a payment intent. Only the gateway's callbacks move an intent out
+// of processing, the API call that started it never marks it paid
+const (
+	IntentProcessing    = "processing"
+	IntentSucceeded     = "succeeded"
+	IntentFailed        = "failed"
+	IntentRefundPending = "refund_pending"
+	IntentRefunded      = "refunded"
+)
+
+// PaymentIntent is one attempt to pay an order through a gateway. Every attempt
+// gets its own provider reference, attempts that never reached the gateway have none
+type PaymentIntent struct {
+	ID            uint        `gorm:"primarykey" json:"id"`
+	OrderID       uint        `gorm:"not null;index" json:"order_id"`
+	Provider      string      `gorm:"type:varchar(32);not null;uniqueIndex:idx_payment_intents_provider_ref_key,priority:1" json:"provider"`
+	ProviderRef   string      `gorm:"type:varchar(255);uniqueIndex:idx_payment_intents_provider_ref_key,priority:2,where:provider_ref <> ''" json:"provider_ref"`
+	Amount        money.Money `gorm:"embedded" json:"amount"`
+	Status        string      `gorm:"type:varchar(32);not null" json:"status"`
+	FailureReason string      `gorm:"type:text" json:"failure_reason,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	// ClientSecret is handed to the buyer once and never stored
+	ClientSecret string `gorm:"-" json:"client_secret,omitempty"`
+}
+
+// WebhookEvent remembers every gateway callback that was applied, a callback
+// delivered twice finds its row and is acknowledged without being applied again
+type WebhookEvent struct {
+	ID          uint   `gorm:"primarykey"`
+	Provider    string `gorm:"type:varchar(32);not null;uniqueIndex:idx_webhook_events_provider_event,priority:1"`
+	EventID     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_webhook_events_provider_event,priority:2"`
+	Type        string `gorm:"type:varchar(64);not null"`
+	ProviderRef string `gorm:"type:varchar(255);not null"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+// FakeDeclineCents makes the fake gateway decline every amount whose minor
+// units end in it, 10.02 USD is declined and 10.00 USD is not
+const FakeDeclineCents = 2
+
+// FakeGateway is an in-process provider for development and tests. References
+// are derived from the idempotency key so the same input always gives the same
+// output, and webhooks are signed with HMAC-SHA256 over the raw payload
+type FakeGateway struct {
+	secret []byte
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount   money.Money
+	captured bool
+	refunded int64
+}
+
+func (f *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (f *FakeGateway) Authorize(request AuthorizeRequest) (*Authorization, error) {
+	if request.Amount.Amount%100 == FakeDeclineCents {
+		return nil, fmt.Errorf("%w: test amount %s", ErrDeclined, request.Amount)
+	}
+
+	sum := sha256.Sum256([]byte(request.IdempotencyKey))
+	ref := "fake_pi_" + hex.EncodeToString(sum[:8])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.payments[ref]; !ok {
+		f.payments[ref] = &fakePayment{amount: request.Amount}
+	}
+	return &Authorization{ProviderRef: ref, ClientSecret: ref + "_secret_" + hex.EncodeToString(sum[8:16])}, nil
+}
+
+func (f *FakeGateway) Capture(providerRef string, amount money.Money) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payment, ok := f.payments[providerRef]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPayment, providerRef)
+	}
+	if amount != payment.amount {
+		return fmt.Errorf("capturing %s of a %s authorization", amount, payment.amount)
+	}
+	payment.captured = true
+	return nil
+}
+
+func (f *FakeGateway) Refund(providerRef string, amount money.Money) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payment, ok := f.payments[providerRef]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPayment, providerRef)
+	}
+	if !payment.captured {
+		return fmt.Errorf("refunding %s: nothing was captured", providerRef)
+	}
+	if amount.Currency != payment.amount.Currency || payment.refunded+amount.Amount > payment.amount.Amount {
+		return fmt.Errorf("refunding %s exceeds the captured %s", amount, payment.amount)
+	}
+	payment.refunded += amount.Amount
+	return nil
+}
+
+func (f *FakeGateway) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !hmac.Equal(given, f.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&event); err != nil {
+		return nil, fmt.Errorf("decoding webhook: %w", err)
+	}
+	if event.ID == "" || event.Type == "" || event.ProviderRef == "" {
+		return nil, fmt.Errorf("decoding webhook: id, type and provider_ref are required")
+	}
+	return &event, nil
+}
+
+// SignEvent encodes event the way the fake provider posts it and returns the
+// value of its signature header, use it to simulate callbacks
+func (f *FakeGateway) SignEvent(event Event) ([]byte, string, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, "sha256=" + hex.EncodeToString(f.mac(payload)), nil
+}
+
+func (f *FakeGateway) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func NewFakeGateway(secret []byte) *FakeGateway {
+	return &FakeGateway{
+		secret:   secret,
+		payments: map[string]*fakePayment{},
+	}
+}
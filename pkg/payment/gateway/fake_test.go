@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeGatewayIsDeterministic(t *testing.T) {
+	fake := NewFakeGateway([]byte("secret"))
+	amount := money.Money{Amount: 1999, Currency: "USD"}
+
+	first, err := fake.Authorize(AuthorizeRequest{Amount: amount, Reference: "order 1", IdempotencyKey: "order-1"})
+	require.NoError(t, err)
+	again, err := NewFakeGateway([]byte("secret")).Authorize(AuthorizeRequest{Amount: amount, Reference: "order 1", IdempotencyKey: "order-1"})
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	_, err = fake.Authorize(AuthorizeRequest{Amount: money.Money{Amount: 1002, Currency: "USD"}, IdempotencyKey: "order-2"})
+	assert.ErrorIs(t, err, ErrDeclined)
+
+	// Refunds need a capture and cannot exceed it
+	assert.Error(t, fake.Refund(first.ProviderRef, amount))
+	require.NoError(t, fake.Capture(first.ProviderRef, amount))
+	require.NoError(t, fake.Refund(first.ProviderRef, money.Money{Amount: 999, Currency: "USD"}))
+	assert.Error(t, fake.Refund(first.ProviderRef, amount))
+	assert.ErrorIs(t, fake.Capture("fake_pi_missing", amount), ErrUnknownPayment)
+}
+
+func TestFakeGatewayWebhookSignature(t *testing.T) {
+	fake := NewFakeGateway([]byte("secret"))
+	event := Event{ID: "evt_1", Type: EventCaptured, ProviderRef: "fake_pi_1", Amount: money.Money{Amount: 1999, Currency: "USD"}}
+
+	payload, signature, err := fake.SignEvent(event)
+	require.NoError(t, err)
+	verified, err := fake.VerifyWebhook(payload, signature)
+	require.NoError(t, err)
+	assert.Equal(t, &event, verified)
+
+	// Tampered payloads, other secrets and missing signatures are refused
+	_, err = fake.VerifyWebhook(append(payload[:len(payload)-1], ' ', '}'), signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = NewFakeGateway([]byte("other")).VerifyWebhook(payload, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = fake.VerifyWebhook(payload, "")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
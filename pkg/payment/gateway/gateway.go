@@ -0,0 +1,80 @@
+// Package gateway talks to payment providers. Providers implement PaymentGateway,
+// the rest of the application never sees which one is configured
+package gateway
+
+import (
+	"errors"
+	"log"
+
+	"github.com/ratheeshkumar25/pkg/config"
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+var (
+	ErrDeclined         = errors.New("payment declined")
+	ErrUnknownPayment   = errors.New("payment unknown to the gateway")
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+)
+
+// Types of the events providers send to the webhook
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventRefunded = "payment.refunded"
+)
+
+// AuthorizeRequest holds funds for Reference, retrying with the same
+// IdempotencyKey returns the first authorization instead of a second one
+type AuthorizeRequest struct {
+	Amount         money.Money
+	Reference      string
+	IdempotencyKey string
+}
+
+// Authorization is the provider's handle on held funds. ClientSecret lets the
+// buyer's browser confirm the payment with the provider directly
+type Authorization struct {
+	ProviderRef  string
+	ClientSecret string
+}
+
+// Event is a verified webhook callback, ID is unique per event and provider
+type Event struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	ProviderRef string      `json:"provider_ref"`
+	Amount      money.Money `json:"amount"`
+	Reason      string      `json:"reason,omitempty"`
+}
+
+type PaymentGateway interface {
+	// Name identifies the provider in stored payments
+	Name() string
+	Authorize(request AuthorizeRequest) (*Authorization, error)
+	// Capture asks for the held funds, the provider confirms it with an EventCaptured
+	// callback. Capturing a captured payment again takes nothing more
+	Capture(providerRef string, amount money.Money) error
+	// Refund gives amount back, the provider confirms it with an EventRefunded callback
+	Refund(providerRef string, amount money.Money) error
+	// VerifyWebhook checks that payload was signed by the provider and decodes it
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// NewGatewayFromEnv picks the gateway configured by PAYMENT_GATEWAY, only fake is
+// built in so far. There is no default so a deployment never takes fake payments by accident
+func NewGatewayFromEnv() PaymentGateway {
+	switch kind := config.String("PAYMENT_GATEWAY", ""); kind {
+	case "":
+		log.Fatal("PAYMENT_GATEWAY must be set")
+		return nil
+	case "fake":
+		secret := config.String("PAYMENT_WEBHOOK_SECRET", "")
+		if secret == "" {
+			log.Fatal("PAYMENT_WEBHOOK_SECRET must be set")
+		}
+		return NewFakeGateway([]byte(secret))
+	default:
+		log.Fatalf("unsupported PAYMENT_GATEWAY %q", kind)
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentRepository interface {
+	CreateIntent(intent *payment.PaymentIntent) error
+	SaveIntent(intent *payment.PaymentIntent) error
+	LockIntentByRef(provider, providerRef string) (*payment.PaymentIntent, error)
+	FindOrderIntent(orderID uint, statuses ...string) (*payment.PaymentIntent, error)
+	RecordEvent(event *payment.WebhookEvent) (bool, error)
+}
+
+type PaymentDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (p *PaymentDataBaseInteraction) CreateIntent(intent *payment.PaymentIntent) error {
+	if err := p.DB.Create(intent).Error; err != nil {
+		return fmt.Errorf("creating payment intent: %w", err)
+	}
+	return nil
+}
+
+func (p *PaymentDataBaseInteraction) SaveIntent(intent *payment.PaymentIntent) error {
+	if err := p.DB.Save(intent).Error; err != nil {
+		return fmt.Errorf("saving payment intent: %w", err)
+	}
+	return nil
+}
+
+// LockIntentByRef finds the intent a gateway callback is about, references are
+// unique per provider, and holds its row until the surrounding transaction ends
+func (p *PaymentDataBaseInteraction) LockIntentByRef(provider, providerRef string) (*payment.PaymentIntent, error) {
+	var intent payment.PaymentIntent
+	err := p.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("provider = ? AND provider_ref = ?", provider, providerRef).First(&intent).Error
+	if err != nil {
+		return nil, fmt.Errorf("finding payment intent %s: %w", providerRef, err)
+	}
+	return &intent, nil
+}
+
+// FindOrderIntent returns the newest intent of the order in one of statuses
+func (p *PaymentDataBaseInteraction) FindOrderIntent(orderID uint, statuses ...string) (*payment.PaymentIntent, error) {
+	var intent payment.PaymentIntent
+	err := p.DB.Where("order_id = ? AND status IN ?", orderID, statuses).Order("id DESC").First(&intent).Error
+	if err != nil {
+		return nil, fmt.Errorf("finding payment of order %d: %w", orderID, err)
+	}
+	return &intent, nil
+}
+
+// RecordEvent stores a callback and reports false when it was stored before
+func (p *PaymentDataBaseInteraction) RecordEvent(event *payment.WebhookEvent) (bool, error) {
+	result := p.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, fmt.Errorf("recording webhook event: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepository {
+	return &PaymentDataBaseInteraction{
+		DB: db,
+	}
+}
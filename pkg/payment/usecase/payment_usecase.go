@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	orderUseCase "github.com/ratheeshkumar25/pkg/order/usecase"
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
+	"github.com/ratheeshkumar25/pkg/payment/gateway"
+	"github.com/ratheeshkumar25/pkg/uow"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrOrderNotPayable  = errors.New("only pending orders can be paid")
+	ErrPaymentDeclined  = errors.New("payment was declined")
+	ErrNothingToRefund  = errors.New("order has no successful payment to refund")
+	ErrUnknownIntent    = errors.New("webhook refers to an unknown payment")
+	ErrAmountMismatch   = errors.New("webhook amount does not match the payment")
+	ErrInvalidSignature = gateway.ErrInvalidSignature
+)
+
+type PaymentUseCase interface {
+	CreateIntent(userID, orderID uint) (*payment.PaymentIntent, error)
+	HandleWebhook(payload []byte, signature string) (bool, error)
+	Refund(actor string, orderID uint) (*payment.PaymentIntent, error)
+}
+
+type paymentInteraction struct {
+	gateway gateway.PaymentGateway
+	unit    uow.UnitOfWork
+}
+
+// CreateIntent authorizes and captures the total of a pending order. The order
+// stays pending until the gateway confirms the capture through the webhook, asking
+// again while that is outstanding resumes the same intent with the gateway
+func (p *paymentInteraction) CreateIntent(userID, orderID uint) (*payment.PaymentIntent, error) {
+	var intent *payment.PaymentIntent
+	err := p.unit.Do(func(repos *uow.Repositories) error {
+		current, err := repos.Orders.LockOrder(orderID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && current.UserID != userID) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.Status != order.StatusPending {
+			return ErrOrderNotPayable
+		}
+
+		intent, err = repos.Payments.FindOrderIntent(current.ID, payment.IntentProcessing)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		intent = &payment.PaymentIntent{OrderID: current.ID, Provider: p.gateway.Name(), Amount: current.Total, Status: payment.IntentProcessing}
+		return repos.Payments.CreateIntent(intent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//**The gateway is called after the order row was released, the processing
+	//**intent keeps a second request from starting another payment meanwhile.
+	//**The key belongs to the attempt: a request that finds the intent still
+	//**processing, because an earlier one crashed or failed to save it, gets the
+	//**same authorization back, while a retry after a decline is a new attempt
+	authorization, err := p.gateway.Authorize(gateway.AuthorizeRequest{
+		Amount:         intent.Amount,
+		Reference:      fmt.Sprintf("order %d", intent.OrderID),
+		IdempotencyKey: fmt.Sprintf("payment-intent-%d", intent.ID),
+	})
+	if err == nil {
+		intent.ProviderRef, intent.ClientSecret = authorization.ProviderRef, authorization.ClientSecret
+		err = p.gateway.Capture(intent.ProviderRef, intent.Amount)
+	}
+	//**Failed attempts are kept so support can see them
+	if err != nil {
+		intent.Status, intent.FailureReason = payment.IntentFailed, err.Error()
+	}
+	if saveErr := p.unit.Do(func(repos *uow.Repositories) error { return repos.Payments.SaveIntent(intent) }); saveErr != nil {
+		return nil, saveErr
+	}
+	switch {
+	case errors.Is(err, gateway.ErrDeclined):
+		return intent, fmt.Errorf("%w: %s", ErrPaymentDeclined, intent.FailureReason)
+	case err != nil:
+		return nil, fmt.Errorf("starting payment: %w", err)
+	}
+	return intent, nil
+}
+
+// HandleWebhook applies a verified gateway callback once. It reports true for a
+// callback that was already applied, which is acknowledged and otherwise ignored
+func (p *paymentInteraction) HandleWebhook(payload []byte, signature string) (bool, error) {
+	event, err := p.gateway.VerifyWebhook(payload, signature)
+	if err != nil {
+		return false, err
+	}
+
+	duplicate := false
+	err = p.unit.Do(func(repos *uow.Repositories) error {
+		recorded, err := repos.Payments.RecordEvent(&payment.WebhookEvent{
+			Provider:    p.gateway.Name(),
+			EventID:     event.ID,
+			Type:        event.Type,
+			ProviderRef: event.ProviderRef,
+		})
+		if err != nil {
+			return err
+		}
+		if !recorded {
+			duplicate = true
+			return nil
+		}
+
+		intent, err := repos.Payments.LockIntentByRef(p.gateway.Name(), event.ProviderRef)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownIntent
+		}
+		if err != nil {
+			return err
+		}
+		return p.apply(repos, intent, event)
+	})
+	return duplicate, err
+}
+
+// apply moves the intent, and its order where the state machine allows, to what
+// the event reports. Events of types the shop does not act on are only recorded
+func (p *paymentInteraction) apply(repos *uow.Repositories, intent *payment.PaymentIntent, event *gateway.Event) error {
+	actor, note := "gateway:"+p.gateway.Name(), "payment "+intent.ProviderRef
+	switch event.Type {
+	case gateway.EventCaptured:
+		if event.Amount != intent.Amount {
+			return fmt.Errorf("%w: %s reported, %s expected", ErrAmountMismatch, event.Amount, intent.Amount)
+		}
+		current, err := repos.Orders.LockOrder(intent.OrderID)
+		if err != nil {
+			return err
+		}
+		//**Money arriving for an order that was cancelled or paid meanwhile goes straight back
+		if current.Status != order.StatusPending {
+			log.Printf("payment %s captured for %s order %d, refunding it", intent.ProviderRef, current.Status, current.ID)
+			return p.refund(repos, intent)
+		}
+		intent.Status = payment.IntentSucceeded
+		if err := repos.Payments.SaveIntent(intent); err != nil {
+			return err
+		}
+		return orderUseCase.ApplyTransition(repos, current, order.StatusPaid, actor, note)
+	case gateway.EventFailed:
+		intent.Status, intent.FailureReason = payment.IntentFailed, event.Reason
+		return repos.Payments.SaveIntent(intent)
+	case gateway.EventRefunded:
+		intent.Status = payment.IntentRefunded
+		if err := repos.Payments.SaveIntent(intent); err != nil {
+			return err
+		}
+		current, err := repos.Orders.LockOrder(intent.OrderID)
+		if err != nil {
+			return err
+		}
+		//**Shipped orders become refunded, ones still in the warehouse must not be
+		//**sent out anymore and cancelled ones stay cancelled
+		switch {
+		case order.CanTransition(current.Status, order.StatusRefunded):
+			return orderUseCase.ApplyTransition(repos, current, order.StatusRefunded, actor, note)
+		case order.CanTransition(current.Status, order.StatusCancelled):
+			return orderUseCase.ApplyTransition(repos, current, order.StatusCancelled, actor, note)
+		}
+	}
+	return nil
+}
+
+// Refund asks the gateway to pay back the order's successful payment, the
+// intent becomes refunded once the gateway confirms it. An order that has not
+// shipped yet is cancelled with it so it can no longer be fulfilled
+func (p *paymentInteraction) Refund(actor string, orderID uint) (*payment.PaymentIntent, error) {
+	var intent *payment.PaymentIntent
+	err := p.unit.Do(func(repos *uow.Repositories) error {
+		current, err := repos.Orders.LockOrder(orderID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		intent, err = repos.Payments.FindOrderIntent(orderID, payment.IntentSucceeded)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNothingToRefund
+		}
+		if err != nil {
+			return err
+		}
+		if order.CanTransition(current.Status, order.StatusCancelled) {
+			if err := orderUseCase.ApplyTransition(repos, current, order.StatusCancelled, actor, "refunded before shipping"); err != nil {
+				return err
+			}
+		}
+		return p.refund(repos, intent)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+// refund asks the gateway for the money back inside the caller's transaction,
+// if the gateway refuses the intent and the order stay as they were
+func (p *paymentInteraction) refund(repos *uow.Repositories, intent *payment.PaymentIntent) error {
+	if err := p.gateway.Refund(intent.ProviderRef, intent.Amount); err != nil {
+		return fmt.Errorf("refunding payment: %w", err)
+	}
+	intent.Status = payment.IntentRefundPending
+	intent.FailureReason = ""
+	return repos.Payments.SaveIntent(intent)
+}
+
+func NewPaymentUseCase(paymentGateway gateway.PaymentGateway, unit uow.UnitOfWork) PaymentUseCase {
+	return &paymentInteraction{
+		gateway: paymentGateway,
+		unit:    unit,
+	}
+}
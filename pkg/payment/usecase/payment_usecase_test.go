@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	cart "github.com/ratheeshkumar25/pkg/cart/entity"
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	order "github.com/ratheeshkumar25/pkg/order/entity"
+	orderRepository "github.com/ratheeshkumar25/pkg/order/repository"
+	orderUseCase "github.com/ratheeshkumar25/pkg/order/usecase"
+	payment "github.com/ratheeshkumar25/pkg/payment/entity"
+	"github.com/ratheeshkumar25/pkg/payment/gateway"
+	"github.com/ratheeshkumar25/pkg/uow"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type paymentFixture struct {
+	payments PaymentUseCase
+	orders   orderUseCase.OrderUseCase
+	fake     *gateway.FakeGateway
+	db       *gorm.DB
+}
+
+// newPaymentFixture wires payments and orders to an in-memory SQLite database and the fake gateway
+func newPaymentFixture(tb testing.TB) *paymentFixture {
+	tb.Helper()
+	db := testdb.Open(tb, &user.Category{}, &user.Product{}, &user.StockMovement{}, &user.UserRegister{},
+		&cart.Cart{}, &cart.CartItem{}, &order.Order{}, &order.OrderItem{}, &order.OrderStatusChange{}, &coupon.Coupon{}, &coupon.CouponTarget{}, &coupon.Redemption{},
+		&payment.PaymentIntent{}, &payment.WebhookEvent{})
+
+	unit := uow.NewUnitOfWork(db)
+	fake := gateway.NewFakeGateway([]byte("secret"))
+	return &paymentFixture{
+		payments: NewPaymentUseCase(fake, unit),
+		orders:   orderUseCase.NewOrderUseCase(orderRepository.NewOrderRepository(db), unit),
+		fake:     fake,
+		db:       db,
+	}
+}
+
+// placeOrder checks out quantity units of a new product priced at amount cents
+func (f *paymentFixture) placeOrder(tb testing.TB, amount int64, quantity int) *order.Order {
+	tb.Helper()
+	product := &user.Product{ProductName: "Shoe", Quantity: 10, Price: money.Money{Amount: amount, Currency: "USD"}, CategoryID: 1}
+	require.NoError(tb, f.db.Create(product).Error)
+	placed, err := f.orders.Checkout(7, &order.CheckoutRequest{Items: []order.CheckoutItem{{ProductID: product.ID, Quantity: quantity}}})
+	require.NoError(tb, err)
+	return placed
+}
+
+func (f *paymentFixture) deliver(tb testing.TB, event gateway.Event) (bool, error) {
+	tb.Helper()
+	payload, signature, err := f.fake.SignEvent(event)
+	require.NoError(tb, err)
+	return f.payments.HandleWebhook(payload, signature)
+}
+
+func (f *paymentFixture) orderStatus(tb testing.TB, id uint) string {
+	tb.Helper()
+	found, err := f.orders.FindOrder(id)
+	require.NoError(tb, err)
+	return found.Status
+}
+
+func TestOrderIsPaidByVerifiedWebhook(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 2)
+
+	_, err := f.payments.CreateIntent(8, placed.ID)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.IntentProcessing, intent.Status)
+	assert.Equal(t, "20.00", intent.Amount.Decimal())
+	assert.NotEmpty(t, intent.ClientSecret)
+
+	// Asking again while the gateway has not answered returns the same intent
+	again, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, intent.ID, again.ID)
+	assert.Equal(t, intent.ProviderRef, again.ProviderRef)
+	assert.Equal(t, intent.ClientSecret, again.ClientSecret)
+	assert.Equal(t, order.StatusPending, f.orderStatus(t, placed.ID))
+
+	// Unsigned callbacks change nothing
+	payload, _, err := f.fake.SignEvent(gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	_, err = f.payments.HandleWebhook(payload, "sha256=00")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Equal(t, order.StatusPending, f.orderStatus(t, placed.ID))
+
+	captured := gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount}
+	duplicate, err := f.deliver(t, captured)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	found, err := f.orders.FindOrder(placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusPaid, found.Status)
+	assert.Equal(t, "gateway:fake", found.History[1].Actor)
+
+	// The same callback delivered again is acknowledged and not applied twice
+	duplicate, err = f.deliver(t, captured)
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	var changes int64
+	require.NoError(t, f.db.Model(&order.OrderStatusChange{}).Where("order_id = ?", placed.ID).Count(&changes).Error)
+	assert.EqualValues(t, 2, changes)
+
+	_, err = f.payments.CreateIntent(7, placed.ID)
+	assert.ErrorIs(t, err, ErrOrderNotPayable)
+}
+
+func TestWebhookRejectsUnknownPaymentsAndWrongAmounts(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 1)
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: "fake_pi_missing", Amount: intent.Amount})
+	assert.ErrorIs(t, err, ErrUnknownIntent)
+	_, err = f.deliver(t, gateway.Event{ID: "evt_2", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: money.Money{Amount: 1, Currency: "USD"}})
+	assert.ErrorIs(t, err, ErrAmountMismatch)
+	assert.Equal(t, order.StatusPending, f.orderStatus(t, placed.ID))
+
+	// Rejected callbacks were rolled back, so the gateway's retry is applied
+	duplicate, err := f.deliver(t, gateway.Event{ID: "evt_2", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, order.StatusPaid, f.orderStatus(t, placed.ID))
+}
+
+func TestDeclinedPaymentKeepsOrderPending(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1001, 2)
+
+	_, err := f.payments.CreateIntent(7, placed.ID)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, order.StatusPending, f.orderStatus(t, placed.ID))
+
+	var failed payment.PaymentIntent
+	require.NoError(t, f.db.Where("order_id = ?", placed.ID).First(&failed).Error)
+	assert.Equal(t, payment.IntentFailed, failed.Status)
+	assert.Contains(t, failed.FailureReason, "declined")
+}
+
+func TestRefundIsConfirmedByWebhook(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 1)
+
+	_, err := f.payments.Refund("refunder", placed.ID)
+	assert.ErrorIs(t, err, ErrNothingToRefund)
+	_, err = f.payments.Refund("refunder", placed.ID+1)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	for _, status := range []string{order.StatusPacked, order.StatusShipped} {
+		_, err = f.orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: status})
+		require.NoError(t, err)
+	}
+
+	refunding, err := f.payments.Refund("refunder", placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.IntentRefundPending, refunding.Status)
+	assert.Equal(t, order.StatusShipped, f.orderStatus(t, placed.ID))
+
+	_, err = f.deliver(t, gateway.Event{ID: "evt_2", Type: gateway.EventRefunded, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusRefunded, f.orderStatus(t, placed.ID))
+}
+
+func TestRefundBeforeShippingCancelsOrder(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 3)
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	_, err = f.orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusPacked})
+	require.NoError(t, err)
+
+	// The order is cancelled right away so it cannot be shipped after the money went back
+	refunding, err := f.payments.Refund("refunder", placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.IntentRefundPending, refunding.Status)
+	found, err := f.orders.FindOrder(placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, found.Status)
+	assert.Equal(t, "refunder", found.History[len(found.History)-1].Actor)
+	var product user.Product
+	require.NoError(t, f.db.First(&product, placed.Items[0].ProductID).Error)
+	assert.Equal(t, 10, product.Quantity)
+	_, err = f.orders.Transition("packer", placed.ID, &order.TransitionRequest{Status: order.StatusShipped})
+	assert.ErrorIs(t, err, orderUseCase.ErrIllegalTransition)
+
+	// The gateway's confirmation leaves the order cancelled
+	_, err = f.deliver(t, gateway.Event{ID: "evt_2", Type: gateway.EventRefunded, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, f.orderStatus(t, placed.ID))
+	var refunded payment.PaymentIntent
+	require.NoError(t, f.db.First(&refunded, intent.ID).Error)
+	assert.Equal(t, payment.IntentRefunded, refunded.Status)
+}
+
+func TestRefundAtGatewayStopsFulfilment(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 1)
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+
+	// A refund issued from the provider's dashboard still cancels the paid order
+	_, err = f.deliver(t, gateway.Event{ID: "evt_2", Type: gateway.EventRefunded, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, f.orderStatus(t, placed.ID))
+}
+
+func TestCaptureAfterCancelIsRefunded(t *testing.T) {
+	f := newPaymentFixture(t)
+	placed := f.placeOrder(t, 1000, 1)
+	intent, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	_, err = f.orders.Transition("support", placed.ID, &order.TransitionRequest{Status: order.StatusCancelled})
+	require.NoError(t, err)
+
+	duplicate, err := f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: intent.ProviderRef, Amount: intent.Amount})
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, order.StatusCancelled, f.orderStatus(t, placed.ID))
+	var late payment.PaymentIntent
+	require.NoError(t, f.db.First(&late, intent.ID).Error)
+	assert.Equal(t, payment.IntentRefundPending, late.Status)
+
+	// The gateway took the refund, asking for it again is turned down
+	assert.Error(t, f.fake.Refund(intent.ProviderRef, intent.Amount))
+}
+
+// flakyCaptureGateway declines the first capture it is asked for
+type flakyCaptureGateway struct {
+	*gateway.FakeGateway
+	declined bool
+}
+
+func (g *flakyCaptureGateway) Capture(providerRef string, amount money.Money) error {
+	if !g.declined {
+		g.declined = true
+		return fmt.Errorf("%w: card issuer unavailable", gateway.ErrDeclined)
+	}
+	return g.FakeGateway.Capture(providerRef, amount)
+}
+
+func TestRetryAfterFailedCaptureIsANewPayment(t *testing.T) {
+	f := newPaymentFixture(t)
+	flaky := &flakyCaptureGateway{FakeGateway: f.fake}
+	f.payments = NewPaymentUseCase(flaky, uow.NewUnitOfWork(f.db))
+	placed := f.placeOrder(t, 1000, 1)
+
+	failed, err := f.payments.CreateIntent(7, placed.ID)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, payment.IntentFailed, failed.Status)
+	require.NotEmpty(t, failed.ProviderRef)
+
+	retried, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, failed.ID, retried.ID)
+	assert.NotEqual(t, failed.ProviderRef, retried.ProviderRef)
+
+	// The capture of the retry resolves to the retry, not to the failed attempt
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: retried.ProviderRef, Amount: retried.Amount})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusPaid, f.orderStatus(t, placed.ID))
+	var stored payment.PaymentIntent
+	require.NoError(t, f.db.First(&stored, retried.ID).Error)
+	assert.Equal(t, payment.IntentSucceeded, stored.Status)
+	var first payment.PaymentIntent
+	require.NoError(t, f.db.First(&first, failed.ID).Error)
+	assert.Equal(t, payment.IntentFailed, first.Status)
+
+	// References are unique per provider, attempts without one are not
+	assert.Error(t, f.db.Create(&payment.PaymentIntent{OrderID: placed.ID, Provider: "fake", ProviderRef: retried.ProviderRef, Status: payment.IntentFailed}).Error)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, f.db.Create(&payment.PaymentIntent{OrderID: placed.ID, Provider: "fake", Status: payment.IntentFailed}).Error)
+	}
+}
+
+// failingUnit fails the unit of work with the given number and runs every other one
+type failingUnit struct {
+	uow.UnitOfWork
+	calls  int
+	failOn int
+}
+
+func (u *failingUnit) Do(fn func(repos *uow.Repositories) error) error {
+	u.calls++
+	if u.calls == u.failOn {
+		return errors.New("database went away")
+	}
+	return u.UnitOfWork.Do(fn)
+}
+
+func TestIntentLeftProcessingIsResumed(t *testing.T) {
+	f := newPaymentFixture(t)
+	unit := uow.NewUnitOfWork(f.db)
+	placed := f.placeOrder(t, 1000, 1)
+
+	// The gateway took the payment but saving its reference failed
+	f.payments = NewPaymentUseCase(f.fake, &failingUnit{UnitOfWork: unit, failOn: 2})
+	_, err := f.payments.CreateIntent(7, placed.ID)
+	require.Error(t, err)
+	var stale payment.PaymentIntent
+	require.NoError(t, f.db.Where("order_id = ?", placed.ID).First(&stale).Error)
+	assert.Equal(t, payment.IntentProcessing, stale.Status)
+	assert.Empty(t, stale.ProviderRef)
+
+	// The next request finishes the same attempt instead of waiting on it forever
+	f.payments = NewPaymentUseCase(f.fake, unit)
+	resumed, err := f.payments.CreateIntent(7, placed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stale.ID, resumed.ID)
+	assert.NotEmpty(t, resumed.ProviderRef)
+	assert.NotEmpty(t, resumed.ClientSecret)
+	var attempts int64
+	require.NoError(t, f.db.Model(&payment.PaymentIntent{}).Where("order_id = ?", placed.ID).Count(&attempts).Error)
+	assert.EqualValues(t, 1, attempts)
+
+	_, err = f.deliver(t, gateway.Event{ID: "evt_1", Type: gateway.EventCaptured, ProviderRef: resumed.ProviderRef, Amount: resumed.Amount})
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusPaid, f.orderStatus(t, placed.ID))
+}
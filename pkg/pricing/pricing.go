@@ -0,0 +1,185 @@
+// Package pricing turns product lines and an optional coupon into the amounts a
+// buyer pays, with every discount shown per line so the total can be checked by hand
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+)
+
+var (
+	ErrNoLines             = errors.New("nothing to price")
+	ErrMixedCurrencies     = errors.New("all lines must be priced in the same currency")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to any item")
+	ErrMinimumNotMet       = errors.New("order is below the coupon's minimum value")
+)
+
+// Line is one product and quantity to price
+type Line struct {
+	ProductID   uint
+	CategoryID  uint
+	ProductName string
+	UnitPrice   money.Money
+	Quantity    int
+}
+
+type LineBreakdown struct {
+	ProductID   uint        `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	UnitPrice   money.Money `json:"unit_price"`
+	Quantity    int         `json:"quantity"`
+	Subtotal    money.Money `json:"subtotal"`
+	Discount    money.Money `json:"discount"`
+	Total       money.Money `json:"total"`
+}
+
+// AppliedCoupon describes the coupon behind a discount
+type AppliedCoupon struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description,omitempty"`
+	Kind        string       `json:"kind"`
+	PercentOff  int          `json:"percent_off,omitempty"`
+	AmountOff   *money.Money `json:"amount_off,omitempty"`
+}
+
+// Breakdown is the priced order, Total is Subtotal minus Discount and the line
+// discounts add up to Discount
+type Breakdown struct {
+	Lines    []LineBreakdown `json:"lines"`
+	Subtotal money.Money     `json:"subtotal"`
+	Discount money.Money     `json:"discount"`
+	Total    money.Money     `json:"total"`
+	Coupon   *AppliedCoupon  `json:"coupon"`
+}
+
+// Price prices lines and applies applied, which may be nil. It does not check
+// whether the coupon is active or within its limits, callers do that first
+func Price(lines []Line, applied *coupon.Coupon) (*Breakdown, error) {
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+
+	currency := lines[0].UnitPrice.Currency
+	zero := money.Money{Currency: currency}
+	breakdown := &Breakdown{Lines: make([]LineBreakdown, 0, len(lines)), Subtotal: zero, Discount: zero, Total: zero}
+	for _, line := range lines {
+		if line.UnitPrice.Currency != currency {
+			return nil, fmt.Errorf("%w: %s and %s", ErrMixedCurrencies, currency, line.UnitPrice.Currency)
+		}
+		subtotal := line.UnitPrice.Mul(int64(line.Quantity))
+		breakdown.Lines = append(breakdown.Lines, LineBreakdown{
+			ProductID:   line.ProductID,
+			ProductName: line.ProductName,
+			UnitPrice:   line.UnitPrice,
+			Quantity:    line.Quantity,
+			Subtotal:    subtotal,
+			Discount:    zero,
+			Total:       subtotal,
+		})
+		breakdown.Subtotal.Amount += subtotal.Amount
+	}
+
+	if applied != nil {
+		if err := discount(breakdown, lines, applied); err != nil {
+			return nil, err
+		}
+	}
+	breakdown.Total.Amount = breakdown.Subtotal.Amount - breakdown.Discount.Amount
+	return breakdown, nil
+}
+
+// ExpandCategories returns a copy of applied whose category targets also cover
+// every category below them, subtree lists the IDs under a category as
+// CategoryRepository.SubtreeIDs does. applied itself is left as stored
+func ExpandCategories(applied *coupon.Coupon, subtree func(id uint) ([]uint, error)) (*coupon.Coupon, error) {
+	if applied == nil {
+		return nil, nil
+	}
+	expanded := *applied
+	expanded.Targets = make([]coupon.CouponTarget, 0, len(applied.Targets))
+	for _, target := range applied.Targets {
+		//**The target itself stays even when its category is gone, a coupon
+		//**without targets would cover everything
+		expanded.Targets = append(expanded.Targets, target)
+		if target.Kind != coupon.TargetCategory {
+			continue
+		}
+		ids, err := subtree(target.TargetID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if id != target.TargetID {
+				expanded.Targets = append(expanded.Targets, coupon.CouponTarget{Kind: coupon.TargetCategory, TargetID: id})
+			}
+		}
+	}
+	return &expanded, nil
+}
+
+// discount spreads the coupon's discount over the lines it covers. Percentages
+// are taken per line and rounded down, a fixed amount is split in proportion to
+// the lines' subtotals with the rounding left on the last covered line
+func discount(breakdown *Breakdown, lines []Line, applied *coupon.Coupon) error {
+	currency := breakdown.Subtotal.Currency
+	if applied.MinOrder.Amount > 0 {
+		if applied.MinOrder.Currency != currency {
+			return fmt.Errorf("%w: the minimum is in %s", ErrCouponNotApplicable, applied.MinOrder.Currency)
+		}
+		if breakdown.Subtotal.Amount < applied.MinOrder.Amount {
+			return fmt.Errorf("%w: spend at least %s", ErrMinimumNotMet, applied.MinOrder)
+		}
+	}
+
+	var covered []int
+	var eligible int64
+	for i, line := range lines {
+		if applied.Covers(line.ProductID, line.CategoryID) {
+			covered = append(covered, i)
+			eligible += breakdown.Lines[i].Subtotal.Amount
+		}
+	}
+	if len(covered) == 0 || eligible == 0 {
+		return ErrCouponNotApplicable
+	}
+
+	switch applied.Kind {
+	case coupon.KindPercentage:
+		for _, i := range covered {
+			breakdown.Lines[i].Discount.Amount = breakdown.Lines[i].Subtotal.Amount * int64(applied.PercentOff) / 100
+		}
+	case coupon.KindFixed:
+		if applied.AmountOff.Currency != currency {
+			return fmt.Errorf("%w: it takes off %s", ErrCouponNotApplicable, applied.AmountOff)
+		}
+		off := min(applied.AmountOff.Amount, eligible)
+		left := off
+		for n, i := range covered {
+			share := left
+			if n < len(covered)-1 {
+				share = off * breakdown.Lines[i].Subtotal.Amount / eligible
+			}
+			breakdown.Lines[i].Discount.Amount = share
+			left -= share
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrCouponNotApplicable, applied.Kind)
+	}
+
+	for _, i := range covered {
+		line := &breakdown.Lines[i]
+		line.Total.Amount = line.Subtotal.Amount - line.Discount.Amount
+		breakdown.Discount.Amount += line.Discount.Amount
+	}
+	breakdown.Coupon = &AppliedCoupon{Code: applied.Code, Description: applied.Description, Kind: applied.Kind}
+	if applied.Kind == coupon.KindPercentage {
+		breakdown.Coupon.PercentOff = applied.PercentOff
+	} else {
+		amountOff := applied.AmountOff
+		breakdown.Coupon.AmountOff = &amountOff
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package pricing
+
+import (
+	"testing"
+
+	coupon "github.com/ratheeshkumar25/pkg/coupon/entity"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "USD"}
+}
+
+var testLines = []Line{
+	{ProductID: 1, CategoryID: 10, ProductName: "Shoe", UnitPrice: usd(1999), Quantity: 2},
+	{ProductID: 2, CategoryID: 20, ProductName: "Sock", UnitPrice: usd(333), Quantity: 1},
+}
+
+func TestPriceWithoutCoupon(t *testing.T) {
+	breakdown, err := Price(testLines, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "43.31", breakdown.Subtotal.Decimal())
+	assert.Equal(t, "0.00", breakdown.Discount.Decimal())
+	assert.Equal(t, "43.31", breakdown.Total.Decimal())
+	assert.Nil(t, breakdown.Coupon)
+
+	_, err = Price(nil, nil)
+	assert.ErrorIs(t, err, ErrNoLines)
+	_, err = Price(append(testLines, Line{ProductID: 3, UnitPrice: money.Money{Amount: 100, Currency: "EUR"}, Quantity: 1}), nil)
+	assert.ErrorIs(t, err, ErrMixedCurrencies)
+}
+
+func TestPercentageCoupon(t *testing.T) {
+	// 15% of 3.33 is 0.4995, rounded down per line
+	breakdown, err := Price(testLines, &coupon.Coupon{Code: "SAVE15", Kind: coupon.KindPercentage, PercentOff: 15})
+	require.NoError(t, err)
+	assert.Equal(t, "5.99", breakdown.Lines[0].Discount.Decimal())
+	assert.Equal(t, "0.49", breakdown.Lines[1].Discount.Decimal())
+	assert.Equal(t, "6.48", breakdown.Discount.Decimal())
+	assert.Equal(t, "36.83", breakdown.Total.Decimal())
+	assert.Equal(t, &AppliedCoupon{Code: "SAVE15", Kind: coupon.KindPercentage, PercentOff: 15}, breakdown.Coupon)
+
+	// Scoped to a category, other lines pay full price
+	scoped := &coupon.Coupon{Code: "SOCKS", Kind: coupon.KindPercentage, PercentOff: 50, Targets: []coupon.CouponTarget{{Kind: coupon.TargetCategory, TargetID: 20}}}
+	breakdown, err = Price(testLines, scoped)
+	require.NoError(t, err)
+	assert.Equal(t, "0.00", breakdown.Lines[0].Discount.Decimal())
+	assert.Equal(t, "1.66", breakdown.Discount.Decimal())
+
+	_, err = Price(testLines[:1], scoped)
+	assert.ErrorIs(t, err, ErrCouponNotApplicable)
+}
+
+func TestExpandCategories(t *testing.T) {
+	subtree := func(id uint) ([]uint, error) {
+		return map[uint][]uint{10: {10, 11, 12}}[id], nil
+	}
+	stored := &coupon.Coupon{Code: "SHOES", Kind: coupon.KindPercentage, PercentOff: 10, Targets: []coupon.CouponTarget{
+		{Kind: coupon.TargetCategory, TargetID: 10}, {Kind: coupon.TargetProduct, TargetID: 5}, {Kind: coupon.TargetCategory, TargetID: 30},
+	}}
+
+	expanded, err := ExpandCategories(stored, subtree)
+	require.NoError(t, err)
+	assert.True(t, expanded.Covers(1, 12))
+	assert.True(t, expanded.Covers(5, 20))
+	assert.False(t, expanded.Covers(1, 20))
+	// A category that is gone stays a target, it must not leave the coupon covering everything
+	assert.True(t, expanded.Covers(1, 30))
+	assert.Len(t, stored.Targets, 3)
+
+	expanded, err = ExpandCategories(nil, subtree)
+	require.NoError(t, err)
+	assert.Nil(t, expanded)
+}
+
+func TestFixedCoupon(t *testing.T) {
+	// 5.00 split 39.98 to 3.33, the last covered line takes the rounding
+	breakdown, err := Price(testLines, &coupon.Coupon{Code: "FIVE", Kind: coupon.KindFixed, AmountOff: usd(500)})
+	require.NoError(t, err)
+	assert.Equal(t, "4.61", breakdown.Lines[0].Discount.Decimal())
+	assert.Equal(t, "0.39", breakdown.Lines[1].Discount.Decimal())
+	assert.Equal(t, "5.00", breakdown.Discount.Decimal())
+	assert.Equal(t, "38.31", breakdown.Total.Decimal())
+
+	// A discount larger than the covered lines makes them free and no more
+	breakdown, err = Price(testLines, &coupon.Coupon{Code: "BIG", Kind: coupon.KindFixed, AmountOff: usd(10000),
+		Targets: []coupon.CouponTarget{{Kind: coupon.TargetProduct, TargetID: 2}}})
+	require.NoError(t, err)
+	assert.Equal(t, "3.33", breakdown.Discount.Decimal())
+	assert.Equal(t, "39.98", breakdown.Total.Decimal())
+
+	_, err = Price(testLines, &coupon.Coupon{Code: "EURO", Kind: coupon.KindFixed, AmountOff: money.Money{Amount: 500, Currency: "EUR"}})
+	assert.ErrorIs(t, err, ErrCouponNotApplicable)
+}
+
+func TestMinimumOrder(t *testing.T) {
+	applied := &coupon.Coupon{Code: "MIN50", Kind: coupon.KindFixed, AmountOff: usd(500), MinOrder: usd(5000)}
+	_, err := Price(testLines, applied)
+	assert.ErrorIs(t, err, ErrMinimumNotMet)
+
+	applied.MinOrder = usd(4331)
+	_, err = Price(testLines, applied)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package rbac
+
+// Roles an account can hold, staff roles live on admin accounts
+const (
+	RoleSuperAdmin     = "super-admin"
+	RoleCatalogManager = "catalog-manager"
+	RoleSupport        = "support"
+	RoleCustomer       = "customer"
+)
+
+// Permissions checked by the routes
+const (
+	PermProductRead  = "product:read"
+	PermProductWrite = "product:write"
+	PermUserRead     = "user:read"
+	PermUserWrite    = "user:write"
+	PermAdminManage  = "admin:manage"
+	PermAccountWrite = "account:write"
+	PermStockRead    = "stock:read"
+	PermStockWrite   = "stock:write"
+	PermTrashPurge   = "trash:purge"
+	PermCartWrite    = "cart:write"
+	PermOrderWrite   = "order:write"
+	PermOrderRead    = "order:read"
+	PermOrderManage  = "order:manage"
+	PermCouponManage = "coupon:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleSuperAdmin: {
+		PermProductRead,
+		PermProductWrite,
+		PermUserRead,
+		PermUserWrite,
+		PermAdminManage,
+		PermStockRead,
+		PermStockWrite,
+		PermTrashPurge,
+		PermOrderRead,
+		PermOrderManage,
+		PermCouponManage,
+	},
+	RoleCatalogManager: {
+		PermProductRead,
+		PermProductWrite,
+		PermStockRead,
+		PermStockWrite,
+		PermOrderRead,
+		PermOrderManage,
+		PermCouponManage,
+	},
+	RoleSupport: {
+		PermProductRead,
+		PermUserRead,
+		PermStockRead,
+		PermOrderRead,
+	},
+	RoleCustomer: {
+		PermProductRead,
+		PermAccountWrite,
+		PermCartWrite,
+		PermOrderWrite,
+	},
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role, perm string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaffRole reports whether role may be assigned to an admin account
+func IsStaffRole(role string) bool {
+	switch role {
+	case RoleSuperAdmin, RoleCatalogManager, RoleSupport:
+		return true
+	}
+	return false
+}
+
+// Permissions lists what role grants
+func Permissions(role string) []string {
+	return append([]string(nil), rolePermissions[role]...)
+}
@@ -1,8 +1,10 @@
 package routes
 
 import (
+	"github.com/ratheeshkumar25/pkg/middleware"
 	"github.com/ratheeshkumar25/pkg/server"
 	"github.com/ratheeshkumar25/pkg/user/delivery"
+	"github.com/ratheeshkumar25/pkg/rbac"
 )
 
 
@@ -10,22 +12,33 @@ import (
 type AdminRoutes struct{
 	Server *server.Server
 	Admin delivery.AdminUseCases
+	Auth *middleware.Auth
 }
 
 func (a AdminRoutes)AdminRoutes(){
 	a.Server.R.POST("/adminsignup",a.Admin.RegisterAdminHandler)
 	a.Server.R.POST("/adminlogin",a.Admin.LoginAdminHandler)
-	a.Server.R.GET("/userlist",a.Admin.GetUserListHandler)
-	a.Server.R.POST("/addproduct",a.Admin.AddProductHandler)
 	a.Server.R.GET("/getproduct",a.Admin.GetProductHandler)
-	a.Server.R.PUT("/productupdate",a.Admin.UpdateProductHandler)
-	a.Server.R.DELETE("/productdelet/:id",a.Admin.DeletProductHandler)
+	a.Server.R.GET("/products/:id",a.Admin.GetProductByIDHandler)
+
+	// Everything below requires an authenticated account holding the route's permission
+	// and, when policy demands it, a session that passed 2FA
+	admin := a.Server.R.Group("/", a.Auth.RequireAuth(), a.Auth.RequireMFA())
+	admin.GET("/userlist",a.Auth.RequirePermission(rbac.PermUserRead),a.Admin.GetUserListHandler)
+	admin.POST("/addproduct",a.Auth.RequirePermission(rbac.PermProductWrite),a.Admin.AddProductHandler)
+	admin.PUT("/productupdate",a.Auth.RequirePermission(rbac.PermProductWrite),a.Admin.UpdateProductHandler)
+	admin.PATCH("/products/:id",a.Auth.RequirePermission(rbac.PermProductWrite),a.Admin.PatchProductHandler)
+	admin.DELETE("/productdelet/:id",a.Auth.RequirePermission(rbac.PermProductWrite),a.Admin.DeletProductHandler)
+	admin.PUT("/admins/:id/role",a.Auth.RequirePermission(rbac.PermAdminManage),a.Admin.AssignAdminRoleHandler)
+	admin.POST("/admins/invites",a.Auth.RequirePermission(rbac.PermAdminManage),a.Admin.InviteAdminHandler)
+	admin.POST("/admins/unlock",a.Auth.RequirePermission(rbac.PermUserWrite),a.Admin.UnlockAccountHandler)
 }
 
 // NewAdminInit creates a new AdminRoutes instance
-func NewAdminInit(server *server.Server, admin delivery.AdminUseCases) *AdminRoutes {
+func NewAdminInit(server *server.Server, admin delivery.AdminUseCases, auth *middleware.Auth) *AdminRoutes {
     return &AdminRoutes{
         Server: server,
         Admin:  admin,
+        Auth:   auth,
     }
 }
\ No newline at end of file
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	cartDelivery "github.com/ratheeshkumar25/pkg/cart/delivery"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+)
+
+type CartRoutes struct {
+	Server *server.Server
+	Cart   cartDelivery.CartUseCases
+	Auth   *middleware.Auth
+}
+
+func (r *CartRoutes) CartRoutes() {
+	// Every route works on the calling user's own cart
+	cart := r.Server.R.Group("/cart", r.Auth.RequireAuth(), r.Auth.RequirePermission(rbac.PermCartWrite))
+	cart.GET("", r.Cart.GetCartHandler)
+	cart.DELETE("", r.Cart.ClearCartHandler)
+	cart.POST("/items", r.Cart.AddItemHandler)
+	cart.PUT("/items/:product_id", r.Cart.UpdateItemHandler)
+	cart.DELETE("/items/:product_id", r.Cart.RemoveItemHandler)
+}
+
+func NewCartInit(server *server.Server, cart cartDelivery.CartUseCases, auth *middleware.Auth) *CartRoutes {
+	return &CartRoutes{
+		Server: server,
+		Cart:   cart,
+		Auth:   auth,
+	}
+}
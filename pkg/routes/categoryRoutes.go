@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type CategoryRoutes struct {
+	Server   *server.Server
+	Category delivery.CategoryUseCases
+	Auth     *middleware.Auth
+}
+
+func (r *CategoryRoutes) CategoryRoutes() {
+	// Categories are public to browse, like the product list
+	r.Server.R.GET("/categories", r.Category.ListCategoriesHandler)
+	r.Server.R.GET("/categories/:id", r.Category.GetCategoryHandler)
+
+	// Managing them is part of the catalog
+	manage := r.Server.R.Group("/categories", r.Auth.RequireAuth(), r.Auth.RequireMFA(), r.Auth.RequirePermission(rbac.PermProductWrite))
+	manage.POST("", r.Category.CreateCategoryHandler)
+	manage.PUT("/:id", r.Category.UpdateCategoryHandler)
+	manage.DELETE("/:id", r.Category.DeleteCategoryHandler)
+}
+
+func NewCategoryInit(server *server.Server, category delivery.CategoryUseCases, auth *middleware.Auth) *CategoryRoutes {
+	return &CategoryRoutes{
+		Server:   server,
+		Category: category,
+		Auth:     auth,
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	couponDelivery "github.com/ratheeshkumar25/pkg/coupon/delivery"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+)
+
+type CouponRoutes struct {
+	Server *server.Server
+	Coupon couponDelivery.CouponUseCases
+	Auth   *middleware.Auth
+}
+
+func (r *CouponRoutes) CouponRoutes() {
+	// Users price their cart before checking out, a coupon is only redeemed by checkout
+	r.Server.R.POST("/cart/quote", r.Auth.RequireAuth(), r.Auth.RequirePermission(rbac.PermCartWrite), r.Coupon.QuoteCartHandler)
+
+	// Staff manage the promo codes
+	staff := r.Server.R.Group("/admin/coupons", r.Auth.RequireAuth(), r.Auth.RequireMFA(), r.Auth.RequirePermission(rbac.PermCouponManage))
+	staff.POST("", r.Coupon.CreateCouponHandler)
+	staff.GET("", r.Coupon.ListCouponsHandler)
+	staff.GET("/:id", r.Coupon.GetCouponHandler)
+	staff.PUT("/:id", r.Coupon.UpdateCouponHandler)
+	staff.DELETE("/:id", r.Coupon.DeleteCouponHandler)
+}
+
+func NewCouponInit(server *server.Server, coupon couponDelivery.CouponUseCases, auth *middleware.Auth) *CouponRoutes {
+	return &CouponRoutes{
+		Server: server,
+		Coupon: coupon,
+		Auth:   auth,
+	}
+}
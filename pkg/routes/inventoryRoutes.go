@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type InventoryRoutes struct {
+	Server    *server.Server
+	Inventory delivery.InventoryUseCases
+	Auth      *middleware.Auth
+}
+
+func (r *InventoryRoutes) InventoryRoutes() {
+	// Stock is staff only, every movement is booked against the calling admin
+	staff := r.Server.R.Group("/", r.Auth.RequireAuth(), r.Auth.RequireMFA())
+	staff.GET("/products/:id/stock-movements", r.Auth.RequirePermission(rbac.PermStockRead), r.Inventory.StockHistoryHandler)
+	staff.POST("/products/:id/stock-movements", r.Auth.RequirePermission(rbac.PermStockWrite), r.Inventory.RecordMovementHandler)
+	staff.GET("/inventory/low-stock", r.Auth.RequirePermission(rbac.PermStockRead), r.Inventory.LowStockHandler)
+}
+
+func NewInventoryInit(server *server.Server, inventory delivery.InventoryUseCases, auth *middleware.Auth) *InventoryRoutes {
+	return &InventoryRoutes{
+		Server:    server,
+		Inventory: inventory,
+		Auth:      auth,
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type MFARoutes struct {
+	Server *server.Server
+	MFA    delivery.MFAUseCases
+	Auth   *middleware.Auth
+}
+
+func (m *MFARoutes) MFARoutes() {
+	// Second login step for users and admins with 2FA enabled
+	m.Server.R.POST("/login/mfa", m.MFA.VerifyLoginHandler)
+
+	// Managing the factor needs a session but not a 2FA one, otherwise nobody could enroll
+	mfa := m.Server.R.Group("/mfa", m.Auth.RequireAuth())
+	mfa.POST("/enroll", m.MFA.EnrollHandler)
+	mfa.POST("/enroll/confirm", m.MFA.ConfirmEnrollmentHandler)
+	mfa.POST("/recovery-codes", m.MFA.RegenerateRecoveryCodesHandler)
+	mfa.POST("/disable", m.MFA.DisableHandler)
+}
+
+func NewMFAInit(server *server.Server, mfa delivery.MFAUseCases, auth *middleware.Auth) *MFARoutes {
+	return &MFARoutes{
+		Server: server,
+		MFA:    mfa,
+		Auth:   auth,
+	}
+}
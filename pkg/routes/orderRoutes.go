@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	orderDelivery "github.com/ratheeshkumar25/pkg/order/delivery"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+)
+
+type OrderRoutes struct {
+	Server *server.Server
+	Order  orderDelivery.OrderUseCases
+	Auth   *middleware.Auth
+}
+
+func (r *OrderRoutes) OrderRoutes() {
+	// Users place and read their own orders
+	orders := r.Server.R.Group("/", r.Auth.RequireAuth(), r.Auth.RequirePermission(rbac.PermOrderWrite))
+	orders.POST("/checkout", r.Order.CheckoutHandler)
+	orders.GET("/orders", r.Order.ListOrdersHandler)
+	orders.GET("/orders/:id", r.Order.GetOrderHandler)
+
+	// Staff see every order and move it through fulfilment
+	staff := r.Server.R.Group("/admin/orders", r.Auth.RequireAuth(), r.Auth.RequireMFA())
+	staff.GET("", r.Auth.RequirePermission(rbac.PermOrderRead), r.Order.AdminListOrdersHandler)
+	staff.GET("/:id", r.Auth.RequirePermission(rbac.PermOrderRead), r.Order.AdminGetOrderHandler)
+	staff.POST("/:id/transitions", r.Auth.RequirePermission(rbac.PermOrderManage), r.Order.TransitionOrderHandler)
+}
+
+func NewOrderInit(server *server.Server, order orderDelivery.OrderUseCases, auth *middleware.Auth) *OrderRoutes {
+	return &OrderRoutes{
+		Server: server,
+		Order:  order,
+		Auth:   auth,
+	}
+}
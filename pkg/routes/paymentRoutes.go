@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	paymentDelivery "github.com/ratheeshkumar25/pkg/payment/delivery"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+)
+
+type PaymentRoutes struct {
+	Server  *server.Server
+	Payment paymentDelivery.PaymentUseCases
+	Auth    *middleware.Auth
+}
+
+func (r *PaymentRoutes) PaymentRoutes() {
+	// The gateway authenticates its callbacks by signing them, not with a token
+	r.Server.R.POST("/payments/webhook", r.Payment.WebhookHandler)
+
+	r.Server.R.POST("/orders/:id/payments", r.Auth.RequireAuth(), r.Auth.RequirePermission(rbac.PermOrderWrite), r.Payment.CreatePaymentHandler)
+	r.Server.R.POST("/admin/orders/:id/refund", r.Auth.RequireAuth(), r.Auth.RequireMFA(), r.Auth.RequirePermission(rbac.PermOrderManage), r.Payment.RefundHandler)
+}
+
+func NewPaymentInit(server *server.Server, payment paymentDelivery.PaymentUseCases, auth *middleware.Auth) *PaymentRoutes {
+	return &PaymentRoutes{
+		Server:  server,
+		Payment: payment,
+		Auth:    auth,
+	}
+}
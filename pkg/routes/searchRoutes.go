@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type SearchRoutes struct {
+	Server *server.Server
+	Search delivery.SearchUseCases
+}
+
+func (s *SearchRoutes) SearchRoutes() {
+	// Search is public like the product list
+	s.Server.R.GET("/products/search", s.Search.SearchProductsHandler)
+}
+
+func NewSearchInit(server *server.Server, search delivery.SearchUseCases) *SearchRoutes {
+	return &SearchRoutes{
+		Server: server,
+		Search: search,
+	}
+}
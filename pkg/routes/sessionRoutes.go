@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type SessionRoutes struct {
+	Server  *server.Server
+	Session delivery.SessionUseCases
+	Auth    *middleware.Auth
+}
+
+func (s *SessionRoutes) SessionRoutes() {
+	s.Server.R.POST("/refresh", s.Session.RefreshHandler)
+
+	// Logging out works for users and admins alike
+	session := s.Server.R.Group("/", s.Auth.RequireAuth())
+	session.POST("/logout", s.Session.LogoutHandler)
+	session.POST("/logout/all", s.Session.LogoutEverywhereHandler)
+}
+
+func NewSessionInit(server *server.Server, session delivery.SessionUseCases, auth *middleware.Auth) *SessionRoutes {
+	return &SessionRoutes{
+		Server:  server,
+		Session: session,
+		Auth:    auth,
+	}
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/server"
+	"github.com/ratheeshkumar25/pkg/user/delivery"
+)
+
+type TrashRoutes struct {
+	Server *server.Server
+	Trash  delivery.TrashUseCases
+	Auth   *middleware.Auth
+}
+
+func (r *TrashRoutes) TrashRoutes() {
+	// Restoring needs the same permission as deleting, purging for good is super-admin only
+	trash := r.Server.R.Group("/trash", r.Auth.RequireAuth(), r.Auth.RequireMFA())
+	trash.GET("/products", r.Auth.RequirePermission(rbac.PermProductWrite), r.Trash.ListDeletedProductsHandler)
+	trash.POST("/products/:id/restore", r.Auth.RequirePermission(rbac.PermProductWrite), r.Trash.RestoreProductHandler)
+	trash.DELETE("/products/:id", r.Auth.RequirePermission(rbac.PermTrashPurge), r.Trash.PurgeProductHandler)
+	trash.GET("/users", r.Auth.RequirePermission(rbac.PermUserRead), r.Trash.ListDeletedUsersHandler)
+	trash.POST("/users/:id/restore", r.Auth.RequirePermission(rbac.PermUserWrite), r.Trash.RestoreUserHandler)
+	trash.DELETE("/users/:id", r.Auth.RequirePermission(rbac.PermTrashPurge), r.Trash.PurgeUserHandler)
+}
+
+func NewTrashInit(server *server.Server, trash delivery.TrashUseCases, auth *middleware.Auth) *TrashRoutes {
+	return &TrashRoutes{
+		Server: server,
+		Trash:  trash,
+		Auth:   auth,
+	}
+}
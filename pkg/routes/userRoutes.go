@@ -1,25 +1,39 @@
 package routes
 
 import (
+	"github.com/ratheeshkumar25/pkg/middleware"
 	"github.com/ratheeshkumar25/pkg/server"
 	"github.com/ratheeshkumar25/pkg/user/delivery"
+	"github.com/ratheeshkumar25/pkg/rbac"
 )
 
 type UserRoutes struct {
 	Server *server.Server
 	User   delivery.UserUseCases
+	Auth   *middleware.Auth
 }
 
 func (u *UserRoutes) UsersRoutes() {
 	u.Server.R.POST("/signup", u.User.RegisterUserHandler)
 	u.Server.R.POST("/login", u.User.LoginUserHandler)
-	u.Server.R.PUT("/usersupdate", u.User.UpdateUserHandler)
-	u.Server.R.DELETE("/userdelete/:id", u.User.DeleteUserHandler)
+	u.Server.R.POST("/password/forgot", u.User.ForgotPasswordHandler)
+	u.Server.R.POST("/password/reset", u.User.ResetPasswordHandler)
+	u.Server.R.GET("/verify-email", u.User.VerifyEmailHandler)
+	u.Server.R.POST("/verify-email/resend", u.User.ResendVerificationHandler)
+
+	// Account management is limited to the logged in user's own account
+	account := u.Server.R.Group("/", u.Auth.RequireAuth(), u.Auth.RequirePermission(rbac.PermAccountWrite))
+	account.GET("/users/me", u.User.GetCurrentUserHandler)
+	account.PUT("/usersupdate", u.User.UpdateUserHandler)
+	account.PATCH("/users/:id", u.Auth.RequireSelf("id"), u.User.PatchUserHandler)
+	account.PUT("/users/me/password", u.User.ChangePasswordHandler)
+	account.DELETE("/userdelete/:id", u.Auth.RequireSelf("id"), u.User.DeleteUserHandler)
 }
 
-func NewUserInit(server *server.Server, user *delivery.UserHandler) *UserRoutes {
+func NewUserInit(server *server.Server, user *delivery.UserHandler, auth *middleware.Auth) *UserRoutes {
 	return &UserRoutes{
 		Server: server,
 		User:   user,
+		Auth:   auth,
 	}
 }
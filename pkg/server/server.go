@@ -1,6 +1,12 @@
 package server
 
-import "github.com/gin-gonic/gin"
+import (
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/config"
+)
 
 type Server struct {
 	R *gin.Engine
@@ -12,7 +18,23 @@ func (s *Server) StartServer() {
 
 func NewHTTPServer() *Server {
 	router := gin.Default()
+	//**Only proxies listed in TRUSTED_PROXIES may set X-Forwarded-For, otherwise any
+	//**caller could pick the client IP that login throttling is keyed on
+	if err := router.SetTrustedProxies(trustedProxies(config.String("TRUSTED_PROXIES", ""))); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	return &Server{
 		R: router,
 	}
 }
+
+// trustedProxies splits a comma separated list of IPs and CIDRs, nil trusts no proxy
+func trustedProxies(value string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
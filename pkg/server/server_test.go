@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// clientIP sends a request from remoteAddr with a forwarded header and returns
+// the client IP handlers see, which is what login throttling is keyed on
+func clientIP(t *testing.T, remoteAddr, forwardedFor string) string {
+	t.Helper()
+	server := NewHTTPServer()
+	server.R.GET("/ip", func(c *gin.Context) { c.String(200, c.ClientIP()) })
+
+	req, _ := http.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	server.R.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestForgedForwardedHeaderKeepsClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+
+	// Rotating the header does not give a caller a fresh throttle key
+	assert.Equal(t, "203.0.113.9", clientIP(t, "203.0.113.9:5000", "198.51.100.1"))
+	assert.Equal(t, "203.0.113.9", clientIP(t, "203.0.113.9:5000", "198.51.100.2"))
+}
+
+func TestTrustedProxyForwardsClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.7")
+
+	assert.Equal(t, "198.51.100.1", clientIP(t, "10.1.2.3:5000", "198.51.100.1"))
+	assert.Equal(t, "198.51.100.1", clientIP(t, "192.0.2.7:5000", "198.51.100.1"))
+	assert.Equal(t, "203.0.113.9", clientIP(t, "203.0.113.9:5000", "198.51.100.1"))
+}
@@ -0,0 +1,25 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// NewOpaqueToken returns a random token for the client and the hash to store server side
+func NewOpaqueToken() (plain string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generating random token: %w", err)
+	}
+	plain = base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashOpaqueToken(plain), nil
+}
+
+// HashOpaqueToken hashes a client supplied opaque token for lookups
+func HashOpaqueToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
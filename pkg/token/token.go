@@ -0,0 +1,153 @@
+package token
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ratheeshkumar25/pkg/config"
+)
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// Claims is the payload carried by every access token
+type Claims struct {
+	SubjectID uint   `json:"-"`
+	Account   string `json:"acc"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Maker issues and verifies signed access tokens
+type Maker interface {
+	CreateToken(subjectID uint, account, role string, sessionID uint) (string, *Claims, error)
+	VerifyToken(tokenString string) (*Claims, error)
+}
+
+type JWTMaker struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	ttl       time.Duration
+	issuer    string
+}
+
+func (m *JWTMaker) CreateToken(subjectID uint, account, role string, sessionID uint) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		SubjectID: subjectID,
+		Account:   account,
+		Role:      role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(subjectID), 10),
+			Issuer:    m.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(m.method, claims).SignedString(m.signKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("signing token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+func (m *JWTMaker) VerifyToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		//**Reject tokens signed with any other algorithm than the configured one
+		if t.Method.Alg() != m.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+		return m.verifyKey, nil
+	})
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if m.issuer != "" && claims.Issuer != m.issuer {
+		return nil, ErrInvalidToken
+	}
+
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims.SubjectID = uint(id)
+	return claims, nil
+}
+
+// NewHS256Maker signs tokens with a shared HMAC secret
+func NewHS256Maker(secret []byte, ttl time.Duration, issuer string) (*JWTMaker, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("HS256 secret must be at least 32 bytes")
+	}
+	return &JWTMaker{
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+		ttl:       ttl,
+		issuer:    issuer,
+	}, nil
+}
+
+// NewRS256Maker signs tokens with an RSA private key, verification only needs the public half
+func NewRS256Maker(privateKey *rsa.PrivateKey, ttl time.Duration, issuer string) (*JWTMaker, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("RS256 private key is required")
+	}
+	return &JWTMaker{
+		method:    jwt.SigningMethodRS256,
+		signKey:   privateKey,
+		verifyKey: &privateKey.PublicKey,
+		ttl:       ttl,
+		issuer:    issuer,
+	}, nil
+}
+
+// NewMakerFromEnv builds the Maker configured through JWT_* environment variables
+func NewMakerFromEnv() Maker {
+	ttl := config.Duration("JWT_TTL", 15*time.Minute)
+	issuer := os.Getenv("JWT_ISSUER")
+
+	var (
+		maker *JWTMaker
+		err   error
+	)
+	switch alg := os.Getenv("JWT_ALGORITHM"); alg {
+	case "", "HS256":
+		maker, err = NewHS256Maker([]byte(os.Getenv("JWT_SECRET")), ttl, issuer)
+	case "RS256":
+		pemBytes, readErr := os.ReadFile(os.Getenv("JWT_PRIVATE_KEY_FILE"))
+		if readErr != nil {
+			log.Fatalf("reading JWT_PRIVATE_KEY_FILE failed: %v", readErr)
+		}
+		privateKey, parseErr := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if parseErr != nil {
+			log.Fatalf("parsing JWT_PRIVATE_KEY_FILE failed: %v", parseErr)
+		}
+		maker, err = NewRS256Maker(privateKey, ttl, issuer)
+	default:
+		log.Fatalf("unsupported JWT_ALGORITHM %q", alg)
+	}
+	if err != nil {
+		log.Fatalf("token maker configuration failed: %v", err)
+	}
+	return maker
+}
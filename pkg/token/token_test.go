@@ -0,0 +1,82 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("test-secret-that-is-32-bytes-long")
+
+func TestHS256MakerRoundTrip(t *testing.T) {
+	maker, err := NewHS256Maker(testSecret, time.Minute, "crud")
+	assert.NoError(t, err)
+
+	signed, issued, err := maker.CreateToken(42, "admin", "super-admin", 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, signed)
+
+	claims, err := maker.VerifyToken(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), claims.SubjectID)
+	assert.Equal(t, "admin", claims.Account)
+	assert.Equal(t, "super-admin", claims.Role)
+	assert.Equal(t, "crud", claims.Issuer)
+	assert.Equal(t, issued.ExpiresAt.Unix(), claims.ExpiresAt.Unix())
+}
+
+func TestHS256MakerRejectsShortSecret(t *testing.T) {
+	_, err := NewHS256Maker([]byte("short"), time.Minute, "")
+	assert.Error(t, err)
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	maker, err := NewHS256Maker(testSecret, -time.Minute, "")
+	assert.NoError(t, err)
+
+	signed, _, err := maker.CreateToken(1, "user", "customer", 0)
+	assert.NoError(t, err)
+
+	_, err = maker.VerifyToken(signed)
+	assert.ErrorIs(t, err, ErrExpiredToken)
+}
+
+func TestVerifyTamperedToken(t *testing.T) {
+	maker, err := NewHS256Maker(testSecret, time.Minute, "")
+	assert.NoError(t, err)
+	other, err := NewHS256Maker([]byte("another-secret-that-is-32-bytes!"), time.Minute, "")
+	assert.NoError(t, err)
+
+	signed, _, err := other.CreateToken(1, "admin", "super-admin", 0)
+	assert.NoError(t, err)
+
+	_, err = maker.VerifyToken(signed)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = maker.VerifyToken("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRS256MakerRoundTrip(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	maker, err := NewRS256Maker(privateKey, time.Minute, "")
+	assert.NoError(t, err)
+
+	signed, _, err := maker.CreateToken(9, "user", "customer", 0)
+	assert.NoError(t, err)
+
+	claims, err := maker.VerifyToken(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), claims.SubjectID)
+
+	// An HS256 maker must not accept the RS256 token
+	hsMaker, err := NewHS256Maker(testSecret, time.Minute, "")
+	assert.NoError(t, err)
+	_, err = hsMaker.VerifyToken(signed)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,50 @@
+// Package uow runs work that spans several repositories in one database transaction
+package uow
+
+import (
+	cartRepository "github.com/ratheeshkumar25/pkg/cart/repository"
+	couponRepository "github.com/ratheeshkumar25/pkg/coupon/repository"
+	orderRepository "github.com/ratheeshkumar25/pkg/order/repository"
+	paymentRepository "github.com/ratheeshkumar25/pkg/payment/repository"
+	userRepository "github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+// Repositories are bound to the transaction of one unit of work, they must not
+// be kept or used after Do returns
+type Repositories struct {
+	Inventory  userRepository.InventoryRepository
+	Carts      cartRepository.CartRepository
+	Orders     orderRepository.OrderRepository
+	Payments   paymentRepository.PaymentRepository
+	Coupons    couponRepository.CouponRepository
+	Categories userRepository.CategoryRepository
+}
+
+type UnitOfWork interface {
+	// Do commits everything fn did through repos when it returns nil and rolls it back otherwise
+	Do(fn func(repos *Repositories) error) error
+}
+
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func (u *gormUnitOfWork) Do(fn func(repos *Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Repositories{
+			Inventory:  userRepository.NewInventoryRepository(tx),
+			Carts:      cartRepository.NewCartRepository(tx),
+			Orders:     orderRepository.NewOrderRepository(tx),
+			Payments:   paymentRepository.NewPaymentRepository(tx),
+			Coupons:    couponRepository.NewCouponRepository(tx),
+			Categories: userRepository.NewCategoryRepository(tx),
+		})
+	})
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &gormUnitOfWork{
+		db: db,
+	}
+}
@@ -1,16 +1,23 @@
 package delivery
 
 import (
+	"errors"
 	"log"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/pagination"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
 	"github.com/ratheeshkumar25/pkg/user/usecase"
 )
 
 type AdminHandler struct {
-	adminUseCase usecase.AdminUseCase
+	adminUseCase   usecase.AdminUseCase
+	sessionUseCase usecase.SessionUseCase
+	mfaUseCase     usecase.MFAUseCase
 }
 
 type AdminUseCases interface {
@@ -19,20 +26,38 @@ type AdminUseCases interface {
 	GetUserListHandler(c *gin.Context)
 	AddProductHandler(c *gin.Context)
 	GetProductHandler(c *gin.Context)
+	GetProductByIDHandler(c *gin.Context)
 	UpdateProductHandler(c *gin.Context)
+	PatchProductHandler(c *gin.Context)
 	DeletProductHandler(c *gin.Context)
+	AssignAdminRoleHandler(c *gin.Context)
+	InviteAdminHandler(c *gin.Context)
+	UnlockAccountHandler(c *gin.Context)
 }
 
 func (a *AdminHandler) RegisterAdminHandler(c *gin.Context) {
-	var admin user.AdminRegister
+	var signup user.AdminSignup
 
-	if err := c.BindJSON(&admin); err != nil {
+	if err := c.BindJSON(&signup); err != nil {
 		c.JSON(400, gin.H{"Error": "bindig error"})
 		return
 	}
 
-	err := a.adminUseCase.RegisterAdmin(&admin)
+	admin := user.AdminRegister{
+		Username: signup.Username,
+		Email:    signup.Email,
+		Password: signup.Password,
+	}
+	err := a.adminUseCase.RegisterAdmin(&admin, signup.InviteToken)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidInvite) {
+			c.JSON(403, gin.H{"Error": err.Error()})
+			return
+		}
+		if errors.Is(err, usecase.ErrUsernameTaken) {
+			c.JSON(409, gin.H{"Error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"Error": "admin already register"})
 		return
 	}
@@ -47,26 +72,71 @@ func (a *AdminHandler) LoginAdminHandler(c *gin.Context) {
 		return
 	}
 
-	admin, err := a.adminUseCase.Login(&adminLogin)
+	admin, err := a.adminUseCase.Login(&adminLogin, c.ClientIP())
 	if err != nil {
+		var locked *usecase.LockedError
+		if errors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
 		c.JSON(500, gin.H{"Error": "Wrong UserName and Password"})
 		return
 	}
 
-	c.JSON(200, gin.H{"Status": "Success", "admin": gin.H{
-		"username": admin.Username,
-		"name":     admin.Email,
-	}})
+	mfaEnabled, err := a.mfaUseCase.Enabled(admin.ID, user.AccountAdmin)
+	if err != nil {
+		c.JSON(500, gin.H{"Error": "failed to start session"})
+		return
+	}
+	if mfaEnabled {
+		challenge := &user.MFAChallenge{
+			SubjectID: admin.ID,
+			Account:   user.AccountAdmin,
+			Username:  admin.Username,
+			Role:      admin.Role,
+			UserAgent: c.Request.UserAgent(),
+			ClientIP:  c.ClientIP(),
+		}
+		mfaToken, err := a.mfaUseCase.StartChallenge(challenge)
+		if err != nil {
+			c.JSON(500, gin.H{"Error": "failed to start two-factor challenge"})
+			return
+		}
+		c.JSON(200, gin.H{"Status": "Two-factor authentication required", "mfa_required": true, "mfa_token": mfaToken, "expires_at": challenge.ExpiresAt})
+		return
+	}
+
+	tokens, err := a.sessionUseCase.StartSession(admin.ID, user.AccountAdmin, admin.Role, c.Request.UserAgent(), c.ClientIP(), false)
+	if err != nil {
+		c.JSON(500, gin.H{"Error": "failed to start session"})
+		return
+	}
+
+	response := gin.H{"Status": "Success", "admin": NewAdminResponse(admin), "token": tokens}
+	//**Under a mandatory 2FA policy this session can only be used to enroll
+	if a.mfaUseCase.Required(user.AccountAdmin) {
+		response["mfa_enrollment_required"] = true
+	}
+	c.JSON(200, response)
 }
 
 func (a *AdminHandler) GetUserListHandler(c *gin.Context) {
-	user := c.Query("name")
-	users, err := a.adminUseCase.GetUseList(user)
+	var query user.UserListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters, dates use YYYY-MM-DD"})
+		return
+	}
+
+	users, page, err := a.adminUseCase.GetUseList(&query)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidSort) || errors.Is(err, pagination.ErrInvalidCursor) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, users)
+	c.JSON(200, NewPageResponse(c.Request.URL, NewUserListResponse(*users), query.Page, query.Limit, query.Cursor != "", page))
 }
 
 func (a *AdminHandler) AddProductHandler(c *gin.Context) {
@@ -77,7 +147,17 @@ func (a *AdminHandler) AddProductHandler(c *gin.Context) {
 		return
 	}
 
-	if err := a.adminUseCase.AddProduct(&product); err != nil {
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only admins can add products"})
+		return
+	}
+
+	if err := a.adminUseCase.AddProduct(actor.Username, &product); err != nil {
+		if errors.Is(err, usecase.ErrCategoryNotFound) || errors.Is(err, usecase.ErrInvalidStockQuantity) || errors.Is(err, usecase.ErrInvalidPrice) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": "Failed to add product"})
 		return
 	}
@@ -87,16 +167,53 @@ func (a *AdminHandler) AddProductHandler(c *gin.Context) {
 }
 
 func (a *AdminHandler) GetProductHandler(c *gin.Context) {
-	productname := c.Query("name")
-	products, err := a.adminUseCase.GetProducts(productname)
+	var query user.ProductListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	products, page, err := a.adminUseCase.GetProducts(&query)
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidSort) || errors.Is(err, pagination.ErrInvalidCursor) ||
+			errors.Is(err, usecase.ErrInvalidPriceRange) || errors.Is(err, usecase.ErrPriceFilterCurrency) || errors.Is(err, usecase.ErrInvalidPriceFilter) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(200, products)
+	c.JSON(200, NewPageResponse(c.Request.URL, NewProductListResponse(*products), query.Page, query.Limit, query.Cursor != "", page))
+}
+
+// GetProductByIDHandler returns one product with its version as the ETag, the
+// value to send back in If-Match when updating it
+func (a *AdminHandler) GetProductByIDHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+
+	product, err := a.adminUseCase.FindProduct(id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrProductNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to get product"})
+		return
+	}
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(200, NewProductResponse(product))
 }
 
 func (h *AdminHandler) UpdateProductHandler(c *gin.Context) {
+	ifMatch, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	var product user.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
@@ -109,19 +226,75 @@ func (h *AdminHandler) UpdateProductHandler(c *gin.Context) {
 		return
 	}
 
-	// Update the existing product fields with the new values
+	// Update the existing product fields with the new values, quantity only changes through stock movements
 	existingProduct.ProductName = product.ProductName
 	existingProduct.Description = product.Description
 	existingProduct.Price = product.Price
-	existingProduct.Quantity = product.Quantity
+	existingProduct.LowStockThreshold = product.LowStockThreshold
 	existingProduct.CategoryID = product.CategoryID
 
+	// The update only applies to the version the client read, If-Match wins over the body
+	if ifMatch != 0 {
+		existingProduct.Version = ifMatch
+	} else if product.Version != 0 {
+		existingProduct.Version = product.Version
+	}
+
 	if err := h.adminUseCase.UpdateProduct(existingProduct); err != nil {
+		if errors.Is(err, usecase.ErrCategoryNotFound) || errors.Is(err, usecase.ErrInvalidPrice) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			c.JSON(conflictStatus(c), gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": "Failed to update product"})
 		return
 	}
 
-	c.JSON(200, existingProduct)
+	c.Header("ETag", versionETag(existingProduct.Version))
+	c.JSON(200, NewProductResponse(existingProduct))
+}
+
+// PatchProductHandler applies a JSON merge patch, members left out of the body keep their value
+func (h *AdminHandler) PatchProductHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	ifMatch, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var patch user.ProductPatch
+	if err := bindMergePatch(c, &patch); err != nil {
+		c.JSON(patchErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if ifMatch != 0 {
+		patch.Version = ifMatch
+	}
+
+	product, err := h.adminUseCase.PatchProduct(id, &patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrProductNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, mergepatch.ErrInvalidPatch), errors.Is(err, usecase.ErrCategoryNotFound), errors.Is(err, usecase.ErrInvalidPrice):
+			c.JSON(400, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrVersionConflict):
+			c.JSON(conflictStatus(c), gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "Failed to update product"})
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(200, NewProductResponse(product))
 }
 
 func (a *AdminHandler) DeletProductHandler(c *gin.Context) {
@@ -139,6 +312,83 @@ func (a *AdminHandler) DeletProductHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "product deleted successfully"})
 }
 
-func NewAdminHandler(adminUseCase usecase.AdminUseCase) *AdminHandler {
-	return &AdminHandler{adminUseCase: adminUseCase}
+func (a *AdminHandler) AssignAdminRoleHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	var update user.AdminRoleUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(400, gin.H{"error": "role is required"})
+		return
+	}
+
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only admins can assign roles"})
+		return
+	}
+
+	if err := a.adminUseCase.AssignRole(actor.ID, uint(id), update.Role); err != nil {
+		if errors.Is(err, usecase.ErrInvalidRole) || errors.Is(err, usecase.ErrOwnRole) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "admin role updated successfully"})
+}
+
+func (a *AdminHandler) InviteAdminHandler(c *gin.Context) {
+	var request user.AdminInviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "email and role are required"})
+		return
+	}
+
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only admins can send invites"})
+		return
+	}
+
+	invite, inviteToken, err := a.adminUseCase.InviteAdmin(actor.ID, &request)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRole) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to create invite"})
+		return
+	}
+
+	// The plain token is only shown once, it is stored hashed
+	c.JSON(201, gin.H{"message": "invite created", "invite": NewInviteResponse(invite), "invite_token": inviteToken})
+}
+
+func (a *AdminHandler) UnlockAccountHandler(c *gin.Context) {
+	var request user.UnlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "account (user or admin) and username are required"})
+		return
+	}
+
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only admins can unlock accounts"})
+		return
+	}
+
+	if err := a.adminUseCase.UnlockAccount(actor.Username, request.Account, request.Username); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "account unlocked"})
+}
+
+func NewAdminHandler(adminUseCase usecase.AdminUseCase, sessionUseCase usecase.SessionUseCase, mfaUseCase usecase.MFAUseCase) *AdminHandler {
+	return &AdminHandler{adminUseCase: adminUseCase, sessionUseCase: sessionUseCase, mfaUseCase: mfaUseCase}
 }
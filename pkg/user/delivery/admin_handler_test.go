@@ -4,12 +4,20 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pagination"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
@@ -20,29 +28,54 @@ type MockAdminUseCase struct {
 	mock.Mock
 }
 
-func (m *MockAdminUseCase) RegisterAdmin(admin *user.AdminRegister) error {
+func (m *MockAdminUseCase) RegisterAdmin(admin *user.AdminRegister, inviteToken string) error {
+	args := m.Called(admin, inviteToken)
+	return args.Error(0)
+}
+
+func (m *MockAdminUseCase) InviteAdmin(actorID uint, request *user.AdminInviteRequest) (*user.AdminInvite, string, error) {
+	args := m.Called(actorID, request)
+	return args.Get(0).(*user.AdminInvite), args.String(1), args.Error(2)
+}
+
+func (m *MockAdminUseCase) BootstrapSuperAdmin(admin *user.AdminRegister) (bool, error) {
 	args := m.Called(admin)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAdminUseCase) Login(login *user.AdminLogin, clientIP string) (*user.AdminRegister, error) {
+	args := m.Called(login, clientIP)
+	return args.Get(0).(*user.AdminRegister), args.Error(1)
+}
+
+func (m *MockAdminUseCase) UnlockAccount(actor, account, username string) error {
+	args := m.Called(actor, account, username)
 	return args.Error(0)
 }
 
-func (m *MockAdminUseCase) Login(login *user.AdminLogin) (*user.AdminRegister, error) {
-	args := m.Called(login)
+func (m *MockAdminUseCase) GetAdminDetail(id uint) (*user.AdminRegister, error) {
+	args := m.Called(id)
 	return args.Get(0).(*user.AdminRegister), args.Error(1)
 }
 
-func (m *MockAdminUseCase) GetUseList(name string) (*[]user.UserRegister, error) {
-	args := m.Called(name)
-	return args.Get(0).(*[]user.UserRegister), args.Error(1)
+func (m *MockAdminUseCase) AssignRole(actorID, adminID uint, role string) error {
+	args := m.Called(actorID, adminID, role)
+	return args.Error(0)
 }
 
-func (m *MockAdminUseCase) AddProduct(product *user.Product) error {
-	args := m.Called(product)
+func (m *MockAdminUseCase) GetUseList(query *user.UserListQuery) (*[]user.UserRegister, *pagination.Page, error) {
+	args := m.Called(query)
+	return args.Get(0).(*[]user.UserRegister), args.Get(1).(*pagination.Page), args.Error(2)
+}
+
+func (m *MockAdminUseCase) AddProduct(actor string, product *user.Product) error {
+	args := m.Called(actor, product)
 	return args.Error(0)
 }
 
-func (m *MockAdminUseCase) GetProducts(productname string) (*[]user.Product, error) {
-	args := m.Called(productname)
-	return args.Get(0).(*[]user.Product), args.Error(1)
+func (m *MockAdminUseCase) GetProducts(query *user.ProductListQuery) (*[]user.Product, *pagination.Page, error) {
+	args := m.Called(query)
+	return args.Get(0).(*[]user.Product), args.Get(1).(*pagination.Page), args.Error(2)
 }
 
 func (m *MockAdminUseCase) FindProduct(id uint) (*user.Product, error) {
@@ -55,6 +88,11 @@ func (m *MockAdminUseCase) UpdateProduct(product *user.Product) error {
 	return args.Error(0)
 }
 
+func (m *MockAdminUseCase) PatchProduct(id uint, patch *user.ProductPatch) (*user.Product, error) {
+	args := m.Called(id, patch)
+	return args.Get(0).(*user.Product), args.Error(1)
+}
+
 func (m *MockAdminUseCase) DeleteProduct(id int) error {
 	args := m.Called(id)
 	return args.Error(0)
@@ -63,7 +101,7 @@ func (m *MockAdminUseCase) DeleteProduct(id int) error {
 
 func TestRegisterAdminHandler(t *testing.T) {
     mockUseCase := new(MockAdminUseCase)
-    handler := NewAdminHandler(mockUseCase)
+    handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
     router := gin.Default()
     router.POST("/adminsignup", handler.RegisterAdminHandler)
@@ -73,11 +111,17 @@ func TestRegisterAdminHandler(t *testing.T) {
         Password: "password123",
         Email:    "admin1@example.com",
     }
+    signup := &user.AdminSignup{
+        Username:    admin.Username,
+        Password:    admin.Password,
+        Email:       admin.Email,
+        InviteToken: "invite-token",
+    }
 
     // Successful registration setup
-    mockUseCase.On("RegisterAdmin", admin).Return(nil)
+    mockUseCase.On("RegisterAdmin", admin, "invite-token").Return(nil)
 
-    body, _ := json.Marshal(admin)
+    body, _ := json.Marshal(signup)
     req, _ := http.NewRequest("POST", "/adminsignup", bytes.NewBuffer(body))
     req.Header.Set("Content-Type", "application/json")
 
@@ -89,9 +133,9 @@ func TestRegisterAdminHandler(t *testing.T) {
 
    // Error case setup
    mockUseCase.ExpectedCalls = nil //***** Clear previous expectations
-   mockUseCase.On("RegisterAdmin", admin).Return(errors.New("admin already register"))
+   mockUseCase.On("RegisterAdmin", admin, "invite-token").Return(errors.New("admin already register"))
 
-   body, _ = json.Marshal(admin)
+   body, _ = json.Marshal(signup)
    req, _ = http.NewRequest("POST", "/adminsignup", bytes.NewBuffer(body))
    req.Header.Set("Content-Type", "application/json")
 
@@ -100,11 +144,56 @@ func TestRegisterAdminHandler(t *testing.T) {
 
    assert.Equal(t, http.StatusInternalServerError, w.Code)
    assert.JSONEq(t, `{"Error":"admin already register"}`, w.Body.String())
+
+   // Signing up without a valid invite is forbidden
+   signup.InviteToken = ""
+   mockUseCase.On("RegisterAdmin", admin, "").Return(usecase.ErrInvalidInvite)
+
+   body, _ = json.Marshal(signup)
+   req, _ = http.NewRequest("POST", "/adminsignup", bytes.NewBuffer(body))
+   req.Header.Set("Content-Type", "application/json")
+
+   w = httptest.NewRecorder()
+   router.ServeHTTP(w, req)
+
+   assert.Equal(t, http.StatusForbidden, w.Code)
+   assert.JSONEq(t, `{"Error":"a valid admin invite is required"}`, w.Body.String())
+}
+
+func TestInviteAdminHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	// Simulate the authentication middleware loading the inviting super-admin
+	actor := &user.AdminRegister{Model: gorm.Model{ID: 1}, Username: "root", Role: "super-admin"}
+	router := gin.Default()
+	router.POST("/admins/invites", func(c *gin.Context) { c.Set(middleware.AdminKey, actor) }, handler.InviteAdminHandler)
+
+	request := &user.AdminInviteRequest{Email: "ops@example.com", Role: "support"}
+	invite := &user.AdminInvite{
+		Model:     gorm.Model{ID: 4},
+		Email:     "ops@example.com",
+		Role:      "support",
+		InvitedBy: 1,
+		ExpiresAt: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+	}
+	mockUseCase.On("InviteAdmin", uint(1), request).Return(invite, "plain-invite-token", nil)
+
+	body, _ := json.Marshal(request)
+	req, _ := http.NewRequest("POST", "/admins/invites", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"message":"invite created","invite_token":"plain-invite-token","invite":{"id":4,"email":"ops@example.com","role":"support","invited_by":1,"expires_at":"2024-01-04T00:00:00Z"}}`, w.Body.String())
 }
 
 func TestLoginAdminHandler(t *testing.T) {
     mockUseCase := new(MockAdminUseCase)
-    handler := NewAdminHandler(mockUseCase)
+    mockSessionUseCase := new(MockSessionUseCase)
+    mockMFAUseCase := new(MockMFAUseCase)
+    handler := NewAdminHandler(mockUseCase, mockSessionUseCase, mockMFAUseCase)
 
     router := gin.Default()
     router.POST("/adminlogin", handler.LoginAdminHandler)
@@ -115,12 +204,17 @@ func TestLoginAdminHandler(t *testing.T) {
     }
 
     admin := &user.AdminRegister{
+        Model:    gorm.Model{ID: 7},
         Username: "admin1",
         Email:    "admin1@example.com",
+        Role:     "super-admin",
     }
 
     // Successful login setup
-    mockUseCase.On("Login", adminLogin).Return(admin, nil)
+    mockUseCase.On("Login", adminLogin, mock.Anything).Return(admin, nil)
+    mockMFAUseCase.On("Enabled", uint(7), user.AccountAdmin).Return(false, nil)
+    mockMFAUseCase.On("Required", user.AccountAdmin).Return(false)
+    mockSessionUseCase.On("StartSession", uint(7), user.AccountAdmin, "super-admin", mock.Anything, mock.Anything, false).Return(testTokenPair, nil)
 
     body, _ := json.Marshal(adminLogin)
     req, _ := http.NewRequest("POST", "/adminlogin", bytes.NewBuffer(body))
@@ -130,24 +224,90 @@ func TestLoginAdminHandler(t *testing.T) {
     router.ServeHTTP(w, req)
 
     assert.Equal(t, http.StatusOK, w.Code)
-    assert.JSONEq(t, `{"Status":"Success", "admin":{"username":"admin1", "name":"admin1@example.com"}}`, w.Body.String())
-
 
+    expectedResponse, _ := json.Marshal(gin.H{"Status": "Success", "admin": gin.H{
+        "id":         7,
+        "username":   "admin1",
+        "email":      "admin1@example.com",
+        "role":       "super-admin",
+        "created_at": "0001-01-01T00:00:00Z",
+    }, "token": testTokenPair})
+    assert.JSONEq(t, string(expectedResponse), w.Body.String())
+    mockSessionUseCase.AssertExpectations(t)
 }
 
 
+func TestGetUserListHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	router := gin.Default()
+	router.GET("/userlist", handler.GetUserListHandler)
+
+	verifiedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	users := &[]user.UserRegister{{
+		Model:      gorm.Model{ID: 3, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		UserName:   "ratheeshgk",
+		Name:       "Ratheesh G",
+		Email:      "ratheeshgk@live1.com",
+		Phone:      "9961429911",
+		Password:   "$2a$10$IgtDVCIs6Tx07/0IQ3A5f.UYWOvbw4CEGyukAnESd8rgI8Bc",
+		Role:       "customer",
+		VerifiedAt: &verifiedAt,
+	}}
+	createdFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockUseCase.On("GetUseList", &user.UserListQuery{
+		EmailDomain: "live1.com",
+		CreatedFrom: &createdFrom,
+		Sort:        "-created_at",
+		Page:        2,
+		Limit:       1,
+	}).Return(users, &pagination.Page{Total: 3}, nil)
+	mockUseCase.On("GetUseList", &user.UserListQuery{Sort: "password"}).Return((*[]user.UserRegister)(nil), (*pagination.Page)(nil), fmt.Errorf("failed to get user list: %w", pagination.ErrInvalidSort))
+
+	req, _ := http.NewRequest("GET", "/userlist?email_domain=live1.com&created_from=2024-01-01&sort=-created_at&page=2&limit=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// The bcrypt hash must never be sent to the client
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data":[{"id":3,"username":"ratheeshgk","name":"Ratheesh G","email":"ratheeshgk@live1.com","phone":"9961429911","email_verified":true,"version":0,"created_at":"2024-01-01T00:00:00Z"}],
+		"page":2,"limit":1,"total":3,"total_pages":3,
+		"links":{
+			"next":"/userlist?created_from=2024-01-01&email_domain=live1.com&limit=1&page=3&sort=-created_at",
+			"prev":"/userlist?created_from=2024-01-01&email_domain=live1.com&limit=1&page=1&sort=-created_at"
+		}
+	}`, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "$2a$")
+
+	// Sorting is limited to an allow-list
+	req, _ = http.NewRequest("GET", "/userlist?sort=password", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("GET", "/userlist?created_from=yesterday", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestAddProductHandler(t *testing.T) {
 	mockUseCase := new(MockAdminUseCase)
-	handler := NewAdminHandler(mockUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
+	actor := &user.AdminRegister{Model: gorm.Model{ID: 1}, Username: "root"}
 	router := gin.Default()
-	router.POST("/addproduct", handler.AddProductHandler)
+	router.POST("/addproduct", func(c *gin.Context) { c.Set(middleware.AdminKey, actor) }, handler.AddProductHandler)
 
 	product := &user.Product{
 		ProductName: "Product1",
-		Price:       18.30,
+		Quantity:    5,
+		Price:       money.Money{Amount: 1830, Currency: "USD"},
 	}
-	mockUseCase.On("AddProduct", product).Return(nil)
+	// The opening stock is booked against the calling admin
+	mockUseCase.On("AddProduct", "root", product).Return(nil)
 
 
 	body, _ := json.Marshal(product)
@@ -162,7 +322,7 @@ func TestAddProductHandler(t *testing.T) {
 
 	// Error case setup
     mockUseCase.ExpectedCalls = nil  // **Clear previous expectations
-    mockUseCase.On("AddProduct", product).Return(errors.New("database error"))
+    mockUseCase.On("AddProduct", "root", product).Return(errors.New("database error"))
 
     body, _ = json.Marshal(product)
     req, _ = http.NewRequest("POST", "/addproduct", bytes.NewBuffer(body))
@@ -177,35 +337,71 @@ func TestAddProductHandler(t *testing.T) {
 
 func TestGetProductHandler(t *testing.T) {
 	mockUseCase := new(MockAdminUseCase)
-	handler := NewAdminHandler(mockUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
     
 	router := gin.Default()
 	router.GET("/getproduct", handler.GetProductHandler)
 	
 	productName := "Product1"
 	products := &[]user.Product{
-		{ProductName: "Product1", Price: 18.30},
-		{ProductName: "Product2", Price: 20.50},
+		{ProductName: "Product1", Price: money.Money{Amount: 1830, Currency: "USD"}},
+		{ProductName: "Product2", Price: money.Money{Amount: 2050, Currency: "USD"}},
 	}
-	mockUseCase.On("GetProducts", productName).Return(products, nil)
+	categoryID := uint(4)
+	minPrice, maxPrice := "10", "25.50"
+	inStock := true
+	// The use case fills in the default page and limit
+	mockUseCase.On("GetProducts", &user.ProductListQuery{
+		Name:       productName,
+		CategoryID: &categoryID,
+		Currency:   "USD",
+		MinPrice:   &minPrice,
+		MaxPrice:   &maxPrice,
+		InStock:    &inStock,
+		Sort:       "-price",
+	}).Run(func(args mock.Arguments) {
+		query := args.Get(0).(*user.ProductListQuery)
+		query.Page, query.Limit = 1, 20
+	}).Return(products, &pagination.Page{Total: 2}, nil)
 
 	req, _ := http.NewRequest("GET", "/getproduct", nil)
 	q := req.URL.Query()
 	q.Add("name", productName)
+	q.Add("category_id", "4")
+	q.Add("currency", "USD")
+	q.Add("min_price", "10")
+	q.Add("max_price", "25.50")
+	q.Add("in_stock", "true")
+	q.Add("sort", "-price")
 	req.URL.RawQuery = q.Encode()
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	responseBody, _ := json.Marshal(products)
-
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.JSONEq(t, string(responseBody), w.Body.String())
+	assert.JSONEq(t, `{
+		"data":[
+			{"id":0,"product_name":"Product1","description":"","quantity":0,"price":{"amount":"18.30","currency":"USD"},"category_id":0,"low_stock_threshold":0,"low_stock":false,"version":0,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},
+			{"id":0,"product_name":"Product2","description":"","quantity":0,"price":{"amount":"20.50","currency":"USD"},"category_id":0,"low_stock_threshold":0,"low_stock":false,"version":0,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}
+		],
+		"page":1,"limit":20,"total":2,"total_pages":1,
+		"links":{"next":null,"prev":null}
+	}`, w.Body.String())
+
+	// An inverted price range is rejected
+	mockUseCase.On("GetProducts", mock.MatchedBy(func(query *user.ProductListQuery) bool {
+		return query.MinPrice != nil && *query.MinPrice == "30"
+	})).Return((*[]user.Product)(nil), (*pagination.Page)(nil), usecase.ErrInvalidPriceRange)
+	req, _ = http.NewRequest("GET", "/getproduct?currency=USD&min_price=30&max_price=5", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"min_price cannot be greater than max_price"}`, w.Body.String())
 }
 
 func TestUpdateProductHandler(t *testing.T) {
 	mockUseCase := new(MockAdminUseCase)
-	handler := NewAdminHandler(mockUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
 	//Setpup the new gin router 
 	router := gin.Default()
@@ -216,19 +412,21 @@ func TestUpdateProductHandler(t *testing.T) {
 		Model:       gorm.Model{ID: 1},
 		ProductName: "Product1",
 		Description: "TEST",
-		Price:       18.300,
+		Price:       money.Money{Amount: 1830, Currency: "USD"},
 		Quantity:    10,
 		CategoryID:  1,
+		Version:     3,
 	}
 
-	// Updated product details
+	// Updated product details, the quantity is ignored as stock only moves through the ledger
 	updatedProduct := &user.Product{
-		Model:       gorm.Model{ID: 1},
-		ProductName: "UpdatedProduct",
-		Description: "Updated Description",
-		Price:       25.500,
-		Quantity:    15,
-		CategoryID:  1,
+		Model:             gorm.Model{ID: 1},
+		ProductName:       "UpdatedProduct",
+		Description:       "Updated Description",
+		Price:             money.Money{Amount: 2550, Currency: "USD"},
+		Quantity:          15,
+		CategoryID:        1,
+		LowStockThreshold: 10,
 	}
 
 	// Mock the FindProduct and UpdateProduct methods
@@ -237,9 +435,15 @@ func TestUpdateProductHandler(t *testing.T) {
 		return p.ProductName == updatedProduct.ProductName &&
 			p.Description == updatedProduct.Description &&
 			p.Price == updatedProduct.Price &&
-			p.Quantity == updatedProduct.Quantity &&
-			p.CategoryID == updatedProduct.CategoryID
-	})).Return(nil)
+			p.Quantity == product.Quantity &&
+			p.LowStockThreshold == updatedProduct.LowStockThreshold &&
+			p.CategoryID == updatedProduct.CategoryID &&
+			p.Version == 3
+	})).Run(func(args mock.Arguments) {
+		// The repository bumps the version of a successful update
+		args.Get(0).(*user.Product).Version++
+	}).Return(nil).Once()
+	mockUseCase.On("UpdateProduct", mock.Anything).Return(repository.ErrVersionConflict)
 
 
 
@@ -253,14 +457,138 @@ func TestUpdateProductHandler(t *testing.T) {
 	// Check the status code
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	// Check the response body
-	expectedResponse, _ := json.Marshal(updatedProduct)
-	assert.JSONEq(t, string(expectedResponse), w.Body.String())
+	// Check the response body, gorm internals such as DeletedAt are not exposed
+	assert.JSONEq(t, `{"id":1,"product_name":"UpdatedProduct","description":"Updated Description","quantity":10,"price":{"amount":"25.50","currency":"USD"},"category_id":1,"low_stock_threshold":10,"low_stock":true,"version":4,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	// A second writer still holding version 3 loses, 412 for If-Match and 409 for a body version
+	req, _ = http.NewRequest("PUT", "/productupdate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"3"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	updatedProduct.Version = 3
+	body, _ = json.Marshal(updatedProduct)
+	req, _ = http.NewRequest("PUT", "/productupdate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"record was modified by someone else, reload it and try again"}`, w.Body.String())
+
+	// Weak ETags never satisfy If-Match
+	req, _ = http.NewRequest("PUT", "/productupdate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `W/"4"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPatchProductHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	router := gin.Default()
+	router.PATCH("/products/:id", handler.PatchProductHandler)
+
+	patched := &user.Product{
+		Model:       gorm.Model{ID: 1},
+		ProductName: "Shoe",
+		Price:       money.Money{Amount: 1200, Currency: "USD"},
+		CategoryID:  1,
+		Version:     5,
+	}
+
+	// null and absent members reach the use case apart, If-Match becomes the expected version
+	mockUseCase.On("PatchProduct", uint(1), &user.ProductPatch{
+		Description: mergepatch.Null[string](),
+		Price:       mergepatch.Value(user.PricePatch{Amount: mergepatch.Value(json.Number("12.00"))}),
+		Version:     4,
+	}).Return(patched, nil)
+	mockUseCase.On("PatchProduct", uint(2), &user.ProductPatch{ProductName: mergepatch.Value("Boot")}).Return((*user.Product)(nil), usecase.ErrProductNotFound)
+
+	req, _ := http.NewRequest("PATCH", "/products/1", bytes.NewBufferString(`{"description":null,"price":{"amount":"12.00"}}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"id":1,"product_name":"Shoe","description":"","quantity":0,"price":{"amount":"12.00","currency":"USD"},"category_id":1,"low_stock_threshold":0,"low_stock":false,"version":5,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, w.Body.String())
+
+	req, _ = http.NewRequest("PATCH", "/products/2", bytes.NewBufferString(`{"product_name":"Boot"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Read-only members and other media types are refused before the use case runs
+	req, _ = http.NewRequest("PATCH", "/products/1", bytes.NewBufferString(`{"id":7}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("PATCH", "/products/1", bytes.NewBufferString(`product_name=Boot`))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	mockUseCase.AssertNumberOfCalls(t, "PatchProduct", 2)
+}
+
+func TestGetProductByIDHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	router := gin.Default()
+	router.GET("/products/:id", handler.GetProductByIDHandler)
+	router.PATCH("/products/:id", handler.PatchProductHandler)
+
+	product := &user.Product{
+		Model:       gorm.Model{ID: 1},
+		ProductName: "Shoe",
+		Price:       money.Money{Amount: 1200, Currency: "USD"},
+		CategoryID:  1,
+		Version:     6,
+	}
+	mockUseCase.On("FindProduct", uint(1)).Return(product, nil)
+	mockUseCase.On("FindProduct", uint(2)).Return((*user.Product)(nil), usecase.ErrProductNotFound)
+
+	req, _ := http.NewRequest("GET", "/products/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"6"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"id":1,"product_name":"Shoe","description":"","quantity":0,"price":{"amount":"12.00","currency":"USD"},"category_id":1,"low_stock_threshold":0,"low_stock":false,"version":6,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`, w.Body.String())
+
+	// The ETag sent back as If-Match becomes the version the patch expects
+	etag := w.Header().Get("ETag")
+	mockUseCase.On("PatchProduct", uint(1), &user.ProductPatch{ProductName: mergepatch.Value("Boot"), Version: 6}).Return(product, nil)
+	req, _ = http.NewRequest("PATCH", "/products/1", bytes.NewBufferString(`{"product_name":"Boot"}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/products/2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	mockUseCase.AssertExpectations(t)
 }
 
 func TestDeleteProductHandler(t *testing.T) { 
 	mockUseCase := new(MockAdminUseCase)
-	handler := NewAdminHandler(mockUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
 
 	router := gin.Default()
@@ -280,3 +608,65 @@ func TestDeleteProductHandler(t *testing.T) {
 	assert.JSONEq(t, `{"message":"product deleted successfully"}`, w.Body.String())
 }
 
+
+func TestAssignAdminRoleHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	// Simulate the authentication middleware loading the calling super-admin
+	actor := &user.AdminRegister{Model: gorm.Model{ID: 1}, Username: "root", Role: "super-admin"}
+	router := gin.Default()
+	router.PUT("/admins/:id/role", func(c *gin.Context) { c.Set(middleware.AdminKey, actor) }, handler.AssignAdminRoleHandler)
+
+	mockUseCase.On("AssignRole", uint(1), uint(2), "catalog-manager").Return(nil)
+	mockUseCase.On("AssignRole", uint(1), uint(2), "customer").Return(usecase.ErrInvalidRole)
+
+	body, _ := json.Marshal(user.AdminRoleUpdate{Role: "catalog-manager"})
+	req, _ := http.NewRequest("PUT", "/admins/2/role", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"admin role updated successfully"}`, w.Body.String())
+
+	// Customer is not a staff role
+	body, _ = json.Marshal(user.AdminRoleUpdate{Role: "customer"})
+	req, _ = http.NewRequest("PUT", "/admins/2/role", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"role cannot be assigned to an admin account"}`, w.Body.String())
+}
+
+func TestUnlockAccountHandler(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	actor := &user.AdminRegister{Model: gorm.Model{ID: 1}, Username: "root", Role: "super-admin"}
+	router := gin.Default()
+	router.POST("/admins/unlock", func(c *gin.Context) { c.Set(middleware.AdminKey, actor) }, handler.UnlockAccountHandler)
+
+	mockUseCase.On("UnlockAccount", "root", "user", "ratheeshgk").Return(nil)
+
+	body, _ := json.Marshal(user.UnlockRequest{Account: "user", Username: "ratheeshgk"})
+	req, _ := http.NewRequest("POST", "/admins/unlock", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"account unlocked"}`, w.Body.String())
+
+	// Only user and admin accounts exist
+	body, _ = json.Marshal(user.UnlockRequest{Account: "robot", Username: "ratheeshgk"})
+	req, _ = http.NewRequest("POST", "/admins/unlock", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockUseCase.AssertNumberOfCalls(t, "UnlockAccount", 1)
+}
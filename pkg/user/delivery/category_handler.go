@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type CategoryHandler struct {
+	categoryUseCase usecase.CategoryUseCase
+}
+
+type CategoryUseCases interface {
+	ListCategoriesHandler(c *gin.Context)
+	GetCategoryHandler(c *gin.Context)
+	CreateCategoryHandler(c *gin.Context)
+	UpdateCategoryHandler(c *gin.Context)
+	DeleteCategoryHandler(c *gin.Context)
+}
+
+func (h *CategoryHandler) ListCategoriesHandler(c *gin.Context) {
+	categories, err := h.categoryUseCase.ListCategories()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list categories"})
+		return
+	}
+	c.JSON(200, gin.H{"data": NewCategoryListResponse(categories)})
+}
+
+func (h *CategoryHandler) GetCategoryHandler(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+
+	category, children, err := h.categoryUseCase.GetCategory(id)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	response := NewCategoryResponse(category)
+	response.Children = NewCategoryListResponse(children)
+	c.JSON(200, response)
+}
+
+func (h *CategoryHandler) CreateCategoryHandler(c *gin.Context) {
+	var request user.CategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	category, err := h.categoryUseCase.CreateCategory(&request)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(201, NewCategoryResponse(category))
+}
+
+func (h *CategoryHandler) UpdateCategoryHandler(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+
+	var request user.CategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "name is required"})
+		return
+	}
+
+	category, err := h.categoryUseCase.UpdateCategory(id, &request)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(200, NewCategoryResponse(category))
+}
+
+func (h *CategoryHandler) DeleteCategoryHandler(c *gin.Context) {
+	id, ok := categoryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.categoryUseCase.DeleteCategory(id); err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "category deleted successfully"})
+}
+
+func categoryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid category ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrCategoryNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCategoryCycle):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrCategoryInUse):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "category request failed"})
+	}
+}
+
+func NewCategoryHandler(categoryUseCase usecase.CategoryUseCase) *CategoryHandler {
+	return &CategoryHandler{
+		categoryUseCase: categoryUseCase,
+	}
+}
@@ -0,0 +1,101 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockCategoryUseCase is a mock implementation of the CategoryUseCase interface
+type MockCategoryUseCase struct {
+	mock.Mock
+}
+
+func (m *MockCategoryUseCase) CreateCategory(request *user.CategoryRequest) (*user.Category, error) {
+	args := m.Called(request)
+	return args.Get(0).(*user.Category), args.Error(1)
+}
+
+func (m *MockCategoryUseCase) GetCategory(id uint) (*user.Category, []user.Category, error) {
+	args := m.Called(id)
+	return args.Get(0).(*user.Category), args.Get(1).([]user.Category), args.Error(2)
+}
+
+func (m *MockCategoryUseCase) ListCategories() ([]user.Category, error) {
+	args := m.Called()
+	return args.Get(0).([]user.Category), args.Error(1)
+}
+
+func (m *MockCategoryUseCase) UpdateCategory(id uint, request *user.CategoryRequest) (*user.Category, error) {
+	args := m.Called(id, request)
+	return args.Get(0).(*user.Category), args.Error(1)
+}
+
+func (m *MockCategoryUseCase) DeleteCategory(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestCategoryHandlers(t *testing.T) {
+	mockUseCase := new(MockCategoryUseCase)
+	handler := NewCategoryHandler(mockUseCase)
+
+	router := gin.Default()
+	router.GET("/categories/:id", handler.GetCategoryHandler)
+	router.POST("/categories", handler.CreateCategoryHandler)
+	router.PUT("/categories/:id", handler.UpdateCategoryHandler)
+	router.DELETE("/categories/:id", handler.DeleteCategoryHandler)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	parentID := uint(1)
+	shoes := &user.Category{Model: gorm.Model{ID: 2, CreatedAt: createdAt, UpdatedAt: createdAt}, Name: "Shoes", ParentID: &parentID}
+	running := user.Category{Model: gorm.Model{ID: 3, CreatedAt: createdAt, UpdatedAt: createdAt}, Name: "Running", ParentID: &shoes.ID}
+
+	mockUseCase.On("CreateCategory", &user.CategoryRequest{Name: "Shoes", ParentID: &parentID}).Return(shoes, nil)
+	mockUseCase.On("GetCategory", uint(2)).Return(shoes, []user.Category{running}, nil)
+	mockUseCase.On("UpdateCategory", uint(1), &user.CategoryRequest{Name: "Clothing", ParentID: &running.ID}).Return((*user.Category)(nil), usecase.ErrCategoryCycle)
+	mockUseCase.On("DeleteCategory", uint(2)).Return(usecase.ErrCategoryInUse)
+	mockUseCase.On("DeleteCategory", uint(9)).Return(fmt.Errorf("%w: 9", usecase.ErrCategoryNotFound))
+
+	req, _ := http.NewRequest("POST", "/categories", bytes.NewBufferString(`{"name":"Shoes","parent_id":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"id":2,"name":"Shoes","description":"","parent_id":1,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}`, w.Body.String())
+
+	// A category comes with its direct subcategories
+	req, _ = http.NewRequest("GET", "/categories/2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":2,"name":"Shoes","description":"","parent_id":1,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z",
+		"children":[{"id":3,"name":"Running","description":"","parent_id":2,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}]}`, w.Body.String())
+
+	req, _ = http.NewRequest("PUT", "/categories/1", bytes.NewBufferString(`{"name":"Clothing","parent_id":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/categories/2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"category still has subcategories or products"}`, w.Body.String())
+
+	req, _ = http.NewRequest("DELETE", "/categories/9", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,44 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidIfMatch rejects If-Match values that are not one strong ETag from versionETag
+var errInvalidIfMatch = errors.New(`If-Match must be a single ETag such as "3"`)
+
+// versionETag is the strong ETag of a versioned record
+func versionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ifMatchVersion returns the version named by the If-Match header,
+// 0 when the header is absent or "*" so the update is unconditional
+func ifMatchVersion(c *gin.Context) (uint, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	//**Weak validators never match for If-Match, see RFC 9110 section 13.1.1
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.ParseUint(header[1:len(header)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, errInvalidIfMatch
+	}
+	return uint(version), nil
+}
+
+// conflictStatus answers a lost update with 412 when the client used If-Match
+// and with 409 when the stale version came in the body
+func conflictStatus(c *gin.Context) int {
+	if c.GetHeader("If-Match") != "" {
+		return 412
+	}
+	return 409
+}
@@ -0,0 +1,113 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type InventoryHandler struct {
+	inventoryUseCase usecase.InventoryUseCase
+}
+
+type InventoryUseCases interface {
+	RecordMovementHandler(c *gin.Context)
+	StockHistoryHandler(c *gin.Context)
+	LowStockHandler(c *gin.Context)
+}
+
+// RecordMovementHandler books a receipt, sale, adjustment or return against a product
+func (h *InventoryHandler) RecordMovementHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+
+	var request user.StockMovementRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "type (receipt, sale, adjustment or return), quantity and reason are required"})
+		return
+	}
+
+	actor, ok := middleware.CurrentAdmin(c)
+	if !ok {
+		c.JSON(403, gin.H{"error": "only admins can move stock"})
+		return
+	}
+
+	movement, product, err := h.inventoryUseCase.RecordMovement(actor.Username, id, &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrProductNotFound):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrInvalidStockQuantity):
+			c.JSON(400, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrInsufficientStock):
+			c.JSON(409, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to record stock movement"})
+		}
+		return
+	}
+	c.JSON(201, gin.H{"movement": NewStockMovementResponse(movement), "product": NewProductResponse(product)})
+}
+
+// StockHistoryHandler pages through a product's ledger, newest first
+func (h *InventoryHandler) StockHistoryHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+
+	var query user.StockHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	history, err := h.inventoryUseCase.StockHistory(id, &query)
+	if err != nil {
+		if errors.Is(err, usecase.ErrProductNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to load stock history"})
+		return
+	}
+	c.JSON(200, StockHistoryResponse{
+		Product:       NewProductResponse(history.Product),
+		LedgerBalance: history.LedgerBalance,
+		PageResponse:  NewPageResponse(c.Request.URL, NewStockMovementListResponse(history.Movements), query.Page, query.Limit, false, &pagination.Page{Total: history.Total}),
+	})
+}
+
+// LowStockHandler lists the products at or below their low-stock threshold
+func (h *InventoryHandler) LowStockHandler(c *gin.Context) {
+	products, err := h.inventoryUseCase.LowStock()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list low stock products"})
+		return
+	}
+	c.JSON(200, gin.H{"data": NewProductListResponse(products)})
+}
+
+func productID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid product ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func NewInventoryHandler(inventoryUseCase usecase.InventoryUseCase) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryUseCase: inventoryUseCase,
+	}
+}
@@ -0,0 +1,122 @@
+package delivery
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockInventoryUseCase is a mock implementation of the InventoryUseCase interface
+type MockInventoryUseCase struct {
+	mock.Mock
+}
+
+func (m *MockInventoryUseCase) RecordMovement(actor string, productID uint, request *user.StockMovementRequest) (*user.StockMovement, *user.Product, error) {
+	args := m.Called(actor, productID, request)
+	return args.Get(0).(*user.StockMovement), args.Get(1).(*user.Product), args.Error(2)
+}
+
+func (m *MockInventoryUseCase) StockHistory(productID uint, query *user.StockHistoryQuery) (*user.StockHistory, error) {
+	args := m.Called(productID, query)
+	return args.Get(0).(*user.StockHistory), args.Error(1)
+}
+
+func (m *MockInventoryUseCase) LowStock() ([]user.Product, error) {
+	args := m.Called()
+	return args.Get(0).([]user.Product), args.Error(1)
+}
+
+func TestRecordMovementHandler(t *testing.T) {
+	mockUseCase := new(MockInventoryUseCase)
+	handler := NewInventoryHandler(mockUseCase)
+
+	actor := &user.AdminRegister{Model: gorm.Model{ID: 1}, Username: "root"}
+	router := gin.Default()
+	router.POST("/products/:id/stock-movements", func(c *gin.Context) { c.Set(middleware.AdminKey, actor) }, handler.RecordMovementHandler)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sale := &user.StockMovementRequest{Type: user.MovementSale, Quantity: 8, Reason: "order 1"}
+	movement := &user.StockMovement{ID: 4, ProductID: 1, Type: user.MovementSale, Quantity: -8, Balance: 2, Reason: "order 1", Actor: "root", CreatedAt: createdAt}
+	product := &user.Product{Model: gorm.Model{ID: 1, CreatedAt: createdAt, UpdatedAt: createdAt}, ProductName: "Shoe", Quantity: 2, Price: money.Money{Amount: 1000, Currency: "USD"}, CategoryID: 1, LowStockThreshold: 3}
+	mockUseCase.On("RecordMovement", "root", uint(1), sale).Return(movement, product, nil)
+	oversell := &user.StockMovementRequest{Type: user.MovementSale, Quantity: 50, Reason: "order 2"}
+	mockUseCase.On("RecordMovement", "root", uint(1), oversell).Return((*user.StockMovement)(nil), (*user.Product)(nil), fmt.Errorf("%w: 2 in stock", repository.ErrInsufficientStock))
+	mockUseCase.On("RecordMovement", "root", uint(9), sale).Return((*user.StockMovement)(nil), (*user.Product)(nil), usecase.ErrProductNotFound)
+
+	req, _ := http.NewRequest("POST", "/products/1/stock-movements", bytes.NewBufferString(`{"type":"sale","quantity":8,"reason":"order 1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{
+		"movement":{"id":4,"type":"sale","quantity":-8,"balance":2,"reason":"order 1","actor":"root","created_at":"2024-01-01T00:00:00Z"},
+		"product":{"id":1,"product_name":"Shoe","description":"","quantity":2,"price":{"amount":"10.00","currency":"USD"},"category_id":1,"low_stock_threshold":3,"low_stock":true,"version":0,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/products/1/stock-movements", bytes.NewBufferString(`{"type":"sale","quantity":50,"reason":"order 2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error":"not enough stock: 2 in stock"}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/products/9/stock-movements", bytes.NewBufferString(`{"type":"sale","quantity":8,"reason":"order 1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Every movement needs a known type and a reason
+	req, _ = http.NewRequest("POST", "/products/1/stock-movements", bytes.NewBufferString(`{"type":"theft","quantity":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStockHistoryHandler(t *testing.T) {
+	mockUseCase := new(MockInventoryUseCase)
+	handler := NewInventoryHandler(mockUseCase)
+
+	router := gin.Default()
+	router.GET("/products/:id/stock-movements", handler.StockHistoryHandler)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := &user.StockHistory{
+		Product: &user.Product{Model: gorm.Model{ID: 1, CreatedAt: createdAt, UpdatedAt: createdAt}, ProductName: "Shoe", Quantity: 2, Price: money.Money{Amount: 1000, Currency: "USD"}, CategoryID: 1},
+		Movements: []user.StockMovement{
+			{ID: 4, ProductID: 1, Type: user.MovementSale, Quantity: -8, Balance: 2, Reason: "order 1", Actor: "root", CreatedAt: createdAt},
+		},
+		Total:         2,
+		LedgerBalance: 2,
+	}
+	mockUseCase.On("StockHistory", uint(1), &user.StockHistoryQuery{Limit: 1}).Run(func(args mock.Arguments) {
+		query := args.Get(1).(*user.StockHistoryQuery)
+		query.Page = 1
+	}).Return(history, nil)
+
+	req, _ := http.NewRequest("GET", "/products/1/stock-movements?limit=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"product":{"id":1,"product_name":"Shoe","description":"","quantity":2,"price":{"amount":"10.00","currency":"USD"},"category_id":1,"low_stock_threshold":0,"low_stock":false,"version":0,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"},
+		"ledger_balance":2,
+		"data":[{"id":4,"type":"sale","quantity":-8,"balance":2,"reason":"order 1","actor":"root","created_at":"2024-01-01T00:00:00Z"}],
+		"page":1,"limit":1,"total":2,"total_pages":2,
+		"links":{"next":"/products/1/stock-movements?limit=1&page=2","prev":null}
+	}`, w.Body.String())
+}
@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+)
+
+// errPatchMediaType is answered with 415, PATCH bodies have to be merge patches
+var errPatchMediaType = errors.New("PATCH expects Content-Type " + mergepatch.ContentType)
+
+// bindMergePatch decodes the request body into patch, plain application/json is
+// read as a merge patch too since that is what most clients send by default
+func bindMergePatch(c *gin.Context, patch any) error {
+	switch c.ContentType() {
+	case mergepatch.ContentType, gin.MIMEJSON:
+	default:
+		return errPatchMediaType
+	}
+	return mergepatch.Decode(c.Request.Body, patch)
+}
+
+// patchErrorStatus maps body errors of a PATCH request to 415 or 400
+func patchErrorStatus(err error) int {
+	if errors.Is(err, errPatchMediaType) {
+		return 415
+	}
+	return 400
+}
@@ -0,0 +1,163 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type MFAHandler struct {
+	mfaUseCase     usecase.MFAUseCase
+	sessionUseCase usecase.SessionUseCase
+}
+
+type MFAUseCases interface {
+	VerifyLoginHandler(c *gin.Context)
+	EnrollHandler(c *gin.Context)
+	ConfirmEnrollmentHandler(c *gin.Context)
+	RegenerateRecoveryCodesHandler(c *gin.Context)
+	DisableHandler(c *gin.Context)
+}
+
+// VerifyLoginHandler trades the challenge from a password login and a second factor for a session
+func (m *MFAHandler) VerifyLoginHandler(c *gin.Context) {
+	var request user.MFALoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "mfa_token and code are required"})
+		return
+	}
+
+	challenge, err := m.mfaUseCase.VerifyChallenge(request.MFAToken, request.Code, c.ClientIP())
+	if err != nil {
+		var locked *usecase.LockedError
+		if errors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
+		if errors.Is(err, usecase.ErrInvalidMFACode) || errors.Is(err, usecase.ErrInvalidMFAChallenge) {
+			c.JSON(401, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to verify two-factor code"})
+		return
+	}
+
+	tokens, err := m.sessionUseCase.StartSession(challenge.SubjectID, challenge.Account, challenge.Role, c.Request.UserAgent(), c.ClientIP(), true)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to start session"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "Success", "token": tokens})
+}
+
+func (m *MFAHandler) EnrollHandler(c *gin.Context) {
+	claims, ok := middleware.CurrentClaims(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "authentication required"})
+		return
+	}
+
+	enrollment, err := m.mfaUseCase.Enroll(claims.SubjectID, claims.Account, accountLabel(c))
+	if err != nil {
+		if errors.Is(err, usecase.ErrMFAAlreadyEnabled) {
+			c.JSON(409, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to start two-factor enrollment"})
+		return
+	}
+	c.JSON(201, gin.H{"message": "scan the URI with an authenticator app and confirm with a code", "enrollment": enrollment})
+}
+
+func (m *MFAHandler) ConfirmEnrollmentHandler(c *gin.Context) {
+	claims, request, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := m.mfaUseCase.ConfirmEnrollment(claims.SubjectID, claims.Account, request.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "two-factor authentication enabled, store the recovery codes safely", "recovery_codes": codes})
+}
+
+func (m *MFAHandler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	claims, request, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := m.mfaUseCase.RegenerateRecoveryCodes(claims.SubjectID, claims.Account, request.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "recovery codes regenerated, the old ones no longer work", "recovery_codes": codes})
+}
+
+func (m *MFAHandler) DisableHandler(c *gin.Context) {
+	claims, request, ok := bindMFACode(c)
+	if !ok {
+		return
+	}
+
+	if err := m.mfaUseCase.Disable(claims.SubjectID, claims.Account, request.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// bindMFACode reads the caller and the code of a request, answering itself on failure
+func bindMFACode(c *gin.Context) (*token.Claims, *user.MFACodeRequest, bool) {
+	claims, ok := middleware.CurrentClaims(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "authentication required"})
+		return nil, nil, false
+	}
+	var request user.MFACodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "code is required"})
+		return nil, nil, false
+	}
+	return claims, &request, true
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidMFACode):
+		c.JSON(401, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrMFAMandatory):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled),
+		errors.Is(err, usecase.ErrMFANotEnabled),
+		errors.Is(err, usecase.ErrMFANotPending):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		c.JSON(500, gin.H{"error": "two-factor request failed"})
+	}
+}
+
+// accountLabel names the account inside the authenticator app
+func accountLabel(c *gin.Context) string {
+	if admin, ok := middleware.CurrentAdmin(c); ok {
+		return admin.Username
+	}
+	if caller, ok := middleware.CurrentUser(c); ok {
+		return caller.UserName
+	}
+	return "account"
+}
+
+func NewMFAHandler(mfaUseCase usecase.MFAUseCase, sessionUseCase usecase.SessionUseCase) *MFAHandler {
+	return &MFAHandler{
+		mfaUseCase:     mfaUseCase,
+		sessionUseCase: sessionUseCase,
+	}
+}
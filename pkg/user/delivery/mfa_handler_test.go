@@ -0,0 +1,173 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockMFAUseCase is a mock implementation of the MFAUseCase interface
+type MockMFAUseCase struct {
+	mock.Mock
+}
+
+func (m *MockMFAUseCase) Enroll(subjectID uint, account, label string) (*user.MFAEnrollment, error) {
+	args := m.Called(subjectID, account, label)
+	return args.Get(0).(*user.MFAEnrollment), args.Error(1)
+}
+
+func (m *MockMFAUseCase) ConfirmEnrollment(subjectID uint, account, code string) ([]string, error) {
+	args := m.Called(subjectID, account, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAUseCase) Disable(subjectID uint, account, code string) error {
+	args := m.Called(subjectID, account, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAUseCase) RegenerateRecoveryCodes(subjectID uint, account, code string) ([]string, error) {
+	args := m.Called(subjectID, account, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAUseCase) Enabled(subjectID uint, account string) (bool, error) {
+	args := m.Called(subjectID, account)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAUseCase) Required(account string) bool {
+	args := m.Called(account)
+	return args.Bool(0)
+}
+
+func (m *MockMFAUseCase) StartChallenge(pending *user.MFAChallenge) (string, error) {
+	args := m.Called(pending)
+	pending.ExpiresAt = time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAUseCase) VerifyChallenge(challengeToken, code, clientIP string) (*user.MFAChallenge, error) {
+	args := m.Called(challengeToken, code, clientIP)
+	return args.Get(0).(*user.MFAChallenge), args.Error(1)
+}
+
+func TestLoginAdminHandlerRequiresSecondFactor(t *testing.T) {
+	mockUseCase := new(MockAdminUseCase)
+	mockMFAUseCase := new(MockMFAUseCase)
+	handler := NewAdminHandler(mockUseCase, new(MockSessionUseCase), mockMFAUseCase)
+
+	router := gin.Default()
+	router.POST("/adminlogin", handler.LoginAdminHandler)
+
+	adminLogin := &user.AdminLogin{Username: "admin1", Password: "password123"}
+	admin := &user.AdminRegister{Model: gorm.Model{ID: 7}, Username: "admin1", Role: "super-admin"}
+	mockUseCase.On("Login", adminLogin, mock.Anything).Return(admin, nil)
+	mockMFAUseCase.On("Enabled", uint(7), user.AccountAdmin).Return(true, nil)
+	mockMFAUseCase.On("StartChallenge", mock.MatchedBy(func(pending *user.MFAChallenge) bool {
+		return pending.SubjectID == 7 && pending.Account == user.AccountAdmin && pending.Username == "admin1" && pending.Role == "super-admin"
+	})).Return("challenge-token", nil)
+
+	body, _ := json.Marshal(adminLogin)
+	req, _ := http.NewRequest("POST", "/adminlogin", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// No session is started until the second step succeeds
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Status":"Two-factor authentication required","mfa_required":true,"mfa_token":"challenge-token","expires_at":"2024-01-01T00:05:00Z"}`, w.Body.String())
+	mockMFAUseCase.AssertExpectations(t)
+}
+
+func TestVerifyLoginHandler(t *testing.T) {
+	mockMFAUseCase := new(MockMFAUseCase)
+	mockSessionUseCase := new(MockSessionUseCase)
+	handler := NewMFAHandler(mockMFAUseCase, mockSessionUseCase)
+
+	router := gin.Default()
+	router.POST("/login/mfa", handler.VerifyLoginHandler)
+
+	challenge := &user.MFAChallenge{SubjectID: 7, Account: user.AccountAdmin, Role: "super-admin"}
+	mockMFAUseCase.On("VerifyChallenge", "challenge-token", "123456", mock.Anything).Return(challenge, nil)
+	mockMFAUseCase.On("VerifyChallenge", "challenge-token", "000000", mock.Anything).Return((*user.MFAChallenge)(nil), usecase.ErrInvalidMFACode)
+	mockMFAUseCase.On("VerifyChallenge", "challenge-token", "111111", mock.Anything).Return((*user.MFAChallenge)(nil), &usecase.LockedError{RetryAfter: time.Minute})
+	mockSessionUseCase.On("StartSession", uint(7), user.AccountAdmin, "super-admin", mock.Anything, mock.Anything, true).Return(testTokenPair, nil)
+
+	send := func(code string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(user.MFALoginRequest{MFAToken: "challenge-token", Code: code})
+		req, _ := http.NewRequest("POST", "/login/mfa", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("123456")
+	expectedResponse, _ := json.Marshal(gin.H{"message": "Success", "token": testTokenPair})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(expectedResponse), w.Body.String())
+
+	w = send("000000")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"invalid two-factor code"}`, w.Body.String())
+
+	w = send("111111")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	mockSessionUseCase.AssertNumberOfCalls(t, "StartSession", 1)
+}
+
+func TestEnrollmentHandlers(t *testing.T) {
+	mockMFAUseCase := new(MockMFAUseCase)
+	handler := NewMFAHandler(mockMFAUseCase, new(MockSessionUseCase))
+
+	// Simulate the authentication middleware storing the caller
+	claims := &token.Claims{SubjectID: 3, Account: user.AccountUser, Role: "customer", SessionID: 11}
+	caller := &user.UserRegister{Model: gorm.Model{ID: 3}, UserName: "ratheeshgk"}
+	withCaller := func(c *gin.Context) {
+		c.Set(middleware.ClaimsKey, claims)
+		c.Set(middleware.UserKey, caller)
+	}
+
+	router := gin.Default()
+	router.POST("/mfa/enroll", withCaller, handler.EnrollHandler)
+	router.POST("/mfa/enroll/confirm", withCaller, handler.ConfirmEnrollmentHandler)
+	router.POST("/mfa/disable", withCaller, handler.DisableHandler)
+
+	enrollment := &user.MFAEnrollment{Secret: "SECRET", URI: "otpauth://totp/newCrudClean:ratheeshgk?secret=SECRET"}
+	mockMFAUseCase.On("Enroll", uint(3), user.AccountUser, "ratheeshgk").Return(enrollment, nil)
+	mockMFAUseCase.On("ConfirmEnrollment", uint(3), user.AccountUser, "123456").Return([]string{"abcde-fghij"}, nil)
+	mockMFAUseCase.On("Disable", uint(3), user.AccountUser, "123456").Return(usecase.ErrMFAMandatory)
+
+	req, _ := http.NewRequest("POST", "/mfa/enroll", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"message":"scan the URI with an authenticator app and confirm with a code","enrollment":{"secret":"SECRET","otpauth_uri":"otpauth://totp/newCrudClean:ratheeshgk?secret=SECRET"}}`, w.Body.String())
+
+	body, _ := json.Marshal(user.MFACodeRequest{Code: "123456"})
+	req, _ = http.NewRequest("POST", "/mfa/enroll/confirm", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"two-factor authentication enabled, store the recovery codes safely","recovery_codes":["abcde-fghij"]}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/mfa/disable", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
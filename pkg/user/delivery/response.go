@@ -0,0 +1,272 @@
+package delivery
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+)
+
+// Response types are what the API sends back, entities are never marshalled
+// directly so password hashes and other internal columns stay on the server
+
+type UserResponse struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	Phone         string    `json:"phone"`
+	EmailVerified bool      `json:"email_verified"`
+	Version       uint      `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AdminResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ProductResponse struct {
+	ID                uint        `json:"id"`
+	ProductName       string      `json:"product_name"`
+	Description       string      `json:"description"`
+	Quantity          int         `json:"quantity"`
+	Price             money.Money `json:"price"`
+	CategoryID        uint        `json:"category_id"`
+	LowStockThreshold int         `json:"low_stock_threshold"`
+	LowStock          bool        `json:"low_stock"`
+	Version           uint        `json:"version"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// ProductSearchResponse is a product search hit, highlights are HTML escaped text
+// with matches wrapped in <mark> tags
+type ProductSearchResponse struct {
+	ProductResponse
+	Rank      float64         `json:"rank"`
+	Highlight SearchHighlight `json:"highlight"`
+}
+
+type SearchHighlight struct {
+	ProductName string `json:"product_name"`
+	Description string `json:"description"`
+}
+
+type CategoryResponse struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	ParentID    *uint              `json:"parent_id"`
+	Children    []CategoryResponse `json:"children,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+type StockMovementResponse struct {
+	ID        uint      `json:"id"`
+	Type      string    `json:"type"`
+	Quantity  int       `json:"quantity"`
+	Balance   int       `json:"balance"`
+	Reason    string    `json:"reason"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StockHistoryResponse is a page of a product's ledger, ledger_balance differing
+// from the product's quantity means stock changed outside the ledger
+type StockHistoryResponse struct {
+	Product       ProductResponse `json:"product"`
+	LedgerBalance int64           `json:"ledger_balance"`
+	PageResponse
+}
+
+// TrashedProductResponse is a soft deleted product as the trash lists it
+type TrashedProductResponse struct {
+	ProductResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashedUserResponse is a soft deleted user as the trash lists it
+type TrashedUserResponse struct {
+	UserResponse
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type InviteResponse struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy uint      `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PageResponse wraps one page of a list with its paging metadata. Pages reached
+// by cursor carry no page number or totals, follow links.next_cursor instead.
+type PageResponse struct {
+	Data       interface{} `json:"data"`
+	Page       int         `json:"page,omitempty"`
+	Limit      int         `json:"limit"`
+	Total      *int64      `json:"total,omitempty"`
+	TotalPages *int        `json:"total_pages,omitempty"`
+	Links      PageLinks   `json:"links"`
+}
+
+type PageLinks struct {
+	Next       *string `json:"next"`
+	Prev       *string `json:"prev"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func NewPageResponse(current *url.URL, data interface{}, page, limit int, byCursor bool, info *pagination.Page) PageResponse {
+	response := PageResponse{Data: data, Limit: limit, Links: PageLinks{NextCursor: info.NextCursor}}
+	if byCursor {
+		response.Links.Next = pagination.CursorLink(current, limit, info.NextCursor)
+		return response
+	}
+
+	totalPages := pagination.TotalPages(info.Total, limit)
+	response.Page, response.Total, response.TotalPages = page, &info.Total, &totalPages
+	response.Links.Next, response.Links.Prev = pagination.Links(current, page, limit, info.Total)
+	return response
+}
+
+func NewUserResponse(u *user.UserRegister) UserResponse {
+	return UserResponse{
+		ID:            u.ID,
+		Username:      u.UserName,
+		Name:          u.Name,
+		Email:         u.Email,
+		Phone:         u.Phone,
+		EmailVerified: u.EmailVerified(),
+		Version:       u.Version,
+		CreatedAt:     u.CreatedAt,
+	}
+}
+
+func NewUserListResponse(users []user.UserRegister) []UserResponse {
+	responses := make([]UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, NewUserResponse(&users[i]))
+	}
+	return responses
+}
+
+func NewAdminResponse(a *user.AdminRegister) AdminResponse {
+	return AdminResponse{
+		ID:        a.ID,
+		Username:  a.Username,
+		Email:     a.Email,
+		Role:      a.Role,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+func NewProductResponse(p *user.Product) ProductResponse {
+	return ProductResponse{
+		ID:                p.ID,
+		ProductName:       p.ProductName,
+		Description:       p.Description,
+		Quantity:          p.Quantity,
+		Price:             p.Price,
+		CategoryID:        p.CategoryID,
+		LowStockThreshold: p.LowStockThreshold,
+		LowStock:          p.LowStock(),
+		Version:           p.Version,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+}
+
+func NewProductListResponse(products []user.Product) []ProductResponse {
+	responses := make([]ProductResponse, 0, len(products))
+	for i := range products {
+		responses = append(responses, NewProductResponse(&products[i]))
+	}
+	return responses
+}
+
+func NewProductSearchListResponse(hits []user.ProductSearchHit) []ProductSearchResponse {
+	responses := make([]ProductSearchResponse, 0, len(hits))
+	for i := range hits {
+		responses = append(responses, ProductSearchResponse{
+			ProductResponse: NewProductResponse(&hits[i].Product),
+			Rank:            hits[i].Rank,
+			Highlight: SearchHighlight{
+				ProductName: hits[i].NameHighlight,
+				Description: hits[i].DescriptionHighlight,
+			},
+		})
+	}
+	return responses
+}
+
+func NewCategoryResponse(category *user.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:          category.ID,
+		Name:        category.Name,
+		Description: category.Description,
+		ParentID:    category.ParentID,
+		CreatedAt:   category.CreatedAt,
+		UpdatedAt:   category.UpdatedAt,
+	}
+}
+
+func NewCategoryListResponse(categories []user.Category) []CategoryResponse {
+	responses := make([]CategoryResponse, 0, len(categories))
+	for i := range categories {
+		responses = append(responses, NewCategoryResponse(&categories[i]))
+	}
+	return responses
+}
+
+func NewStockMovementResponse(movement *user.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		ID:        movement.ID,
+		Type:      movement.Type,
+		Quantity:  movement.Quantity,
+		Balance:   movement.Balance,
+		Reason:    movement.Reason,
+		Actor:     movement.Actor,
+		CreatedAt: movement.CreatedAt,
+	}
+}
+
+func NewStockMovementListResponse(movements []user.StockMovement) []StockMovementResponse {
+	responses := make([]StockMovementResponse, 0, len(movements))
+	for i := range movements {
+		responses = append(responses, NewStockMovementResponse(&movements[i]))
+	}
+	return responses
+}
+
+func NewTrashedProductListResponse(products []user.Product) []TrashedProductResponse {
+	responses := make([]TrashedProductResponse, 0, len(products))
+	for i := range products {
+		responses = append(responses, TrashedProductResponse{ProductResponse: NewProductResponse(&products[i]), DeletedAt: products[i].DeletedAt.Time})
+	}
+	return responses
+}
+
+func NewTrashedUserListResponse(users []user.UserRegister) []TrashedUserResponse {
+	responses := make([]TrashedUserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, TrashedUserResponse{UserResponse: NewUserResponse(&users[i]), DeletedAt: users[i].DeletedAt.Time})
+	}
+	return responses
+}
+
+func NewInviteResponse(i *user.AdminInvite) InviteResponse {
+	return InviteResponse{
+		ID:        i.ID,
+		Email:     i.Email,
+		Role:      i.Role,
+		InvitedBy: i.InvitedBy,
+		ExpiresAt: i.ExpiresAt,
+	}
+}
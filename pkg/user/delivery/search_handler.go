@@ -0,0 +1,45 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type SearchHandler struct {
+	searchUseCase usecase.SearchUseCase
+}
+
+type SearchUseCases interface {
+	SearchProductsHandler(c *gin.Context)
+}
+
+// SearchProductsHandler returns products ranked by relevance to ?q=, most relevant first
+func (s *SearchHandler) SearchProductsHandler(c *gin.Context) {
+	var query user.ProductSearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+
+	hits, total, err := s.searchUseCase.SearchProducts(&query)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptySearch) {
+			c.JSON(400, gin.H{"error": "q must contain at least one word"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to search products"})
+		return
+	}
+	c.JSON(200, NewPageResponse(c.Request.URL, NewProductSearchListResponse(hits), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+func NewSearchHandler(searchUseCase usecase.SearchUseCase) *SearchHandler {
+	return &SearchHandler{
+		searchUseCase: searchUseCase,
+	}
+}
@@ -0,0 +1,68 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockSearchUseCase is a mock implementation of the SearchUseCase interface
+type MockSearchUseCase struct {
+	mock.Mock
+}
+
+func (m *MockSearchUseCase) SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]user.ProductSearchHit), args.Get(1).(int64), args.Error(2)
+}
+
+func TestSearchProductsHandler(t *testing.T) {
+	mockUseCase := new(MockSearchUseCase)
+	handler := NewSearchHandler(mockUseCase)
+
+	router := gin.Default()
+	router.GET("/products/search", handler.SearchProductsHandler)
+
+	hits := []user.ProductSearchHit{{
+		Product:              user.Product{Model: gorm.Model{ID: 1}, ProductName: "Trail Running Shoe", Description: "Grippy sole", Quantity: 3, Price: money.Money{Amount: 5950, Currency: "USD"}},
+		Rank:                 0.75,
+		NameHighlight:        "Trail <mark>Running</mark> <mark>Shoe</mark>",
+		DescriptionHighlight: "Grippy sole",
+	}}
+	mockUseCase.On("SearchProducts", &user.ProductSearchQuery{Query: "running sho"}).Run(func(args mock.Arguments) {
+		query := args.Get(0).(*user.ProductSearchQuery)
+		query.Page, query.Limit = 1, 20
+	}).Return(hits, int64(1), nil)
+	mockUseCase.On("SearchProducts", &user.ProductSearchQuery{Query: "&&"}).Return([]user.ProductSearchHit(nil), int64(0), fmt.Errorf("failed to search products: %w", repository.ErrEmptySearch))
+
+	req, _ := http.NewRequest("GET", "/products/search?q=running+sho", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data":[{
+			"id":1,"product_name":"Trail Running Shoe","description":"Grippy sole","quantity":3,"price":{"amount":"59.50","currency":"USD"},"category_id":0,"low_stock_threshold":0,"low_stock":false,"version":0,
+			"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z",
+			"rank":0.75,
+			"highlight":{"product_name":"Trail <mark>Running</mark> <mark>Shoe</mark>","description":"Grippy sole"}
+		}],
+		"page":1,"limit":20,"total":1,"total_pages":1,
+		"links":{"next":null,"prev":null}
+	}`, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/products/search?q=%26%26", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"q must contain at least one word"}`, w.Body.String())
+}
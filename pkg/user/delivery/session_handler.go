@@ -0,0 +1,73 @@
+package delivery
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type SessionHandler struct {
+	sessionUseCase usecase.SessionUseCase
+}
+
+type SessionUseCases interface {
+	RefreshHandler(c *gin.Context)
+	LogoutHandler(c *gin.Context)
+	LogoutEverywhereHandler(c *gin.Context)
+}
+
+func (s *SessionHandler) RefreshHandler(c *gin.Context) {
+	var request user.RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	tokens, err := s.sessionUseCase.Refresh(request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidRefreshToken) ||
+			errors.Is(err, usecase.ErrRefreshTokenReused) ||
+			errors.Is(err, usecase.ErrSessionRevoked) {
+			c.JSON(401, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to refresh session"})
+		return
+	}
+	c.JSON(200, gin.H{"token": tokens})
+}
+
+func (s *SessionHandler) LogoutHandler(c *gin.Context) {
+	claims, ok := middleware.CurrentClaims(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "authentication required"})
+		return
+	}
+
+	if err := s.sessionUseCase.Logout(claims.SessionID); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "logged out successfully"})
+}
+
+func (s *SessionHandler) LogoutEverywhereHandler(c *gin.Context) {
+	claims, ok := middleware.CurrentClaims(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "authentication required"})
+		return
+	}
+
+	if err := s.sessionUseCase.LogoutEverywhere(claims.SubjectID, claims.Account); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "logged out from all sessions"})
+}
+
+func NewSessionHandler(sessionUseCase usecase.SessionUseCase) *SessionHandler {
+	return &SessionHandler{sessionUseCase: sessionUseCase}
+}
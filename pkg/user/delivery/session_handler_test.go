@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testTokenPair is what the mocked session use case hands out on login
+var testTokenPair = &user.TokenPair{
+	AccessToken:           "access-token",
+	TokenType:             "Bearer",
+	ExpiresAt:             time.Date(2024, 1, 1, 0, 15, 0, 0, time.UTC),
+	RefreshToken:          "refresh-token",
+	RefreshTokenExpiresAt: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+}
+
+// MockSessionUseCase is a mock implementation of the SessionUseCase interface
+type MockSessionUseCase struct {
+	mock.Mock
+}
+
+func (m *MockSessionUseCase) StartSession(subjectID uint, account, role, userAgent, clientIP string, mfaVerified bool) (*user.TokenPair, error) {
+	args := m.Called(subjectID, account, role, userAgent, clientIP, mfaVerified)
+	return args.Get(0).(*user.TokenPair), args.Error(1)
+}
+
+func (m *MockSessionUseCase) Refresh(refreshToken string) (*user.TokenPair, error) {
+	args := m.Called(refreshToken)
+	return args.Get(0).(*user.TokenPair), args.Error(1)
+}
+
+func (m *MockSessionUseCase) ValidateSession(sessionID uint) (*user.Session, error) {
+	args := m.Called(sessionID)
+	return args.Get(0).(*user.Session), args.Error(1)
+}
+
+func (m *MockSessionUseCase) Logout(sessionID uint) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionUseCase) LogoutEverywhere(subjectID uint, account string) error {
+	args := m.Called(subjectID, account)
+	return args.Error(0)
+}
+
+func (m *MockSessionUseCase) LogoutOthers(subjectID uint, account string, keepSessionID uint) error {
+	args := m.Called(subjectID, account, keepSessionID)
+	return args.Error(0)
+}
+
+func TestRefreshHandler(t *testing.T) {
+	mockSessionUseCase := new(MockSessionUseCase)
+	handler := NewSessionHandler(mockSessionUseCase)
+
+	r := gin.Default()
+	r.POST("/refresh", handler.RefreshHandler)
+
+	mockSessionUseCase.On("Refresh", "good").Return(testTokenPair, nil)
+	mockSessionUseCase.On("Refresh", "reused").Return((*user.TokenPair)(nil), usecase.ErrRefreshTokenReused)
+
+	body, _ := json.Marshal(user.RefreshRequest{RefreshToken: "good"})
+	req, _ := http.NewRequest("POST", "/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	expectedResponse, _ := json.Marshal(gin.H{"token": testTokenPair})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, string(expectedResponse), w.Body.String())
+
+	// A replayed refresh token is rejected
+	body, _ = json.Marshal(user.RefreshRequest{RefreshToken: "reused"})
+	req, _ = http.NewRequest("POST", "/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"refresh token reuse detected, session revoked"}`, w.Body.String())
+}
+
+func TestLogoutHandlers(t *testing.T) {
+	mockSessionUseCase := new(MockSessionUseCase)
+	handler := NewSessionHandler(mockSessionUseCase)
+
+	// Simulate the authentication middleware storing the token claims
+	claims := &token.Claims{SubjectID: 3, Account: user.AccountUser, Role: "customer", SessionID: 11}
+	withClaims := func(c *gin.Context) { c.Set(middleware.ClaimsKey, claims) }
+
+	r := gin.Default()
+	r.POST("/logout", withClaims, handler.LogoutHandler)
+	r.POST("/logout/all", withClaims, handler.LogoutEverywhereHandler)
+
+	mockSessionUseCase.On("Logout", uint(11)).Return(nil)
+	mockSessionUseCase.On("LogoutEverywhere", uint(3), user.AccountUser).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/logout", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"logged out successfully"}`, w.Body.String())
+
+	req, _ = http.NewRequest("POST", "/logout/all", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"logged out from all sessions"}`, w.Body.String())
+
+	mockSessionUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,141 @@
+package delivery
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+)
+
+type TrashHandler struct {
+	trashUseCase usecase.TrashUseCase
+}
+
+type TrashUseCases interface {
+	ListDeletedProductsHandler(c *gin.Context)
+	RestoreProductHandler(c *gin.Context)
+	PurgeProductHandler(c *gin.Context)
+	ListDeletedUsersHandler(c *gin.Context)
+	RestoreUserHandler(c *gin.Context)
+	PurgeUserHandler(c *gin.Context)
+}
+
+// ListDeletedProductsHandler pages through the soft deleted products
+func (h *TrashHandler) ListDeletedProductsHandler(c *gin.Context) {
+	var query user.TrashQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	products, total, err := h.trashUseCase.ListProducts(&query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list deleted products"})
+		return
+	}
+	c.JSON(200, NewPageResponse(c.Request.URL, NewTrashedProductListResponse(products), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+func (h *TrashHandler) RestoreProductHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	product, err := h.trashUseCase.RestoreProduct(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrNotInTrash):
+			c.JSON(404, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrRestoreCategoryGone):
+			c.JSON(409, gin.H{"error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"error": "failed to restore product"})
+		}
+		return
+	}
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(200, NewProductResponse(product))
+}
+
+// PurgeProductHandler deletes a trashed product and its stock ledger for good
+func (h *TrashHandler) PurgeProductHandler(c *gin.Context) {
+	id, ok := productID(c)
+	if !ok {
+		return
+	}
+	if err := h.trashUseCase.PurgeProduct(id); err != nil {
+		if errors.Is(err, usecase.ErrNotInTrash) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to purge product"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "product purged"})
+}
+
+// ListDeletedUsersHandler pages through the soft deleted users
+func (h *TrashHandler) ListDeletedUsersHandler(c *gin.Context) {
+	var query user.TrashQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(400, gin.H{"error": "invalid query parameters"})
+		return
+	}
+	users, total, err := h.trashUseCase.ListUsers(&query)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to list deleted users"})
+		return
+	}
+	c.JSON(200, NewPageResponse(c.Request.URL, NewTrashedUserListResponse(users), query.Page, query.Limit, false, &pagination.Page{Total: total}))
+}
+
+func (h *TrashHandler) RestoreUserHandler(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	restored, err := h.trashUseCase.RestoreUser(id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrNotInTrash) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to restore user"})
+		return
+	}
+	c.Header("ETag", versionETag(restored.Version))
+	c.JSON(200, NewUserResponse(restored))
+}
+
+func (h *TrashHandler) PurgeUserHandler(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	if err := h.trashUseCase.PurgeUser(id); err != nil {
+		if errors.Is(err, usecase.ErrNotInTrash) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to purge user"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "user purged"})
+}
+
+func userID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func NewTrashHandler(trashUseCase usecase.TrashUseCase) *TrashHandler {
+	return &TrashHandler{
+		trashUseCase: trashUseCase,
+	}
+}
@@ -0,0 +1,163 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/money"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockTrashUseCase is a mock implementation of the TrashUseCase interface
+type MockTrashUseCase struct {
+	mock.Mock
+}
+
+func (m *MockTrashUseCase) ListProducts(query *user.TrashQuery) ([]user.Product, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]user.Product), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTrashUseCase) ListUsers(query *user.TrashQuery) ([]user.UserRegister, int64, error) {
+	args := m.Called(query)
+	return args.Get(0).([]user.UserRegister), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockTrashUseCase) RestoreProduct(id uint) (*user.Product, error) {
+	args := m.Called(id)
+	return args.Get(0).(*user.Product), args.Error(1)
+}
+
+func (m *MockTrashUseCase) RestoreUser(id uint) (*user.UserRegister, error) {
+	args := m.Called(id)
+	return args.Get(0).(*user.UserRegister), args.Error(1)
+}
+
+func (m *MockTrashUseCase) PurgeProduct(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTrashUseCase) PurgeUser(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTrashUseCase) PurgeExpired() (*user.PurgeResult, error) {
+	args := m.Called()
+	return args.Get(0).(*user.PurgeResult), args.Error(1)
+}
+
+func TestProductTrashHandlers(t *testing.T) {
+	mockTrashUseCase := new(MockTrashUseCase)
+	handler := NewTrashHandler(mockTrashUseCase)
+
+	r := gin.Default()
+	r.GET("/trash/products", handler.ListDeletedProductsHandler)
+	r.POST("/trash/products/:id/restore", handler.RestoreProductHandler)
+	r.DELETE("/trash/products/:id", handler.PurgeProductHandler)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deletedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	boot := user.Product{
+		Model:       gorm.Model{ID: 2, CreatedAt: created, UpdatedAt: created, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}},
+		ProductName: "Boot",
+		Price:       money.Money{Amount: 4500, Currency: "USD"},
+		CategoryID:  1,
+		Version:     1,
+	}
+
+	mockTrashUseCase.On("ListProducts", &user.TrashQuery{}).Run(func(args mock.Arguments) {
+		query := args.Get(0).(*user.TrashQuery)
+		query.Page, query.Limit = 1, 20
+	}).Return([]user.Product{boot}, int64(1), nil)
+
+	req, _ := http.NewRequest("GET", "/trash/products", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"data":[{"id":2,"product_name":"Boot","description":"","quantity":0,"price":{"amount":"45.00","currency":"USD"},"category_id":1,"low_stock_threshold":0,"low_stock":false,"version":1,
+			"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z","deleted_at":"2024-02-01T00:00:00Z"}],
+		"page":1,"limit":20,"total":1,"total_pages":1,
+		"links":{"next":null,"prev":null}
+	}`, w.Body.String())
+
+	// A restored product comes back with a fresh version
+	restored := boot
+	restored.DeletedAt, restored.Version = gorm.DeletedAt{}, 2
+	mockTrashUseCase.On("RestoreProduct", uint(2)).Return(&restored, nil)
+	mockTrashUseCase.On("RestoreProduct", uint(3)).Return((*user.Product)(nil), fmt.Errorf("%w: category 4", usecase.ErrRestoreCategoryGone))
+	mockTrashUseCase.On("RestoreProduct", uint(9)).Return((*user.Product)(nil), usecase.ErrNotInTrash)
+
+	req, _ = http.NewRequest("POST", "/trash/products/2/restore", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	req, _ = http.NewRequest("POST", "/trash/products/3/restore", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("POST", "/trash/products/9/restore", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Only trashed products can be purged
+	mockTrashUseCase.On("PurgeProduct", uint(2)).Return(nil)
+	mockTrashUseCase.On("PurgeProduct", uint(1)).Return(usecase.ErrNotInTrash)
+
+	req, _ = http.NewRequest("DELETE", "/trash/products/2", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"product purged"}`, w.Body.String())
+
+	req, _ = http.NewRequest("DELETE", "/trash/products/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockTrashUseCase.AssertExpectations(t)
+}
+
+func TestUserTrashHandlers(t *testing.T) {
+	mockTrashUseCase := new(MockTrashUseCase)
+	handler := NewTrashHandler(mockTrashUseCase)
+
+	r := gin.Default()
+	r.POST("/trash/users/:id/restore", handler.RestoreUserHandler)
+	r.DELETE("/trash/users/:id", handler.PurgeUserHandler)
+
+	mockTrashUseCase.On("RestoreUser", uint(5)).Return(&user.UserRegister{Model: gorm.Model{ID: 5}, UserName: "ratheeshgk", Version: 3}, nil)
+	mockTrashUseCase.On("PurgeUser", uint(6)).Return(usecase.ErrNotInTrash)
+
+	req, _ := http.NewRequest("POST", "/trash/users/5/restore", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":5,"username":"ratheeshgk","name":"","email":"","phone":"","email_verified":false,"version":3,"created_at":"0001-01-01T00:00:00Z"}`, w.Body.String())
+
+	req, _ = http.NewRequest("DELETE", "/trash/users/6", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/trash/users/abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid user ID"}`, w.Body.String())
+}
@@ -1,22 +1,36 @@
 package delivery
 
 import (
+	"errors"
+	"math"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/middleware"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
 	"github.com/ratheeshkumar25/pkg/user/usecase"
 )
 
 type UserHandler struct {
-	userUseCase usecase.UserUseCase
+	userUseCase    usecase.UserUseCase
+	sessionUseCase usecase.SessionUseCase
+	mfaUseCase     usecase.MFAUseCase
 }
 
 type UserUseCases interface {
 	RegisterUserHandler(c *gin.Context)
 	LoginUserHandler(c *gin.Context)
+	GetCurrentUserHandler(c *gin.Context)
 	UpdateUserHandler(c *gin.Context)
+	PatchUserHandler(c *gin.Context)
+	ChangePasswordHandler(c *gin.Context)
 	DeleteUserHandler(c *gin.Context)
+	ForgotPasswordHandler(c *gin.Context)
+	ResetPasswordHandler(c *gin.Context)
+	VerifyEmailHandler(c *gin.Context)
+	ResendVerificationHandler(c *gin.Context)
 }
 
 func (u *UserHandler) RegisterUserHandler(c *gin.Context) {
@@ -28,6 +42,11 @@ func (u *UserHandler) RegisterUserHandler(c *gin.Context) {
 
 	err := u.userUseCase.RegisterUser(&user)
 	if err != nil {
+		var policy *usecase.PasswordPolicyError
+		if errors.As(err, &policy) {
+			c.JSON(400, gin.H{"Error": err.Error(), "violations": policy.Violations})
+			return
+		}
 		c.JSON(500, gin.H{"Error": "user already exists"})
 		return
 	}
@@ -41,45 +60,191 @@ func (u *UserHandler) LoginUserHandler(c *gin.Context) {
 		return
 	}
 
-	user, err := u.userUseCase.Login(&userLogin)
+	loggedIn, err := u.userUseCase.Login(&userLogin, c.ClientIP())
 	if err != nil {
+		var locked *usecase.LockedError
+		if errors.As(err, &locked) {
+			respondLocked(c, locked)
+			return
+		}
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			c.JSON(403, gin.H{"Error": err.Error(), "email_verified": false})
+			return
+		}
 		c.JSON(500, gin.H{"Error": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"Status": "Success", "user": gin.H{
-		"username": user.UserName,
-		"name":     user.Name,
-		"email":    user.Email,
-		"phone": user.Phone,
-	}})
+	//**Users who opted into 2FA get a challenge instead of a session
+	mfaEnabled, err := u.mfaUseCase.Enabled(loggedIn.ID, user.AccountUser)
+	if err != nil {
+		c.JSON(500, gin.H{"Error": "failed to start session"})
+		return
+	}
+	if mfaEnabled {
+		challenge := &user.MFAChallenge{
+			SubjectID: loggedIn.ID,
+			Account:   user.AccountUser,
+			Username:  loggedIn.UserName,
+			Role:      loggedIn.Role,
+			UserAgent: c.Request.UserAgent(),
+			ClientIP:  c.ClientIP(),
+		}
+		mfaToken, err := u.mfaUseCase.StartChallenge(challenge)
+		if err != nil {
+			c.JSON(500, gin.H{"Error": "failed to start two-factor challenge"})
+			return
+		}
+		c.JSON(200, gin.H{"Status": "Two-factor authentication required", "mfa_required": true, "mfa_token": mfaToken, "expires_at": challenge.ExpiresAt})
+		return
+	}
+
+	tokens, err := u.sessionUseCase.StartSession(loggedIn.ID, user.AccountUser, loggedIn.Role, c.Request.UserAgent(), c.ClientIP(), false)
+	if err != nil {
+		c.JSON(500, gin.H{"Error": "failed to start session"})
+		return
+	}
+
+	c.JSON(200, gin.H{"Status": "Success", "user": NewUserResponse(loggedIn), "token": tokens})
+}
+
+// GetCurrentUserHandler returns the caller's profile with its version as the ETag,
+// the value to send back in If-Match when updating it
+func (u *UserHandler) GetCurrentUserHandler(c *gin.Context) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(401, gin.H{"Error": "authentication required"})
+		return
+	}
+
+	found, err := u.userUseCase.GetUserDetail(caller.ID)
+	if err != nil {
+		c.JSON(500, gin.H{"Error": "failed to load user"})
+		return
+	}
+	c.Header("ETag", versionETag(found.Version))
+	c.JSON(200, gin.H{"user": NewUserResponse(found)})
 }
 
 func (u *UserHandler) UpdateUserHandler(c *gin.Context) {
-	var existinguser user.UserRegister
-	if err := c.BindJSON(&existinguser); err != nil {
+	ifMatch, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"Error": err.Error()})
+		return
+	}
+
+	var profile user.UserProfileUpdate
+	if err := c.ShouldBindJSON(&profile); err != nil {
 		c.JSON(400, gin.H{"Error": "binding error"})
 		return
 	}
+	if ifMatch != 0 {
+		profile.Version = ifMatch
+	}
+
+	//**Users may only update their own account
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(401, gin.H{"Error": "authentication required"})
+		return
+	}
+	if profile.ID != 0 && profile.ID != caller.ID {
+		c.JSON(403, gin.H{"Error": "you can only update your own account"})
+		return
+	}
 
-	err := u.userUseCase.UpdateUser(&existinguser)
+	err = u.userUseCase.UpdateUser(caller.ID, &profile)
 	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			c.JSON(conflictStatus(c), gin.H{"Error": err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"Error": err.Error()})
 		return
 	}
 
 	// Fetch the user details
-	user, err := u.userUseCase.GetUserDetail(existinguser.ID)
+	updated, err := u.userUseCase.GetUserDetail(caller.ID)
 	if err != nil {
 		c.JSON(500, gin.H{"Error": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"Status": "User details updated successfully", "user": gin.H{
-		"username": user.UserName,
-		"name":     user.Name,
-		"email":    user.Email,
-		"phone":    user.Phone,
-	}})
+	c.Header("ETag", versionETag(updated.Version))
+	c.JSON(200, gin.H{"Status": "User details updated successfully", "user": NewUserResponse(updated)})
+}
+
+// PatchUserHandler applies a JSON merge patch to the caller's profile, RequireSelf guards the :id
+func (u *UserHandler) PatchUserHandler(c *gin.Context) {
+	caller, ok := middleware.CurrentUser(c)
+	if !ok {
+		c.JSON(401, gin.H{"Error": "authentication required"})
+		return
+	}
+	ifMatch, err := ifMatchVersion(c)
+	if err != nil {
+		c.JSON(400, gin.H{"Error": err.Error()})
+		return
+	}
+
+	var patch user.UserPatch
+	if err := bindMergePatch(c, &patch); err != nil {
+		c.JSON(patchErrorStatus(err), gin.H{"Error": err.Error()})
+		return
+	}
+	if ifMatch != 0 {
+		patch.Version = ifMatch
+	}
+
+	updated, err := u.userUseCase.PatchUser(caller.ID, &patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, mergepatch.ErrInvalidPatch):
+			c.JSON(400, gin.H{"Error": err.Error()})
+		case errors.Is(err, repository.ErrVersionConflict):
+			c.JSON(conflictStatus(c), gin.H{"Error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"Error": err.Error()})
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(updated.Version))
+	c.JSON(200, gin.H{"Status": "User details updated successfully", "user": NewUserResponse(updated)})
+}
+
+func (u *UserHandler) ChangePasswordHandler(c *gin.Context) {
+	var request user.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"Error": "current_password and new_password are required"})
+		return
+	}
+
+	caller, ok := middleware.CurrentUser(c)
+	claims, hasClaims := middleware.CurrentClaims(c)
+	if !ok || !hasClaims {
+		c.JSON(401, gin.H{"Error": "authentication required"})
+		return
+	}
+
+	err := u.userUseCase.ChangePassword(caller.ID, claims.SessionID, request.CurrentPassword, request.NewPassword, c.ClientIP())
+	if err != nil {
+		var locked *usecase.LockedError
+		var policy *usecase.PasswordPolicyError
+		switch {
+		case errors.As(err, &locked):
+			respondLocked(c, locked)
+		case errors.Is(err, usecase.ErrWrongPassword):
+			c.JSON(403, gin.H{"Error": err.Error()})
+		case errors.As(err, &policy):
+			c.JSON(400, gin.H{"Error": err.Error(), "violations": policy.Violations})
+		case errors.Is(err, usecase.ErrSamePassword):
+			c.JSON(400, gin.H{"Error": err.Error()})
+		default:
+			c.JSON(500, gin.H{"Error": "failed to change password"})
+		}
+		return
+	}
+	c.JSON(200, gin.H{"Status": "Password changed, other sessions have been signed out"})
 }
 
 func (u *UserHandler) DeleteUserHandler(c *gin.Context) {
@@ -98,8 +263,87 @@ func (u *UserHandler) DeleteUserHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"Status": "User details deleted successfully"})
 }
 
-func NewUserHandler(userUseCase usecase.UserUseCase) *UserHandler {
+func (u *UserHandler) ForgotPasswordHandler(c *gin.Context) {
+	var request user.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"Error": "a valid email is required"})
+		return
+	}
+
+	if err := u.userUseCase.ForgotPassword(request.Email); err != nil {
+		c.JSON(500, gin.H{"Error": "failed to send reset instructions"})
+		return
+	}
+	// Same answer whether or not the email exists
+	c.JSON(202, gin.H{"Status": "If the email is registered, reset instructions have been sent"})
+}
+
+func (u *UserHandler) ResetPasswordHandler(c *gin.Context) {
+	var request user.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"Error": "token and password are required"})
+		return
+	}
+
+	if err := u.userUseCase.ResetPassword(request.Token, request.Password); err != nil {
+		if errors.Is(err, usecase.ErrInvalidResetToken) {
+			c.JSON(400, gin.H{"Error": err.Error()})
+			return
+		}
+		var policy *usecase.PasswordPolicyError
+		if errors.As(err, &policy) {
+			c.JSON(400, gin.H{"Error": err.Error(), "violations": policy.Violations})
+			return
+		}
+		c.JSON(500, gin.H{"Error": "failed to reset password"})
+		return
+	}
+	c.JSON(200, gin.H{"Status": "Password has been reset, please log in again"})
+}
+
+func (u *UserHandler) VerifyEmailHandler(c *gin.Context) {
+	verificationToken := c.Query("token")
+	if verificationToken == "" {
+		c.JSON(400, gin.H{"Error": "token is required"})
+		return
+	}
+
+	if err := u.userUseCase.VerifyEmail(verificationToken); err != nil {
+		if errors.Is(err, usecase.ErrInvalidVerificationToken) {
+			c.JSON(400, gin.H{"Error": err.Error()})
+			return
+		}
+		c.JSON(500, gin.H{"Error": "failed to verify email"})
+		return
+	}
+	c.JSON(200, gin.H{"Status": "Email verified successfully"})
+}
+
+func (u *UserHandler) ResendVerificationHandler(c *gin.Context) {
+	var request user.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(400, gin.H{"Error": "a valid email is required"})
+		return
+	}
+
+	if err := u.userUseCase.ResendVerification(request.Email); err != nil {
+		c.JSON(500, gin.H{"Error": "failed to send verification email"})
+		return
+	}
+	c.JSON(202, gin.H{"Status": "If the email is registered and unverified, a new verification email has been sent"})
+}
+
+// respondLocked answers a throttled login with 429 and a Retry-After hint
+func respondLocked(c *gin.Context, locked *usecase.LockedError) {
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(429, gin.H{"Error": locked.Error(), "retry_after": seconds})
+}
+
+func NewUserHandler(userUseCase usecase.UserUseCase, sessionUseCase usecase.SessionUseCase, mfaUseCase usecase.MFAUseCase) *UserHandler {
 	return &UserHandler{
-		userUseCase: userUseCase,
+		userUseCase:    userUseCase,
+		sessionUseCase: sessionUseCase,
+		mfaUseCase:     mfaUseCase,
 	}
 }
@@ -3,15 +3,22 @@ package delivery
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/ratheeshkumar25/pkg/user/usecase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/middleware"
+	"github.com/ratheeshkumar25/pkg/token"
 )
 
 // MockUserUseCase is a mock implementation of the UserUseCase interface
@@ -24,13 +31,23 @@ func (m *MockUserUseCase) RegisterUser(user *user.UserRegister) error {
 	return args.Error(0)
 }
 
-func (m *MockUserUseCase) Login(login *user.UserLogin) (*user.UserRegister, error) {
-	args := m.Called(login)
+func (m *MockUserUseCase) Login(login *user.UserLogin, clientIP string) (*user.UserRegister, error) {
+	args := m.Called(login, clientIP)
 	return args.Get(0).(*user.UserRegister), args.Error(1)
 }
 
-func (m *MockUserUseCase) UpdateUser(user *user.UserRegister) error {
-	args := m.Called(user)
+func (m *MockUserUseCase) UpdateUser(id uint, profile *user.UserProfileUpdate) error {
+	args := m.Called(id, profile)
+	return args.Error(0)
+}
+
+func (m *MockUserUseCase) PatchUser(id uint, patch *user.UserPatch) (*user.UserRegister, error) {
+	args := m.Called(id, patch)
+	return args.Get(0).(*user.UserRegister), args.Error(1)
+}
+
+func (m *MockUserUseCase) ChangePassword(id, keepSessionID uint, currentPassword, newPassword, clientIP string) error {
+	args := m.Called(id, keepSessionID, currentPassword, newPassword, clientIP)
 	return args.Error(0)
 }
 
@@ -44,6 +61,26 @@ func (m *MockUserUseCase) RemoveUser(id uint) error {
 	return args.Error(0)
 }
 
+func (m *MockUserUseCase) ForgotPassword(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockUserUseCase) ResetPassword(resetToken, password string) error {
+	args := m.Called(resetToken, password)
+	return args.Error(0)
+}
+
+func (m *MockUserUseCase) VerifyEmail(verificationToken string) error {
+	args := m.Called(verificationToken)
+	return args.Error(0)
+}
+
+func (m *MockUserUseCase) ResendVerification(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
 func (m *MockUserUseCase) GetUserDetail(id uint) (*user.UserRegister, error) {
     args := m.Called(id)
     return args.Get(0).(*user.UserRegister), args.Error(1)
@@ -51,7 +88,7 @@ func (m *MockUserUseCase) GetUserDetail(id uint) (*user.UserRegister, error) {
 
 func TestRegisterUserHandler(t *testing.T){
 	mockUseCase := new(MockUserUseCase)
-    handler := NewUserHandler(mockUseCase)
+    handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
     r := gin.Default()
     r.POST("/signup", handler.RegisterUserHandler)
@@ -88,7 +125,9 @@ func TestRegisterUserHandler(t *testing.T){
 
 func TestLoginUserHandler(t *testing.T) {
     mockUseCase := new(MockUserUseCase)
-    handler := NewUserHandler(mockUseCase)
+    mockSessionUseCase := new(MockSessionUseCase)
+    mockMFAUseCase := new(MockMFAUseCase)
+    handler := NewUserHandler(mockUseCase, mockSessionUseCase, mockMFAUseCase)
 
     r := gin.Default()
     r.POST("/login", handler.LoginUserHandler)
@@ -100,16 +139,22 @@ func TestLoginUserHandler(t *testing.T) {
     }
 
     // Mock user data returned by the login use case
+    verifiedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
     user := user.UserRegister{
+        Model:    gorm.Model{ID: 3},
         UserName: "ratheeshgk",
         Name:     "Ratheesh G",
         Email:    "ratheeshgk@live1.com",
         Phone:    "9961429911",
+        Role:     "customer",
+        VerifiedAt: &verifiedAt,
         Password: "$2a$10$IgtDVCIs6Tx07/0IQ3A5f.UYWOvbw4CEGyukAnESd8rgI8Bc",
     }
 
     // Setup the mock to return the expected user data
-    mockUseCase.On("Login", &userLogin).Return(&user, nil)
+    mockUseCase.On("Login", &userLogin, mock.Anything).Return(&user, nil)
+    mockMFAUseCase.On("Enabled", uint(3), "user").Return(false, nil)
+    mockSessionUseCase.On("StartSession", uint(3), "user", "customer", mock.Anything, mock.Anything, false).Return(testTokenPair, nil)
 
     // Create the request
     jsonValue, _ := json.Marshal(userLogin) // Serialize `userLogin` for the request body
@@ -124,16 +169,29 @@ func TestLoginUserHandler(t *testing.T) {
     assert.Equal(t, http.StatusOK, w.Code)
 
     // Define the expected JSON response for 
-    expectedResponse := `{"Status":"Success","user":{"username":"ratheeshgk","name":"Ratheesh G","email":"ratheeshgk@live1.com","phone":"9961429911"}}`
-    assert.JSONEq(t, expectedResponse, w.Body.String())
+    expectedResponse, _ := json.Marshal(gin.H{"Status": "Success", "user": gin.H{
+        "id":             3,
+        "username":       "ratheeshgk",
+        "name":           "Ratheesh G",
+        "email":          "ratheeshgk@live1.com",
+        "phone":          "9961429911",
+        "email_verified": true,
+        "version":        0,
+        "created_at":     "0001-01-01T00:00:00Z",
+    }, "token": testTokenPair})
+    assert.JSONEq(t, string(expectedResponse), w.Body.String())
+    assert.NotContains(t, w.Body.String(), "$2a$")
+    mockSessionUseCase.AssertExpectations(t)
 }
 
 func TestUpdateUserHandler(t *testing.T) {
 	mockUseCase := new(MockUserUseCase)
-	handler := NewUserHandler(mockUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
+	// Simulate the authentication middleware loading the caller
+	caller := &user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk"}
 	r := gin.Default()
-	r.PUT("/userupdate", handler.UpdateUserHandler)
+	r.PUT("/userupdate", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.UpdateUserHandler)
 
 	existingUser := user.UserRegister{
 		Model:    gorm.Model{ID: 1},
@@ -153,8 +211,13 @@ func TestUpdateUserHandler(t *testing.T) {
 		Password: "rathee@1234",
 	}
 
-	// Mock the UpdateUser method
-	mockUseCase.On("UpdateUser", &existingUser).Return(nil)
+	// Only the profile fields reach the use case, the password in the body is ignored
+	mockUseCase.On("UpdateUser", uint(1), &user.UserProfileUpdate{
+		ID:    1,
+		Name:  "Ratheesh G",
+		Email: "ratheeshgk@live1.com",
+		Phone: "9961429911",
+	}).Return(nil)
 	// Mock the GetUserDetail method (corrected from GetUserDetails to GetUserDetail)
 	mockUseCase.On("GetUserDetail", uint(1)).Return(&updateUser, nil)
 
@@ -170,13 +233,142 @@ func TestUpdateUserHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// Expected response (corrected the JSON format and content)
-	expectedResponse := `{"Status":"User details updated successfully","user":{"username":"ratheeshgku","name":"Ratheesh GK","email":"ratheeshgk@live12.com","phone":"9961429921"}}`
+	expectedResponse := `{"Status":"User details updated successfully","user":{"id":1,"username":"ratheeshgku","name":"Ratheesh GK","email":"ratheeshgk@live12.com","phone":"9961429921","email_verified":false,"version":0,"created_at":"0001-01-01T00:00:00Z"}}`
 	assert.JSONEq(t, expectedResponse, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "password")
+
+	// Updating somebody else's account is forbidden
+	otherUser := existingUser
+	otherUser.ID = 2
+	jsonValue, _ = json.Marshal(otherUser)
+	req, _ = http.NewRequest("PUT", "/userupdate", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockUseCase.AssertNumberOfCalls(t, "UpdateUser", 1)
+}
+
+func TestUpdateUserHandlerVersionConflict(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk"}
+	r := gin.Default()
+	r.PUT("/userupdate", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.UpdateUserHandler)
+
+	// If-Match carries the version the client read and wins over the body
+	mockUseCase.On("UpdateUser", uint(1), &user.UserProfileUpdate{Name: "Ratheesh GK", Version: 4}).Return(nil)
+	mockUseCase.On("GetUserDetail", uint(1)).Return(&user.UserRegister{Model: gorm.Model{ID: 1}, Name: "Ratheesh GK", Version: 5}, nil)
+	mockUseCase.On("UpdateUser", uint(1), &user.UserProfileUpdate{Name: "Ratheesh GK", Version: 2}).Return(fmt.Errorf("updating the user: %w", repository.ErrVersionConflict))
+
+	req, _ := http.NewRequest("PUT", "/userupdate", bytes.NewBufferString(`{"name":"Ratheesh GK","version":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"4"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+
+	// A stale version in the body is a conflict
+	req, _ = http.NewRequest("PUT", "/userupdate", bytes.NewBufferString(`{"name":"Ratheesh GK","version":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"Error":"updating the user: record was modified by someone else, reload it and try again"}`, w.Body.String())
+
+	// The same stale version sent as If-Match fails the precondition
+	req, _ = http.NewRequest("PUT", "/userupdate", bytes.NewBufferString(`{"name":"Ratheesh GK"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestGetCurrentUserHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk"}
+	r := gin.Default()
+	setCaller := func(c *gin.Context) { c.Set(middleware.UserKey, caller) }
+	r.GET("/users/me", setCaller, handler.GetCurrentUserHandler)
+	r.PUT("/userupdate", setCaller, handler.UpdateUserHandler)
+
+	mockUseCase.On("GetUserDetail", uint(1)).Return(&user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk", Name: "Ratheesh G", Email: "ratheeshgk@live1.com", Version: 7}, nil)
+
+	req, _ := http.NewRequest("GET", "/users/me", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"7"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"user":{"id":1,"username":"ratheeshgk","name":"Ratheesh G","email":"ratheeshgk@live1.com","phone":"","email_verified":false,"version":7,"created_at":"0001-01-01T00:00:00Z"}}`, w.Body.String())
+
+	// The ETag sent back as If-Match becomes the version the update expects
+	mockUseCase.On("UpdateUser", uint(1), &user.UserProfileUpdate{Name: "Ratheesh GK", Version: 7}).Return(nil)
+	req, _ = http.NewRequest("PUT", "/userupdate", bytes.NewBufferString(`{"name":"Ratheesh GK"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestPatchUserHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	caller := &user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk"}
+	r := gin.Default()
+	r.PATCH("/users/:id", func(c *gin.Context) { c.Set(middleware.UserKey, caller) }, handler.PatchUserHandler)
+
+	// Only the phone was sent, name and email stay as they are
+	mockUseCase.On("PatchUser", uint(1), &user.UserPatch{Phone: mergepatch.Value("9961429921"), Version: 2}).
+		Return(&user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk", Name: "Ratheesh G", Email: "ratheeshgk@live1.com", Phone: "9961429921", Version: 3}, nil)
+	mockUseCase.On("PatchUser", uint(1), &user.UserPatch{Name: mergepatch.Null[string]()}).
+		Return((*user.UserRegister)(nil), fmt.Errorf("%w: name cannot be empty", mergepatch.ErrInvalidPatch))
+
+	req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(`{"phone":"9961429921","version":2}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"Status":"User details updated successfully","user":{"id":1,"username":"ratheeshgk","name":"Ratheesh G","email":"ratheeshgk@live1.com","phone":"9961429921","email_verified":false,"version":3,"created_at":"0001-01-01T00:00:00Z"}}`, w.Body.String())
+
+	req, _ = http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(`{"name":null}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"Error":"invalid merge patch: name cannot be empty"}`, w.Body.String())
+
+	// The password is not part of a profile patch
+	req, _ = http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(`{"password":"secret"}`))
+	req.Header.Set("Content-Type", mergepatch.ContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockUseCase.AssertNumberOfCalls(t, "PatchUser", 2)
 }
 
 func TestDeleteUserHandler(t *testing.T) {
 	mockUseCase := new(MockUserUseCase)
-	handler := NewUserHandler(mockUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
 
 	r := gin.Default()
 	r.DELETE("/userdelete/:id", handler.DeleteUserHandler)
@@ -197,3 +389,175 @@ func TestDeleteUserHandler(t *testing.T) {
 
 
 	
+
+func TestForgotPasswordHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	r := gin.Default()
+	r.POST("/password/forgot", handler.ForgotPasswordHandler)
+
+	mockUseCase.On("ForgotPassword", "ratheeshgk@live1.com").Return(nil)
+
+	jsonValue, _ := json.Marshal(user.ForgotPasswordRequest{Email: "ratheeshgk@live1.com"})
+	req, _ := http.NewRequest("POST", "/password/forgot", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"Status":"If the email is registered, reset instructions have been sent"}`, w.Body.String())
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	r := gin.Default()
+	r.POST("/password/reset", handler.ResetPasswordHandler)
+
+	mockUseCase.On("ResetPassword", "good-token", "n3w-passw0rd").Return(nil)
+	mockUseCase.On("ResetPassword", "used-token", "n3w-passw0rd").Return(usecase.ErrInvalidResetToken)
+	mockUseCase.On("ResetPassword", "good-token", "short").Return(&usecase.PasswordPolicyError{Violations: []string{"at least 10 characters"}})
+
+	jsonValue, _ := json.Marshal(user.ResetPasswordRequest{Token: "good-token", Password: "n3w-passw0rd"})
+	req, _ := http.NewRequest("POST", "/password/reset", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Status":"Password has been reset, please log in again"}`, w.Body.String())
+
+	// A token can only be used once
+	jsonValue, _ = json.Marshal(user.ResetPasswordRequest{Token: "used-token", Password: "n3w-passw0rd"})
+	req, _ = http.NewRequest("POST", "/password/reset", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"Error":"reset token is invalid or expired"}`, w.Body.String())
+
+	// Length is left to the configured policy, like on signup and password change
+	jsonValue, _ = json.Marshal(user.ResetPasswordRequest{Token: "good-token", Password: "short"})
+	req, _ = http.NewRequest("POST", "/password/reset", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"Error":"password does not meet the policy: at least 10 characters","violations":["at least 10 characters"]}`, w.Body.String())
+}
+
+func TestLoginUserHandlerUnverifiedEmail(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	r := gin.Default()
+	r.POST("/login", handler.LoginUserHandler)
+
+	userLogin := user.UserLogin{UserName: "ratheeshgk", Password: "rathee@123"}
+	mockUseCase.On("Login", &userLogin, mock.Anything).Return((*user.UserRegister)(nil), usecase.ErrEmailNotVerified)
+
+	jsonValue, _ := json.Marshal(userLogin)
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"Error":"email address is not verified","email_verified":false}`, w.Body.String())
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	r := gin.Default()
+	r.GET("/verify-email", handler.VerifyEmailHandler)
+
+	mockUseCase.On("VerifyEmail", "good-token").Return(nil)
+	mockUseCase.On("VerifyEmail", "expired-token").Return(usecase.ErrInvalidVerificationToken)
+
+	req, _ := http.NewRequest("GET", "/verify-email?token=good-token", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Status":"Email verified successfully"}`, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/verify-email?token=expired-token", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"Error":"verification token is invalid or expired"}`, w.Body.String())
+
+	req, _ = http.NewRequest("GET", "/verify-email", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestLoginUserHandlerLockedOut(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	r := gin.Default()
+	r.POST("/login", handler.LoginUserHandler)
+
+	userLogin := user.UserLogin{UserName: "ratheeshgk", Password: "wrong"}
+	mockUseCase.On("Login", &userLogin, mock.Anything).Return((*user.UserRegister)(nil), &usecase.LockedError{RetryAfter: 90 * time.Second})
+
+	jsonValue, _ := json.Marshal(userLogin)
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "90", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"Error":"too many failed login attempts, try again later","retry_after":90}`, w.Body.String())
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	mockUseCase := new(MockUserUseCase)
+	handler := NewUserHandler(mockUseCase, new(MockSessionUseCase), new(MockMFAUseCase))
+
+	// Simulate the authentication middleware loading the caller and its session
+	caller := &user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheeshgk"}
+	claims := &token.Claims{SubjectID: 1, Account: "user", Role: "customer", SessionID: 11}
+	r := gin.Default()
+	r.PUT("/users/me/password", func(c *gin.Context) {
+		c.Set(middleware.UserKey, caller)
+		c.Set(middleware.ClaimsKey, claims)
+	}, handler.ChangePasswordHandler)
+
+	mockUseCase.On("ChangePassword", uint(1), uint(11), "rathee@123", "Rathee@1234", mock.Anything).Return(nil)
+	mockUseCase.On("ChangePassword", uint(1), uint(11), "wrong", "Rathee@1234", mock.Anything).Return(usecase.ErrWrongPassword)
+	mockUseCase.On("ChangePassword", uint(1), uint(11), "rathee@123", "short", mock.Anything).Return(&usecase.PasswordPolicyError{Violations: []string{"at least 8 characters"}})
+
+	send := func(current, next string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(user.ChangePasswordRequest{CurrentPassword: current, NewPassword: next})
+		req, _ := http.NewRequest("PUT", "/users/me/password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("rathee@123", "Rathee@1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"Status":"Password changed, other sessions have been signed out"}`, w.Body.String())
+
+	w = send("wrong", "Rathee@1234")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"Error":"current password is incorrect"}`, w.Body.String())
+
+	w = send("rathee@123", "short")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"Error":"password does not meet the policy: at least 8 characters","violations":["at least 8 characters"]}`, w.Body.String())
+}
@@ -0,0 +1,38 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Purposes of single-use action tokens
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// ActionToken is an expiring single-use token mailed to a user, only its hash is stored
+type ActionToken struct {
+	gorm.Model
+	SubjectID uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"type:varchar(32);not null"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	// Email is the address the token was mailed to
+	Email string `gorm:"type:varchar(255)"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
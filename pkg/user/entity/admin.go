@@ -1,24 +1,45 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"github.com/ratheeshkumar25/pkg/money"
+	"gorm.io/gorm"
+)
+
+// AccountAdmin marks tokens and sessions that belong to the admins table
+const AccountAdmin = "admin"
 
 type AdminRegister struct {
-	Username string `json:"username"`
+	gorm.Model
+	Username string `json:"username" gorm:"uniqueIndex"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Role     string `json:"role" gorm:"type:varchar(32);not null"`
+}
+
+type AdminRoleUpdate struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type AdminLogin struct {
-	Username string `json:"username"`
+	Username string `json:"username" gorm:"uniqueIndex"`
 	Password string `json:"password"`
 }
 
 type Product struct {
 	gorm.Model
-	ProductName  string  `gorm:"type:varchar(255);not null" json:"product_name"`
-	Description  string  `gorm:"type:text" json:"description"`
-	Quantity     int     `gorm:"type:int" json:"quantity"`
-	Price        float32 `gorm:"type:decimal(10,2)" json:"price"`
-	CategoryID   uint    `gorm:"not null" json:"category_id"`
+	ProductName string      `gorm:"type:varchar(255);not null" json:"product_name"`
+	Description string      `gorm:"type:text" json:"description"`
+	Quantity    int         `gorm:"type:int" json:"quantity"`
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	CategoryID  uint        `gorm:"not null;index" json:"category_id"`
+	Category    *Category   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	// LowStockThreshold flags the product once stock drops to it, 0 disables the alert
+	LowStockThreshold int `gorm:"not null;default:0" json:"low_stock_threshold" binding:"min=0"`
+	// Version is bumped on every update, writers send back the version they read
+	Version uint `gorm:"not null;default:1" json:"version"`
 }
 
+// LowStock reports whether the stock is at or below the product's threshold
+func (p *Product) LowStock() bool {
+	return p.LowStockThreshold > 0 && p.Quantity <= p.LowStockThreshold
+}
@@ -0,0 +1,19 @@
+package user
+
+import "gorm.io/gorm"
+
+// Category groups products, ParentID nests it under another category
+type Category struct {
+	gorm.Model
+	Name        string    `gorm:"type:varchar(255);not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	ParentID    *uint     `gorm:"index" json:"parent_id"`
+	Parent      *Category `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+}
+
+// CategoryRequest creates or updates a category, a nil ParentID makes it top level
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+}
@@ -0,0 +1,49 @@
+package user
+
+import "time"
+
+// Kinds of stock movement, receipts and returns add stock, sales remove it and
+// adjustments correct it either way
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementAdjustment = "adjustment"
+	MovementReturn     = "return"
+)
+
+// StockMovement is one immutable ledger entry, Product.Quantity always equals the
+// Balance of the product's latest movement
+type StockMovement struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ProductID uint      `gorm:"not null;index:idx_stock_movements_product,priority:1" json:"product_id"`
+	Product   *Product  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	Type      string    `gorm:"type:varchar(16);not null" json:"type"`
+	Quantity  int       `gorm:"not null" json:"quantity"`
+	Balance   int       `gorm:"not null" json:"balance"`
+	Reason    string    `gorm:"type:text;not null" json:"reason"`
+	Actor     string    `gorm:"type:varchar(255);not null" json:"actor"`
+	CreatedAt time.Time `gorm:"index:idx_stock_movements_product,priority:2" json:"created_at"`
+}
+
+// StockMovementRequest records stock changing hands. Quantity is the number of
+// units for receipts, sales and returns and a signed correction for adjustments.
+type StockMovementRequest struct {
+	Type     string `json:"type" binding:"required,oneof=receipt sale adjustment return"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// StockHistoryQuery pages through a product's ledger, newest first
+type StockHistoryQuery struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+// StockHistory is a page of a product's ledger. LedgerBalance sums the whole ledger
+// and matches Product.Quantity while the two are in sync.
+type StockHistory struct {
+	Product       *Product
+	Movements     []StockMovement
+	Total         int64
+	LedgerBalance int64
+}
@@ -0,0 +1,31 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminInvite lets an existing admin bring in a new one, only the token hash is stored
+type AdminInvite struct {
+	gorm.Model
+	Email     string     `gorm:"type:varchar(255);not null" json:"email"`
+	Role      string     `gorm:"type:varchar(32);not null" json:"role"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	InvitedBy uint       `gorm:"not null" json:"invited_by"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type AdminInviteRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// AdminSignup is the registration payload, it must carry an invite token
+type AdminSignup struct {
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	InviteToken string `json:"invite_token"`
+}
@@ -0,0 +1,35 @@
+package user
+
+import "time"
+
+// UserListQuery holds the paging, sorting and filters of the admin user list.
+// Cursor, when set, replaces Page with keyset pagination.
+type UserListQuery struct {
+	Name        string     `form:"name"`
+	EmailDomain string     `form:"email_domain"`
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02" time_utc:"1"`
+	CreatedTo   *time.Time `form:"created_to" time_format:"2006-01-02" time_utc:"1"`
+	Sort        string     `form:"sort"`
+	Cursor      string     `form:"cursor"`
+	Page        int        `form:"page" binding:"omitempty,min=1"`
+	Limit       int        `form:"limit" binding:"omitempty,min=1"`
+}
+
+// ProductListQuery holds the paging, sorting and filters of the product list.
+// Price bounds are decimals in Currency, which they require.
+type ProductListQuery struct {
+	Name       string  `form:"name"`
+	CategoryID *uint   `form:"category_id"`
+	Currency   string  `form:"currency"`
+	MinPrice   *string `form:"min_price"`
+	MaxPrice   *string `form:"max_price"`
+	InStock    *bool   `form:"in_stock"`
+	Sort       string  `form:"sort"`
+	Cursor     string  `form:"cursor"`
+	Page       int     `form:"page" binding:"omitempty,min=1"`
+	Limit      int     `form:"limit" binding:"omitempty,min=1"`
+
+	// MinAmount and MaxAmount are the price bounds in minor units, set by the use case
+	MinAmount *int64 `form:"-"`
+	MaxAmount *int64 `form:"-"`
+}
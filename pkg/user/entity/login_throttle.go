@@ -0,0 +1,16 @@
+package user
+
+import "time"
+
+// LoginThrottle counts failed logins for one account or client IP
+type LoginThrottle struct {
+	ThrottleKey   string    `gorm:"type:varchar(255);primaryKey"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+type UnlockRequest struct {
+	Account  string `json:"account" binding:"required,oneof=user admin"`
+	Username string `json:"username" binding:"required"`
+}
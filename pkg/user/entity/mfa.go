@@ -0,0 +1,61 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MFAFactor is the TOTP authenticator of a user or admin, it only counts once confirmed
+type MFAFactor struct {
+	gorm.Model
+	SubjectID    uint       `gorm:"not null;uniqueIndex:idx_mfa_factors_subject" json:"subject_id"`
+	Account      string     `gorm:"type:varchar(16);not null;uniqueIndex:idx_mfa_factors_subject" json:"account"`
+	Secret       string     `gorm:"type:varchar(64);not null" json:"-"`
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+}
+
+// Enabled reports whether the factor finished enrollment
+func (f *MFAFactor) Enabled() bool {
+	return f.ConfirmedAt != nil
+}
+
+// RecoveryCode is a one-time fallback for a lost authenticator, only its hash is stored
+type RecoveryCode struct {
+	gorm.Model
+	FactorID uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"type:varchar(64);not null;index"`
+	UsedAt   *time.Time
+}
+
+// MFAChallenge is handed out after a correct password and traded for a session
+// once the second factor checks out
+type MFAChallenge struct {
+	gorm.Model
+	SubjectID uint      `gorm:"not null"`
+	Account   string    `gorm:"type:varchar(16);not null"`
+	Username  string    `gorm:"type:varchar(255);not null"`
+	Role      string    `gorm:"type:varchar(32);not null"`
+	UserAgent string    `gorm:"type:varchar(255)"`
+	ClientIP  string    `gorm:"type:varchar(64)"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+// MFAEnrollment is shown once so the authenticator app can be set up
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
@@ -0,0 +1,35 @@
+package user
+
+import (
+	"encoding/json"
+
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+)
+
+// ProductPatch is a merge patch of a product, members left out keep their value
+type ProductPatch struct {
+	ProductName       mergepatch.Field[string]     `json:"product_name"`
+	Description       mergepatch.Field[string]     `json:"description"`
+	Price             mergepatch.Field[PricePatch] `json:"price"`
+	CategoryID        mergepatch.Field[uint]       `json:"category_id"`
+	LowStockThreshold mergepatch.Field[int]        `json:"low_stock_threshold"`
+	// Quantity is accepted only to explain that stock moves through the ledger
+	Quantity mergepatch.Field[json.RawMessage] `json:"quantity"`
+	// Version is the version the client read, 0 skips the check
+	Version uint `json:"version"`
+}
+
+// PricePatch merges into the current price, the amount is read in the patched currency
+type PricePatch struct {
+	Amount   mergepatch.Field[json.Number] `json:"amount"`
+	Currency mergepatch.Field[string]      `json:"currency"`
+}
+
+// UserPatch is a merge patch of the caller's profile, members left out keep their value
+type UserPatch struct {
+	Name  mergepatch.Field[string] `json:"name"`
+	Email mergepatch.Field[string] `json:"email"`
+	Phone mergepatch.Field[string] `json:"phone"`
+	// Version is the version the client read, 0 skips the check
+	Version uint `json:"version"`
+}
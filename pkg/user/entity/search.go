@@ -0,0 +1,17 @@
+package user
+
+// ProductSearchQuery is a full-text product search, the last word matches as a prefix
+type ProductSearchQuery struct {
+	Query string `form:"q"`
+	Page  int    `form:"page" binding:"omitempty,min=1"`
+	Limit int    `form:"limit" binding:"omitempty,min=1"`
+}
+
+// ProductSearchHit is a matching product with its relevance and highlighted text.
+// Highlights wrap the matched words in <mark> tags.
+type ProductSearchHit struct {
+	Product              Product
+	Rank                 float64
+	NameHighlight        string
+	DescriptionHighlight string
+}
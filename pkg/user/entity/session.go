@@ -0,0 +1,43 @@
+package user
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is one login of a user or admin, it lives as long as its refresh tokens
+type Session struct {
+	gorm.Model
+	SubjectID uint       `gorm:"not null;index:idx_sessions_subject" json:"subject_id"`
+	Account   string     `gorm:"type:varchar(16);not null;default:'user';index:idx_sessions_subject" json:"account"`
+	Role      string     `gorm:"type:varchar(32);not null" json:"role"`
+	UserAgent string     `gorm:"type:varchar(255)" json:"user_agent"`
+	ClientIP  string     `gorm:"type:varchar(64)" json:"client_ip"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	// MFAVerified is set when the login passed a second factor
+	MFAVerified bool `gorm:"not null;default:false" json:"mfa_verified"`
+}
+
+// RefreshToken is a single-use token of a session, only its hash is stored
+type RefreshToken struct {
+	gorm.Model
+	SessionID uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+// TokenPair is returned to clients after a login or refresh
+type TokenPair struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,13 @@
+package user
+
+// TrashQuery pages through soft deleted records, most recently deleted first
+type TrashQuery struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1"`
+}
+
+// PurgeResult counts the rows a purge removed for good
+type PurgeResult struct {
+	Products int64 `json:"products"`
+	Users    int64 `json:"users"`
+}
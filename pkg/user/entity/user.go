@@ -1,17 +1,48 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountUser marks tokens and sessions that belong to the users table
+const AccountUser = "user"
 
 type UserRegister struct {
 	gorm.Model
-	UserName string `json:"username" gorm:"not null;unique"`
-	Name     string `json:"name" gorm:"not null; unique"`
-	Email    string `json:"email" gorm:"not null;unique"`
-	Phone    string `json:"phone" gorm:"not null;unique"`
-	Password string `json:"password" gorm:"not null;unique"`
+	UserName   string     `json:"username" gorm:"not null;unique"`
+	Name       string     `json:"name" gorm:"not null; unique"`
+	Email      string     `json:"email" gorm:"not null;unique"`
+	Phone      string     `json:"phone" gorm:"not null;unique"`
+	Password   string     `json:"password" gorm:"not null;unique"`
+	Role       string     `json:"-" gorm:"type:varchar(32);not null;default:'customer'"`
+	VerifiedAt *time.Time `json:"-"`
+	// Version is bumped on every profile update, writers send back the version they read
+	Version uint `json:"version" gorm:"not null;default:1"`
+}
+
+// EmailVerified reports whether the user confirmed their email address
+func (u *UserRegister) EmailVerified() bool {
+	return u.VerifiedAt != nil
 }
 
 type UserLogin struct {
 	UserName string `json:"username"`
 	Password string `json:"password"`
 }
+
+// UserProfileUpdate carries the profile fields a user may change, empty fields are kept
+type UserProfileUpdate struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Phone string `json:"phone"`
+	// Version is the profile version the client read, 0 skips the check
+	Version uint `json:"version"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
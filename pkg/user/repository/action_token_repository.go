@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+// ErrActionTokenUsed is returned when a token was already consumed
+var ErrActionTokenUsed = errors.New("token already used")
+
+type ActionTokenRepository interface {
+	CreateActionToken(actionToken *user.ActionToken) error
+	FindActionToken(tokenHash, purpose string) (*user.ActionToken, error)
+	ConsumeActionToken(id uint) error
+}
+
+type ActionTokenDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (a *ActionTokenDataBaseInteraction) CreateActionToken(actionToken *user.ActionToken) error {
+	if err := a.DB.Create(actionToken).Error; err != nil {
+		return fmt.Errorf("creating token: %w", err)
+	}
+	return nil
+}
+
+func (a *ActionTokenDataBaseInteraction) FindActionToken(tokenHash, purpose string) (*user.ActionToken, error) {
+	var actionToken user.ActionToken
+	if err := a.DB.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&actionToken).Error; err != nil {
+		return nil, fmt.Errorf("finding token: %w", err)
+	}
+	return &actionToken, nil
+}
+
+func (a *ActionTokenDataBaseInteraction) ConsumeActionToken(id uint) error {
+	result := a.DB.Model(&user.ActionToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("consuming token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrActionTokenUsed
+	}
+	return nil
+}
+
+func NewActionTokenRepository(db *gorm.DB) ActionTokenRepository {
+	return &ActionTokenDataBaseInteraction{
+		DB: db,
+	}
+}
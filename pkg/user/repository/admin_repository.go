@@ -1,21 +1,37 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
+	"github.com/ratheeshkumar25/pkg/pagination"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrInviteUsed is returned when an invite was redeemed concurrently
+	ErrInviteUsed = errors.New("invite already used")
+	// ErrUsernameTaken is returned when another admin already has the username
+	ErrUsernameTaken = errors.New("admin username is already taken")
+)
+
 type AdminRepository interface {
 	CreateAdmin(admin *user.AdminRegister) error
+	CountAdmins() (int64, error)
+	CreateInvite(invite *user.AdminInvite) error
+	FindInviteByHash(tokenHash string) (*user.AdminInvite, error)
+	CreateAdminWithInvite(admin *user.AdminRegister, inviteID uint) error
 	GetAdminByUsername(username string) (*user.AdminRegister, error)
 	FindAdmin(username string)(*user.AdminRegister,error)
-	GetUserList(username string) (*[]user.UserRegister, error)
-	AddProduct(product *user.Product) error
-	GetProducts(productname string) (*[]user.Product, error)
+	GetAdminByID(id uint) (*user.AdminRegister, error)
+	UpdateAdminRole(id uint, role string) error
+	GetUserList(query *user.UserListQuery) (*[]user.UserRegister, *pagination.Page, error)
+	AddProduct(product *user.Product, actor string) error
+	GetProducts(query *user.ProductListQuery) (*[]user.Product, *pagination.Page, error)
 	FindProduct(id uint) (*user.Product, error)
 	UpdateProduct(product *user.Product) error
 	DeleteProduct(id int) error
@@ -23,27 +39,79 @@ type AdminRepository interface {
 }
 
 type AdminDataBaseInteraction struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Cursors *pagination.CursorSigner
 }
 
 func (admn *AdminDataBaseInteraction) CreateAdmin(admin *user.AdminRegister) error {
+	return createAdmin(admn.DB, admin)
+}
+
+func createAdmin(db *gorm.DB, admin *user.AdminRegister) error {
 	//HAsh password before storing into DB
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return fmt.Errorf("failed to hash password %W", err)
+		return fmt.Errorf("failed to hash password %w", err)
 	}
 
 	admin.Password = string(hashedPassword)
 
 	//**create the admin in Database
-	result := admn.DB.Create(&admin)
+	result := db.Create(&admin)
 	if result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
+func (admn *AdminDataBaseInteraction) CountAdmins() (int64, error) {
+	var count int64
+	if err := admn.DB.Model(&user.AdminRegister{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting admins: %w", err)
+	}
+	return count, nil
+}
+
+func (admn *AdminDataBaseInteraction) CreateInvite(invite *user.AdminInvite) error {
+	if err := admn.DB.Create(invite).Error; err != nil {
+		return fmt.Errorf("creating invite: %w", err)
+	}
+	return nil
+}
+
+func (admn *AdminDataBaseInteraction) FindInviteByHash(tokenHash string) (*user.AdminInvite, error) {
+	var invite user.AdminInvite
+	if err := admn.DB.Where("token_hash = ?", tokenHash).First(&invite).Error; err != nil {
+		return nil, fmt.Errorf("finding invite: %w", err)
+	}
+	return &invite, nil
+}
+
+func (admn *AdminDataBaseInteraction) CreateAdminWithInvite(admin *user.AdminRegister, inviteID uint) error {
+	return admn.DB.Transaction(func(tx *gorm.DB) error {
+		//**Claim the invite first so two signups cannot share it
+		result := tx.Model(&user.AdminInvite{}).
+			Where("id = ? AND used_at IS NULL", inviteID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("redeeming invite: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInviteUsed
+		}
+		//**Checked in the transaction so a taken name rolls the claim back and the invite stays usable
+		var taken int64
+		if err := tx.Unscoped().Model(&user.AdminRegister{}).Where("username = ?", admin.Username).Count(&taken).Error; err != nil {
+			return fmt.Errorf("checking admin username: %w", err)
+		}
+		if taken > 0 {
+			return ErrUsernameTaken
+		}
+		return createAdmin(tx, admin)
+	})
+}
+
 
 func (admn *AdminDataBaseInteraction) GetAdminByUsername(username string) (*user.AdminRegister, error) {
 	var admin user.AdminRegister
@@ -63,31 +131,241 @@ func (admn *AdminDataBaseInteraction)FindAdmin(username string)(*user.AdminRegis
 }
 
 
-func (admn *AdminDataBaseInteraction) GetUserList(username string) (*[]user.UserRegister, error) {
-	var users []user.UserRegister
-	log.Printf("Executing query with name: %s", username)
-	if err := admn.DB.Where("name LIKE ?", "%"+username+"%").Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("unable to find userlist: %w", err)
+func (admn *AdminDataBaseInteraction) GetAdminByID(id uint) (*user.AdminRegister, error) {
+	var admin user.AdminRegister
+	if err := admn.DB.First(&admin, id).Error; err != nil {
+		return nil, fmt.Errorf("getting admin by ID :%w", err)
 	}
-	log.Printf("Found users: %v", users)
-	return &users, nil
+	return &admin, nil
 }
 
-func (admn *AdminDataBaseInteraction) AddProduct(product *user.Product) error {
-	result := admn.DB.Create(&product)
+func (admn *AdminDataBaseInteraction) UpdateAdminRole(id uint, role string) error {
+	result := admn.DB.Model(&user.AdminRegister{}).Where("id = ?", id).Update("role", role)
 	if result.Error != nil {
-		return result.Error
+		return fmt.Errorf("updating admin role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no admin found with ID: %d", id)
 	}
 	return nil
 }
 
-func (admn *AdminDataBaseInteraction) GetProducts(productname string) (*[]user.Product, error) {
-	var products []user.Product
-	log.Printf("Executing query with name: %s", productname)
-	if err := admn.DB.Where("product_name LIKE ?","%"+productname+"%").Find(&products).Error; err != nil {
-		return nil, fmt.Errorf("unable to find products: %w", err)
+// userSortColumns is the allow-list of fields the user list can be sorted by
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"username":   "user_name",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// userSortValue returns the value of field on u, for the next page cursor
+func userSortValue(u *user.UserRegister, field string) (interface{}, uint) {
+	switch field {
+	case "username":
+		return u.UserName, u.ID
+	case "name":
+		return u.Name, u.ID
+	case "email":
+		return u.Email, u.ID
+	case "created_at":
+		return u.CreatedAt, u.ID
 	}
-	return &products, nil
+	return u.ID, u.ID
+}
+
+func (admn *AdminDataBaseInteraction) GetUserList(query *user.UserListQuery) (*[]user.UserRegister, *pagination.Page, error) {
+	keys, err := pagination.ParseSort(query.Sort, userSortColumns, "id")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope := admn.DB.Model(&user.UserRegister{})
+	if query.Name != "" {
+		scope = scope.Where(`name LIKE ? ESCAPE '\'`, "%"+escapeLike(query.Name)+"%")
+	}
+	if query.EmailDomain != "" {
+		scope = scope.Where(`LOWER(email) LIKE ? ESCAPE '\'`, "%@"+escapeLike(strings.ToLower(strings.TrimPrefix(query.EmailDomain, "@"))))
+	}
+	if query.CreatedFrom != nil {
+		scope = scope.Where("created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		//**The end date is inclusive
+		scope = scope.Where("created_at < ?", query.CreatedTo.AddDate(0, 0, 1))
+	}
+	filter := pagination.Fingerprint(query.Name, query.EmailDomain, optional(query.CreatedFrom), optional(query.CreatedTo))
+
+	users, page, err := listPage(scope, admn.Cursors, keys, filter, query.Page, query.Limit, query.Cursor, userSortValue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find userlist: %w", err)
+	}
+	return &users, page, nil
+}
+
+// AddProduct creates the product with no stock and books the initial quantity as
+// an opening receipt so the ledger accounts for every unit
+func (admn *AdminDataBaseInteraction) AddProduct(product *user.Product, actor string) error {
+	return admn.DB.Transaction(func(tx *gorm.DB) error {
+		opening := product.Quantity
+		product.Quantity = 0
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if opening == 0 {
+			return nil
+		}
+
+		stocked, err := RecordStockMovement(tx, &user.StockMovement{
+			ProductID: product.ID,
+			Type:      user.MovementReceipt,
+			Quantity:  opening,
+			Reason:    "opening stock",
+			Actor:     actor,
+		})
+		if err != nil {
+			return err
+		}
+		product.Quantity = stocked.Quantity
+		return nil
+	})
+}
+
+// productSortColumns is the allow-list of fields the product list can be sorted by
+var productSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "product_name",
+	"price":      "price_amount",
+	"quantity":   "quantity",
+	"created_at": "created_at",
+}
+
+// productSortValue returns the value of field on p, for the next page cursor
+func productSortValue(p *user.Product, field string) (interface{}, uint) {
+	switch field {
+	case "name":
+		return p.ProductName, p.ID
+	case "price":
+		return p.Price.Amount, p.ID
+	case "quantity":
+		return p.Quantity, p.ID
+	case "created_at":
+		return p.CreatedAt, p.ID
+	}
+	return p.ID, p.ID
+}
+
+func (admn *AdminDataBaseInteraction) GetProducts(query *user.ProductListQuery) (*[]user.Product, *pagination.Page, error) {
+	keys, err := pagination.ParseSort(query.Sort, productSortColumns, "id")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scope := admn.DB.Model(&user.Product{})
+	if query.Name != "" {
+		scope = scope.Where(`product_name LIKE ? ESCAPE '\'`, "%"+escapeLike(query.Name)+"%")
+	}
+	if query.CategoryID != nil {
+		//**A category lists the products of its subcategories too
+		scope = scope.Where("category_id IN ("+categorySubtreeSQL+")", *query.CategoryID)
+	}
+	if query.Currency != "" {
+		scope = scope.Where("price_currency = ?", query.Currency)
+	}
+	if query.MinAmount != nil {
+		scope = scope.Where("price_amount >= ?", *query.MinAmount)
+	}
+	if query.MaxAmount != nil {
+		scope = scope.Where("price_amount <= ?", *query.MaxAmount)
+	}
+	if query.InStock != nil {
+		if *query.InStock {
+			scope = scope.Where("quantity > 0")
+		} else {
+			scope = scope.Where("quantity <= 0")
+		}
+	}
+	filter := pagination.Fingerprint(query.Name, optional(query.CategoryID), query.Currency, optional(query.MinAmount), optional(query.MaxAmount), optional(query.InStock))
+
+	products, page, err := listPage(scope, admn.Cursors, keys, filter, query.Page, query.Limit, query.Cursor, productSortValue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find products: %w", err)
+	}
+	return &products, page, nil
+}
+
+// listPage fetches one page of scope. With a cursor it seeks past the cursor's
+// (sort key, id) instead of counting and skipping rows, so deep pages stay cheap
+// and rows inserted meanwhile cannot shift the window.
+func listPage[T any](scope *gorm.DB, cursors *pagination.CursorSigner, keys []pagination.SortKey, filter string, page, limit int, rawCursor string, sortValue func(*T, string) (interface{}, uint)) ([]T, *pagination.Page, error) {
+	sort := pagination.SortString(keys)
+	info := &pagination.Page{Total: -1}
+	scope = scope.Session(&gorm.Session{})
+
+	if rawCursor != "" {
+		if len(keys) != 1 {
+			return nil, nil, fmt.Errorf("%w: cursors support a single sort field", pagination.ErrInvalidSort)
+		}
+		if cursors == nil {
+			return nil, nil, pagination.ErrInvalidCursor
+		}
+		cursor, err := cursors.Decode(rawCursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		//**A cursor only makes sense for the sort and filters it was issued for
+		if cursor.Sort != sort || cursor.Filter != filter {
+			return nil, nil, pagination.ErrInvalidCursor
+		}
+		value, err := cursor.KeyValue()
+		if err != nil {
+			return nil, nil, pagination.ErrInvalidCursor
+		}
+		condition, withKey := pagination.KeysetCondition(keys[0], "id")
+		if withKey {
+			scope = scope.Where(condition, value, cursor.ID)
+		} else {
+			scope = scope.Where(condition, cursor.ID)
+		}
+	} else {
+		if err := scope.Count(&info.Total).Error; err != nil {
+			return nil, nil, err
+		}
+		scope = scope.Offset(pagination.Offset(page, limit))
+	}
+
+	//**One extra row tells whether there is a next page without counting
+	var rows []T
+	if err := scope.Order(pagination.OrderBy(keys, "id")).Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		if len(keys) == 1 && cursors != nil {
+			value, id := sortValue(&rows[len(rows)-1], keys[0].Field)
+			cursor, err := pagination.NewCursor(sort, filter, value, id)
+			if err != nil {
+				return nil, nil, err
+			}
+			if info.NextCursor, err = cursors.Encode(cursor); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return rows, info, nil
+}
+
+// optional formats a pointer filter, nil becomes empty
+func optional[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(*value)
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 func (admn *AdminDataBaseInteraction)FindProduct(id uint) (*user.Product, error){
@@ -104,10 +382,20 @@ func (admn *AdminDataBaseInteraction)UpdateProduct(product *user.Product) error{
 		return fmt.Errorf("productID is not set")
 	}
 
-	result := admn.DB.Model(&product).Where("id = ?",product.ID).Updates(product)
+	//**Only write when nobody updated the row since product.Version was read
+	//**Quantity is left alone, stock only changes through the ledger
+	expected := product.Version
+	product.Version = expected + 1
+	result := admn.DB.Model(product).Where("id = ? AND version = ?", product.ID, expected).
+		Select("product_name", "description", "price_amount", "price_currency", "category_id", "low_stock_threshold", "version").Updates(product)
 	if result.Error != nil{
+		product.Version = expected
 		return fmt.Errorf("updating the product is faile")
 	}
+	if result.RowsAffected == 0 {
+		product.Version = expected
+		return ErrVersionConflict
+	}
 	return nil
 }
 
@@ -120,9 +408,10 @@ func (admn *AdminDataBaseInteraction)DeleteProduct(id int) error{
 
 
 
-func NewAdminUserRepository(db *gorm.DB)AdminRepository{
+func NewAdminUserRepository(db *gorm.DB, cursors *pagination.CursorSigner)AdminRepository{
 	return &AdminDataBaseInteraction{
-		DB: db,
+		DB:      db,
+		Cursors: cursors,
 	}
 } 
 
@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestAdminRepository opens an in-memory SQLite database with the product table
+func newTestAdminRepository(tb testing.TB) *AdminDataBaseInteraction {
+	tb.Helper()
+	db := testdb.Open(tb, &user.Category{}, &user.Product{}, &user.StockMovement{})
+	require.NoError(tb, db.Exec("CREATE INDEX idx_products_price_id ON products (price_amount, id)").Error)
+	return &AdminDataBaseInteraction{DB: db, Cursors: pagination.NewCursorSigner([]byte("test-secret"))}
+}
+
+func seedProducts(tb testing.TB, repo *AdminDataBaseInteraction, count int) {
+	tb.Helper()
+	products := make([]user.Product, 0, count)
+	for i := 0; i < count; i++ {
+		//**Few distinct prices so the id tie-breaker matters
+		products = append(products, user.Product{ProductName: fmt.Sprintf("product-%05d", i), Quantity: i % 7, Price: money.Money{Amount: int64(i%50)*100 + 50, Currency: "USD"}, CategoryID: 1})
+	}
+	require.NoError(tb, repo.DB.CreateInBatches(products, 500).Error)
+}
+
+func TestGetProductsCursorStableUnderInserts(t *testing.T) {
+	repo := newTestAdminRepository(t)
+	seedProducts(t, repo, 95)
+
+	var original []uint
+	require.NoError(t, repo.DB.Model(&user.Product{}).Pluck("id", &original).Error)
+
+	seen := map[uint]int{}
+	query := &user.ProductListQuery{Sort: "-price", Limit: 10}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 20, "pagination did not terminate")
+		products, page, err := repo.GetProducts(query)
+		require.NoError(t, err)
+		for _, p := range *products {
+			seen[p.ID]++
+		}
+
+		//**Rows landing before and after the cursor while the client pages through
+		require.NoError(t, repo.DB.Create(&user.Product{ProductName: "new-expensive", Price: money.Money{Amount: 9900, Currency: "USD"}, CategoryID: 1}).Error)
+		require.NoError(t, repo.DB.Create(&user.Product{ProductName: "new-cheap", Price: money.Money{Amount: 10, Currency: "USD"}, CategoryID: 1}).Error)
+
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	// Every row that existed up front shows up exactly once
+	for _, id := range original {
+		assert.Equal(t, 1, seen[id], "product %d", id)
+	}
+}
+
+func TestGetProductsCursorMatchesOffset(t *testing.T) {
+	repo := newTestAdminRepository(t)
+	seedProducts(t, repo, 45)
+
+	inStock := true
+	offset, page, err := repo.GetProducts(&user.ProductListQuery{Sort: "name", InStock: &inStock, Page: 2, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(38), page.Total)
+
+	first, page, err := repo.GetProducts(&user.ProductListQuery{Sort: "name", InStock: &inStock, Limit: 10})
+	require.NoError(t, err)
+	byCursor, page, err := repo.GetProducts(&user.ProductListQuery{Sort: "name", InStock: &inStock, Limit: 10, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), page.Total)
+	assert.Len(t, *first, 10)
+	assert.Equal(t, *offset, *byCursor)
+}
+
+func TestGetProductsRejectsMismatchedCursor(t *testing.T) {
+	repo := newTestAdminRepository(t)
+	seedProducts(t, repo, 15)
+
+	_, page, err := repo.GetProducts(&user.ProductListQuery{Sort: "price", Limit: 5})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	// A cursor cannot be replayed with another sort or other filters
+	_, _, err = repo.GetProducts(&user.ProductListQuery{Sort: "-price", Limit: 5, Cursor: page.NextCursor})
+	assert.True(t, errors.Is(err, pagination.ErrInvalidCursor))
+	name := "product"
+	_, _, err = repo.GetProducts(&user.ProductListQuery{Sort: "price", Name: name, Limit: 5, Cursor: page.NextCursor})
+	assert.True(t, errors.Is(err, pagination.ErrInvalidCursor))
+	_, _, err = repo.GetProducts(&user.ProductListQuery{Sort: "price", Limit: 5, Cursor: page.NextCursor + "x"})
+	assert.True(t, errors.Is(err, pagination.ErrInvalidCursor))
+
+	// Keyset pagination needs a single sort field
+	_, _, err = repo.GetProducts(&user.ProductListQuery{Sort: "price,name", Limit: 5, Cursor: page.NextCursor})
+	assert.True(t, errors.Is(err, pagination.ErrInvalidSort))
+}
+
+var (
+	benchRepo     *AdminDataBaseInteraction
+	benchRepoOnce sync.Once
+)
+
+const benchProducts = 50000
+
+func benchAdminRepository(b *testing.B) *AdminDataBaseInteraction {
+	benchRepoOnce.Do(func() {
+		benchRepo = newTestAdminRepository(b)
+		seedProducts(b, benchRepo, benchProducts)
+	})
+	return benchRepo
+}
+
+// cursorAt returns the cursor that continues after the first skip rows sorted by -price
+func cursorAt(b *testing.B, repo *AdminDataBaseInteraction, skip int) string {
+	var last user.Product
+	require.NoError(b, repo.DB.Order("price_amount DESC, id DESC").Offset(skip-1).Limit(1).Find(&last).Error)
+	cursor, err := pagination.NewCursor("-price", pagination.Fingerprint("", "", "", "", "", ""), last.Price.Amount, last.ID)
+	require.NoError(b, err)
+	token, err := repo.Cursors.Encode(cursor)
+	require.NoError(b, err)
+	return token
+}
+
+// BenchmarkGetProducts compares reaching a page by offset with seeking to it by cursor,
+// offsets get slower the deeper the page while cursors stay flat
+func BenchmarkGetProducts(b *testing.B) {
+	repo := benchAdminRepository(b)
+	const limit = 20
+
+	for _, depth := range []int{limit, benchProducts / 2, benchProducts - limit} {
+		b.Run(fmt.Sprintf("offset/depth=%d", depth), func(b *testing.B) {
+			query := &user.ProductListQuery{Sort: "-price", Page: depth/limit + 1, Limit: limit}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repo.GetProducts(query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("cursor/depth=%d", depth), func(b *testing.B) {
+			query := &user.ProductListQuery{Sort: "-price", Limit: limit, Cursor: cursorAt(b, repo, depth)}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repo.GetProducts(query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var (
+	benchUserRepo     *AdminDataBaseInteraction
+	benchUserRepoOnce sync.Once
+)
+
+const benchUsers = 50000
+
+// benchUserRepository seeds users that joined in batches, so many share a created_at
+func benchUserRepository(b *testing.B) *AdminDataBaseInteraction {
+	benchUserRepoOnce.Do(func() {
+		db := testdb.Open(b, &user.UserRegister{})
+		require.NoError(b, db.Exec("CREATE INDEX idx_users_created_at_id ON user_registers (created_at, id)").Error)
+		joined := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		users := make([]user.UserRegister, 0, benchUsers)
+		for i := 0; i < benchUsers; i++ {
+			users = append(users, user.UserRegister{Model: gorm.Model{CreatedAt: joined.Add(time.Duration(i/10) * time.Minute)},
+				UserName: fmt.Sprintf("user-%05d", i), Name: fmt.Sprintf("User %05d", i), Email: fmt.Sprintf("user-%05d@example.com", i),
+				Phone: fmt.Sprintf("%010d", i), Password: fmt.Sprintf("hash-%05d", i)})
+		}
+		require.NoError(b, db.CreateInBatches(users, 500).Error)
+		benchUserRepo = &AdminDataBaseInteraction{DB: db, Cursors: pagination.NewCursorSigner([]byte("test-secret"))}
+	})
+	return benchUserRepo
+}
+
+// userCursorAt returns the cursor that continues after the first skip users sorted by -created_at
+func userCursorAt(b *testing.B, repo *AdminDataBaseInteraction, skip int) string {
+	var last user.UserRegister
+	require.NoError(b, repo.DB.Order("created_at DESC, id DESC").Offset(skip-1).Limit(1).Find(&last).Error)
+	cursor, err := pagination.NewCursor("-created_at", pagination.Fingerprint("", "", "", ""), last.CreatedAt, last.ID)
+	require.NoError(b, err)
+	token, err := repo.Cursors.Encode(cursor)
+	require.NoError(b, err)
+	return token
+}
+
+// BenchmarkGetUserList is BenchmarkGetProducts for the user list, newest users first
+func BenchmarkGetUserList(b *testing.B) {
+	repo := benchUserRepository(b)
+	const limit = 20
+
+	for _, depth := range []int{limit, benchUsers / 2, benchUsers - limit} {
+		b.Run(fmt.Sprintf("offset/depth=%d", depth), func(b *testing.B) {
+			query := &user.UserListQuery{Sort: "-created_at", Page: depth/limit + 1, Limit: limit}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repo.GetUserList(query); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("cursor/depth=%d", depth), func(b *testing.B) {
+			query := &user.UserListQuery{Sort: "-created_at", Limit: limit, Cursor: userCursorAt(b, repo, depth)}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				users, _, err := repo.GetUserList(query)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(*users) != limit {
+					b.Fatalf("cursor page has %d users", len(*users))
+				}
+			}
+		})
+	}
+}
+
+func TestProductPriceRoundTrip(t *testing.T) {
+	repo := newTestAdminRepository(t)
+
+	product := &user.Product{ProductName: "Shirt", Price: money.Money{Amount: 1999, Currency: "USD"}, CategoryID: 1}
+	require.NoError(t, repo.AddProduct(product, "root"))
+	require.NoError(t, repo.AddProduct(&user.Product{ProductName: "Tie", Price: money.Money{Amount: 2000, Currency: "USD"}, CategoryID: 1}, "root"))
+	require.NoError(t, repo.AddProduct(&user.Product{ProductName: "Kimono", Price: money.Money{Amount: 1999, Currency: "JPY"}, CategoryID: 1}, "root"))
+
+	stored, err := repo.FindProduct(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "19.99", stored.Price.Decimal())
+
+	// Price bounds compare exact minor units within one currency
+	max := int64(1999)
+	products, page, err := repo.GetProducts(&user.ProductListQuery{Currency: "USD", MaxAmount: &max, Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "Shirt", (*products)[0].ProductName)
+}
+
+func TestUpdateProductVersionConflict(t *testing.T) {
+	repo := newTestAdminRepository(t)
+
+	require.NoError(t, repo.AddProduct(&user.Product{ProductName: "Shirt", Price: money.Money{Amount: 1999, Currency: "USD"}, CategoryID: 1}, "root"))
+
+	// Two admins open the same product
+	first, err := repo.FindProduct(1)
+	require.NoError(t, err)
+	second, err := repo.FindProduct(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), first.Version)
+
+	first.ProductName = "Linen Shirt"
+	require.NoError(t, repo.UpdateProduct(first))
+	assert.Equal(t, uint(2), first.Version)
+
+	// The slower edit is refused instead of overwriting the first one
+	second.ProductName = "Cotton Shirt"
+	assert.True(t, errors.Is(repo.UpdateProduct(second), ErrVersionConflict))
+	assert.Equal(t, uint(1), second.Version)
+
+	stored, err := repo.FindProduct(1)
+	require.NoError(t, err)
+	assert.Equal(t, "Linen Shirt", stored.ProductName)
+	assert.Equal(t, uint(2), stored.Version)
+}
+
+func TestAdminWithoutRoleHasNoPermissions(t *testing.T) {
+	repo := &AdminDataBaseInteraction{DB: testdb.Open(t, &user.AdminRegister{})}
+
+	// A row inserted without a role must not fall back to super-admin
+	require.NoError(t, repo.CreateAdmin(&user.AdminRegister{Username: "ghost", Email: "ghost@example.com"}))
+	stored, err := repo.GetAdminByID(1)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Role)
+	assert.False(t, rbac.HasPermission(stored.Role, rbac.PermProductWrite))
+}
+
+func TestCreateAdminWithInviteRejectsTakenUsername(t *testing.T) {
+	repo := &AdminDataBaseInteraction{DB: testdb.Open(t, &user.AdminRegister{}, &user.AdminInvite{})}
+	require.NoError(t, repo.CreateAdmin(&user.AdminRegister{Username: "root", Email: "root@example.com", Password: "first", Role: rbac.RoleSuperAdmin}))
+	invite := &user.AdminInvite{Email: "new@example.com", Role: rbac.RoleSupport, TokenHash: "hash", InvitedBy: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repo.CreateInvite(invite))
+
+	// The taken name rolls the claim back, the invite can still be redeemed under another name
+	err := repo.CreateAdminWithInvite(&user.AdminRegister{Username: "root", Email: "new@example.com", Password: "second", Role: rbac.RoleSupport}, invite.ID)
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+	found, err := repo.FindInviteByHash("hash")
+	require.NoError(t, err)
+	assert.Nil(t, found.UsedAt)
+
+	require.NoError(t, repo.CreateAdminWithInvite(&user.AdminRegister{Username: "new", Email: "new@example.com", Password: "second", Role: rbac.RoleSupport}, invite.ID))
+	admin, err := repo.FindAdmin("root")
+	require.NoError(t, err)
+	assert.Equal(t, rbac.RoleSuperAdmin, admin.Role)
+
+	// The unique index backs the check up
+	assert.Error(t, repo.DB.Create(&user.AdminRegister{Username: "new", Password: "third", Role: rbac.RoleSupport}).Error)
+}
+
+func TestGetProductsNameMatchesWildcardsLiterally(t *testing.T) {
+	repo := newTestAdminRepository(t)
+	for _, name := range []string{"100% cotton", "100 cotton", "a_b", "axb"} {
+		require.NoError(t, repo.AddProduct(&user.Product{ProductName: name, Price: money.Money{Amount: 100, Currency: "USD"}, CategoryID: 1}, "root"))
+	}
+
+	for filter, want := range map[string]string{"100%": "100% cotton", "a_b": "a_b"} {
+		products, page, err := repo.GetProducts(&user.ProductListQuery{Name: filter, Sort: "name", Page: 1, Limit: 10})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, page.Total, filter)
+		assert.Equal(t, want, (*products)[0].ProductName)
+	}
+
+	require.NoError(t, repo.DB.AutoMigrate(&user.UserRegister{}))
+	for i, name := range []string{"ann_lee", "annXlee"} {
+		require.NoError(t, repo.DB.Create(&user.UserRegister{Name: name, UserName: name, Password: name, Phone: fmt.Sprint(i), Email: fmt.Sprintf("%d@example.com", i)}).Error)
+	}
+	users, page, err := repo.GetUserList(&user.UserListQuery{Name: "n_l", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, page.Total)
+	assert.Equal(t, "ann_lee", (*users)[0].Name)
+}
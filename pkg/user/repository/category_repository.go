@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+// categorySubtreeSQL selects the IDs of a category and all of its descendants
+const categorySubtreeSQL = `WITH RECURSIVE subtree(id) AS (
+	SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+) SELECT id FROM subtree`
+
+type CategoryRepository interface {
+	CreateCategory(category *user.Category) error
+	GetCategory(id uint) (*user.Category, error)
+	ListCategories() ([]user.Category, error)
+	ListChildren(parentID uint) ([]user.Category, error)
+	SubtreeIDs(id uint) ([]uint, error)
+	UpdateCategory(category *user.Category) error
+	CountChildren(id uint) (int64, error)
+	CountProducts(id uint) (int64, error)
+	DeleteCategory(id uint) error
+}
+
+type CategoryDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (c *CategoryDataBaseInteraction) CreateCategory(category *user.Category) error {
+	if err := c.DB.Create(category).Error; err != nil {
+		return fmt.Errorf("creating category: %w", err)
+	}
+	return nil
+}
+
+func (c *CategoryDataBaseInteraction) GetCategory(id uint) (*user.Category, error) {
+	var category user.Category
+	if err := c.DB.First(&category, id).Error; err != nil {
+		return nil, fmt.Errorf("getting category: %w", err)
+	}
+	return &category, nil
+}
+
+func (c *CategoryDataBaseInteraction) ListCategories() ([]user.Category, error) {
+	var categories []user.Category
+	if err := c.DB.Order("name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("listing categories: %w", err)
+	}
+	return categories, nil
+}
+
+func (c *CategoryDataBaseInteraction) ListChildren(parentID uint) ([]user.Category, error) {
+	var categories []user.Category
+	if err := c.DB.Where("parent_id = ?", parentID).Order("name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("listing subcategories: %w", err)
+	}
+	return categories, nil
+}
+
+// SubtreeIDs returns id followed by the IDs of every category below it
+func (c *CategoryDataBaseInteraction) SubtreeIDs(id uint) ([]uint, error) {
+	var ids []uint
+	if err := c.DB.Raw(categorySubtreeSQL, id).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("walking category tree: %w", err)
+	}
+	return ids, nil
+}
+
+func (c *CategoryDataBaseInteraction) UpdateCategory(category *user.Category) error {
+	//**Select so that moving a category to the top level writes the NULL parent
+	result := c.DB.Model(category).Select("name", "description", "parent_id").Updates(category)
+	if result.Error != nil {
+		return fmt.Errorf("updating category: %w", result.Error)
+	}
+	return nil
+}
+
+func (c *CategoryDataBaseInteraction) CountChildren(id uint) (int64, error) {
+	var count int64
+	if err := c.DB.Model(&user.Category{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting subcategories: %w", err)
+	}
+	return count, nil
+}
+
+// CountProducts includes products in the trash, they could not be restored once
+// their category is gone
+func (c *CategoryDataBaseInteraction) CountProducts(id uint) (int64, error) {
+	var count int64
+	if err := c.DB.Unscoped().Model(&user.Product{}).Where("category_id = ?", id).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting category products: %w", err)
+	}
+	return count, nil
+}
+
+func (c *CategoryDataBaseInteraction) DeleteCategory(id uint) error {
+	if err := c.DB.Delete(&user.Category{}, id).Error; err != nil {
+		return fmt.Errorf("deleting category: %w", err)
+	}
+	return nil
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &CategoryDataBaseInteraction{
+		DB: db,
+	}
+}
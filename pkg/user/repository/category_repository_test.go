@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"testing"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategorySubtreeAndProductListing(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	categoryRepo := NewCategoryRepository(adminRepo.DB)
+
+	// clothing > shoes > running, plus an unrelated books category
+	clothing := &user.Category{Name: "Clothing"}
+	require.NoError(t, categoryRepo.CreateCategory(clothing))
+	shoes := &user.Category{Name: "Shoes", ParentID: &clothing.ID}
+	require.NoError(t, categoryRepo.CreateCategory(shoes))
+	running := &user.Category{Name: "Running", ParentID: &shoes.ID}
+	require.NoError(t, categoryRepo.CreateCategory(running))
+	books := &user.Category{Name: "Books"}
+	require.NoError(t, categoryRepo.CreateCategory(books))
+
+	ids, err := categoryRepo.SubtreeIDs(clothing.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{clothing.ID, shoes.ID, running.ID}, ids)
+
+	for _, product := range []user.Product{
+		{ProductName: "T-Shirt", CategoryID: clothing.ID},
+		{ProductName: "Loafer", CategoryID: shoes.ID},
+		{ProductName: "Racer", CategoryID: running.ID},
+		{ProductName: "Novel", CategoryID: books.ID},
+	} {
+		require.NoError(t, adminRepo.AddProduct(&product, "root"))
+	}
+
+	// Listing a category includes the products of its subcategories
+	products, page, err := adminRepo.GetProducts(&user.ProductListQuery{CategoryID: &shoes.ID, Sort: "name", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Equal(t, "Loafer", (*products)[0].ProductName)
+	assert.Equal(t, "Racer", (*products)[1].ProductName)
+
+	// Soft deleted branches drop out of the tree
+	require.NoError(t, categoryRepo.DeleteCategory(running.ID))
+	ids, err = categoryRepo.SubtreeIDs(clothing.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{clothing.ID, shoes.ID}, ids)
+
+	// Moving a category to the top level clears its parent
+	shoes.ParentID = nil
+	require.NoError(t, categoryRepo.UpdateCategory(shoes))
+	stored, err := categoryRepo.GetCategory(shoes.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.ParentID)
+}
+
+func TestCountProductsIncludesTrash(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	categoryRepo := NewCategoryRepository(adminRepo.DB)
+
+	shoes := &user.Category{Name: "Shoes"}
+	require.NoError(t, categoryRepo.CreateCategory(shoes))
+	loafer := &user.Product{ProductName: "Loafer", CategoryID: shoes.ID}
+	require.NoError(t, adminRepo.AddProduct(loafer, "root"))
+	require.NoError(t, adminRepo.DB.Delete(loafer).Error)
+
+	// The trashed product keeps the category from being deleted
+	count, err := categoryRepo.CountProducts(shoes.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	require.NoError(t, adminRepo.DB.Unscoped().Delete(loafer).Error)
+	count, err = categoryRepo.CountProducts(shoes.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientStock is returned when a movement would take stock below zero
+var ErrInsufficientStock = errors.New("not enough stock")
+
+type InventoryRepository interface {
+	RecordMovement(movement *user.StockMovement) (*user.Product, error)
+	ListMovements(productID uint, page, limit int) ([]user.StockMovement, int64, error)
+	ListLowStock() ([]user.Product, error)
+	LedgerBalance(productID uint) (int64, error)
+	LockProducts(ids []uint) ([]user.Product, error)
+}
+
+type InventoryDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (i *InventoryDataBaseInteraction) RecordMovement(movement *user.StockMovement) (*user.Product, error) {
+	var product *user.Product
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		product, err = RecordStockMovement(tx, movement)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// RecordStockMovement applies movement.Quantity to the product's stock and appends
+// the ledger entry, tx must be a transaction so both land or neither does. Other
+// flows that move stock, such as checkout, call it inside their own transaction.
+func RecordStockMovement(tx *gorm.DB, movement *user.StockMovement) (*user.Product, error) {
+	//**The conditional update locks the row, so concurrent movements queue up and the
+	//**stock never goes negative
+	result := tx.Model(&user.Product{}).
+		Where("id = ? AND quantity + ? >= 0", movement.ProductID, movement.Quantity).
+		Update("quantity", gorm.Expr("quantity + ?", movement.Quantity))
+	if result.Error != nil {
+		return nil, fmt.Errorf("updating stock: %w", result.Error)
+	}
+
+	var product user.Product
+	if err := tx.First(&product, movement.ProductID).Error; err != nil {
+		return nil, fmt.Errorf("finding product: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %d in stock", ErrInsufficientStock, product.Quantity)
+	}
+
+	movement.Balance = product.Quantity
+	if err := tx.Create(movement).Error; err != nil {
+		return nil, fmt.Errorf("recording stock movement: %w", err)
+	}
+	return &product, nil
+}
+
+func (i *InventoryDataBaseInteraction) ListMovements(productID uint, page, limit int) ([]user.StockMovement, int64, error) {
+	scope := i.DB.Model(&user.StockMovement{}).Where("product_id = ?", productID)
+
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting stock movements: %w", err)
+	}
+	var movements []user.StockMovement
+	if err := scope.Order("created_at DESC, id DESC").Limit(limit).Offset(pagination.Offset(page, limit)).Find(&movements).Error; err != nil {
+		return nil, 0, fmt.Errorf("listing stock movements: %w", err)
+	}
+	return movements, total, nil
+}
+
+func (i *InventoryDataBaseInteraction) ListLowStock() ([]user.Product, error) {
+	var products []user.Product
+	err := i.DB.Where("low_stock_threshold > 0 AND quantity <= low_stock_threshold").
+		Order("quantity ASC, id ASC").Find(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("listing low stock products: %w", err)
+	}
+	return products, nil
+}
+
+// LedgerBalance sums the ledger, it matches Product.Quantity unless stock was changed behind its back
+func (i *InventoryDataBaseInteraction) LedgerBalance(productID uint) (int64, error) {
+	var balance int64
+	err := i.DB.Model(&user.StockMovement{}).Where("product_id = ?", productID).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&balance).Error
+	if err != nil {
+		return 0, fmt.Errorf("summing stock movements: %w", err)
+	}
+	return balance, nil
+}
+
+// LockProducts loads the products and holds their rows until the surrounding
+// transaction ends, so it only makes sense inside a unit of work. Rows are locked
+// in id order so two checkouts sharing products cannot deadlock each other
+func (i *InventoryDataBaseInteraction) LockProducts(ids []uint) ([]user.Product, error) {
+	var products []user.Product
+	err := i.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).Order("id ASC").Find(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("locking products: %w", err)
+	}
+	return products, nil
+}
+
+func NewInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &InventoryDataBaseInteraction{
+		DB: db,
+	}
+}
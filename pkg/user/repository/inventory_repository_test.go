@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStockLedger(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	inventoryRepo := NewInventoryRepository(adminRepo.DB)
+
+	// The initial quantity becomes an opening receipt
+	product := &user.Product{ProductName: "Shoe", Quantity: 10, LowStockThreshold: 3, CategoryID: 1}
+	require.NoError(t, adminRepo.AddProduct(product, "root"))
+	assert.Equal(t, 10, product.Quantity)
+
+	stocked, err := inventoryRepo.RecordMovement(&user.StockMovement{ProductID: product.ID, Type: user.MovementSale, Quantity: -8, Reason: "order 1", Actor: "root"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stocked.Quantity)
+	assert.True(t, stocked.LowStock())
+
+	// Stock never goes below zero and a refused movement leaves no entry
+	_, err = inventoryRepo.RecordMovement(&user.StockMovement{ProductID: product.ID, Type: user.MovementSale, Quantity: -3, Reason: "order 2", Actor: "root"})
+	assert.True(t, errors.Is(err, ErrInsufficientStock))
+
+	movement := &user.StockMovement{ProductID: product.ID, Type: user.MovementReturn, Quantity: 1, Reason: "order 1 returned", Actor: "support"}
+	_, err = inventoryRepo.RecordMovement(movement)
+	require.NoError(t, err)
+	assert.Equal(t, 3, movement.Balance)
+
+	// Editing the product cannot change the stock behind the ledger's back
+	product.Quantity = 100
+	product.ProductName = "Running Shoe"
+	require.NoError(t, adminRepo.UpdateProduct(product))
+
+	movements, total, err := inventoryRepo.ListMovements(product.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []string{user.MovementReturn, user.MovementSale, user.MovementReceipt}, []string{movements[0].Type, movements[1].Type, movements[2].Type})
+
+	balance, err := inventoryRepo.LedgerBalance(product.ID)
+	require.NoError(t, err)
+	stored, err := adminRepo.FindProduct(product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(stored.Quantity), balance)
+	assert.Equal(t, int64(3), balance)
+
+	low, err := inventoryRepo.ListLowStock()
+	require.NoError(t, err)
+	require.Len(t, low, 1)
+	assert.Equal(t, "Running Shoe", low[0].ProductName)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository interface {
+	GetThrottle(key string) (*user.LoginThrottle, error)
+	RecordFailure(key string, now time.Time, window time.Duration) (*user.LoginThrottle, error)
+	SetLockedUntil(key string, until time.Time) error
+	ResetThrottle(key string) error
+}
+
+type LoginAttemptDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+// GetThrottle returns nil without error when the key never failed
+func (l *LoginAttemptDataBaseInteraction) GetThrottle(key string) (*user.LoginThrottle, error) {
+	var throttle user.LoginThrottle
+	err := l.DB.Where("throttle_key = ?", key).First(&throttle).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting login throttle: %w", err)
+	}
+	return &throttle, nil
+}
+
+func (l *LoginAttemptDataBaseInteraction) RecordFailure(key string, now time.Time, window time.Duration) (*user.LoginThrottle, error) {
+	var throttle user.LoginThrottle
+	err := l.DB.Transaction(func(tx *gorm.DB) error {
+		//**Lock the row so parallel attempts cannot lose increments
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("throttle_key = ?", key).First(&throttle).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			throttle = user.LoginThrottle{ThrottleKey: key, Failures: 1, LastFailureAt: now}
+			return tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "throttle_key"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"failures": gorm.Expr("login_throttles.failures + 1"), "last_failure_at": now}),
+			}).Create(&throttle).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		applyFailure(&throttle, now, window)
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recording login failure: %w", err)
+	}
+	return &throttle, nil
+}
+
+func (l *LoginAttemptDataBaseInteraction) SetLockedUntil(key string, until time.Time) error {
+	result := l.DB.Model(&user.LoginThrottle{}).Where("throttle_key = ?", key).Update("locked_until", until)
+	if result.Error != nil {
+		return fmt.Errorf("locking login: %w", result.Error)
+	}
+	return nil
+}
+
+func (l *LoginAttemptDataBaseInteraction) ResetThrottle(key string) error {
+	if err := l.DB.Where("throttle_key = ?", key).Delete(&user.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("resetting login throttle: %w", err)
+	}
+	return nil
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &LoginAttemptDataBaseInteraction{
+		DB: db,
+	}
+}
+
+// MemoryLoginAttemptStore keeps throttles in process, used by tests and single instance setups
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	throttles map[string]user.LoginThrottle
+}
+
+func (m *MemoryLoginAttemptStore) GetThrottle(key string) (*user.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	throttle, ok := m.throttles[key]
+	if !ok {
+		return nil, nil
+	}
+	return &throttle, nil
+}
+
+func (m *MemoryLoginAttemptStore) RecordFailure(key string, now time.Time, window time.Duration) (*user.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	throttle, ok := m.throttles[key]
+	if !ok {
+		throttle = user.LoginThrottle{ThrottleKey: key, Failures: 1, LastFailureAt: now}
+	} else {
+		applyFailure(&throttle, now, window)
+	}
+	m.throttles[key] = throttle
+	return &throttle, nil
+}
+
+func (m *MemoryLoginAttemptStore) SetLockedUntil(key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if throttle, ok := m.throttles[key]; ok {
+		throttle.LockedUntil = &until
+		m.throttles[key] = throttle
+	}
+	return nil
+}
+
+func (m *MemoryLoginAttemptStore) ResetThrottle(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.throttles, key)
+	return nil
+}
+
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{throttles: map[string]user.LoginThrottle{}}
+}
+
+// applyFailure counts one more failure, old failures outside the window are forgotten
+func applyFailure(throttle *user.LoginThrottle, now time.Time, window time.Duration) {
+	lockExpired := throttle.LockedUntil != nil && now.After(*throttle.LockedUntil)
+	if now.Sub(throttle.LastFailureAt) > window && (throttle.LockedUntil == nil || lockExpired) {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMFACodeReplayed is returned when a TOTP step was already used to sign in
+	ErrMFACodeReplayed = errors.New("code already used")
+	// ErrRecoveryCodeUsed is returned for unknown or consumed recovery codes
+	ErrRecoveryCodeUsed = errors.New("recovery code already used")
+	// ErrMFAChallengeUsed is returned when a challenge was already consumed
+	ErrMFAChallengeUsed = errors.New("challenge already used")
+)
+
+type MFARepository interface {
+	GetFactor(subjectID uint, account string) (*user.MFAFactor, error)
+	SavePendingFactor(factor *user.MFAFactor) error
+	ConfirmFactor(factorID uint, step int64, codeHashes []string) error
+	UseFactorStep(factorID uint, step int64) error
+	DeleteFactor(factorID uint) error
+	ReplaceRecoveryCodes(factorID uint, codeHashes []string) error
+	ConsumeRecoveryCode(factorID uint, codeHash string) error
+	CreateChallenge(challenge *user.MFAChallenge) error
+	FindChallenge(tokenHash string) (*user.MFAChallenge, error)
+	RecordChallengeAttempt(id uint) error
+	ConsumeChallenge(id uint) error
+}
+
+type MFADataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (m *MFADataBaseInteraction) GetFactor(subjectID uint, account string) (*user.MFAFactor, error) {
+	var factor user.MFAFactor
+	if err := m.DB.Where("subject_id = ? AND account = ?", subjectID, account).First(&factor).Error; err != nil {
+		return nil, fmt.Errorf("getting mfa factor: %w", err)
+	}
+	return &factor, nil
+}
+
+// SavePendingFactor replaces an unconfirmed factor, restarting enrollment
+func (m *MFADataBaseInteraction) SavePendingFactor(factor *user.MFAFactor) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("subject_id = ? AND account = ? AND confirmed_at IS NULL", factor.SubjectID, factor.Account).
+			Delete(&user.MFAFactor{}).Error; err != nil {
+			return fmt.Errorf("removing pending mfa factor: %w", err)
+		}
+		if err := tx.Create(factor).Error; err != nil {
+			return fmt.Errorf("creating mfa factor: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m *MFADataBaseInteraction) ConfirmFactor(factorID uint, step int64, codeHashes []string) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.MFAFactor{}).
+			Where("id = ? AND confirmed_at IS NULL", factorID).
+			Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_used_step": step})
+		if result.Error != nil {
+			return fmt.Errorf("confirming mfa factor: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("confirming mfa factor: %w", gorm.ErrRecordNotFound)
+		}
+		return replaceRecoveryCodes(tx, factorID, codeHashes)
+	})
+}
+
+// UseFactorStep moves the factor past step, a step can only be used once
+func (m *MFADataBaseInteraction) UseFactorStep(factorID uint, step int64) error {
+	result := m.DB.Model(&user.MFAFactor{}).
+		Where("id = ? AND last_used_step < ?", factorID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("using mfa step: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFACodeReplayed
+	}
+	return nil
+}
+
+func (m *MFADataBaseInteraction) DeleteFactor(factorID uint) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("factor_id = ?", factorID).Delete(&user.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("deleting recovery codes: %w", err)
+		}
+		if err := tx.Unscoped().Delete(&user.MFAFactor{}, factorID).Error; err != nil {
+			return fmt.Errorf("deleting mfa factor: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m *MFADataBaseInteraction) ReplaceRecoveryCodes(factorID uint, codeHashes []string) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, factorID, codeHashes)
+	})
+}
+
+func (m *MFADataBaseInteraction) ConsumeRecoveryCode(factorID uint, codeHash string) error {
+	result := m.DB.Model(&user.RecoveryCode{}).
+		Where("factor_id = ? AND code_hash = ? AND used_at IS NULL", factorID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("consuming recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeUsed
+	}
+	return nil
+}
+
+func (m *MFADataBaseInteraction) CreateChallenge(challenge *user.MFAChallenge) error {
+	if err := m.DB.Create(challenge).Error; err != nil {
+		return fmt.Errorf("creating mfa challenge: %w", err)
+	}
+	return nil
+}
+
+func (m *MFADataBaseInteraction) FindChallenge(tokenHash string) (*user.MFAChallenge, error) {
+	var challenge user.MFAChallenge
+	if err := m.DB.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		return nil, fmt.Errorf("finding mfa challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+func (m *MFADataBaseInteraction) RecordChallengeAttempt(id uint) error {
+	if err := m.DB.Model(&user.MFAChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return fmt.Errorf("recording mfa attempt: %w", err)
+	}
+	return nil
+}
+
+func (m *MFADataBaseInteraction) ConsumeChallenge(id uint) error {
+	result := m.DB.Model(&user.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("consuming mfa challenge: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFAChallengeUsed
+	}
+	return nil
+}
+
+// replaceRecoveryCodes drops every code of the factor and stores the new set
+func replaceRecoveryCodes(tx *gorm.DB, factorID uint, codeHashes []string) error {
+	if err := tx.Unscoped().Where("factor_id = ?", factorID).Delete(&user.RecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("deleting recovery codes: %w", err)
+	}
+	codes := make([]user.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, user.RecoveryCode{FactorID: factorID, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	if err := tx.Create(&codes).Error; err != nil {
+		return fmt.Errorf("creating recovery codes: %w", err)
+	}
+	return nil
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &MFADataBaseInteraction{
+		DB: db,
+	}
+}
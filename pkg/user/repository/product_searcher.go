@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+// ErrEmptySearch is returned when the search text holds no searchable words
+var ErrEmptySearch = errors.New("search query has no searchable words")
+
+// Markers around matched words in search highlights, the rest of a highlight is
+// HTML escaped so product text can never turn into markup
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// Placeholders the highlighters put around matches before the text is escaped,
+// private use characters so they cannot be confused with the markers above
+const (
+	matchStart = "\uE000"
+	matchStop  = "\uE001"
+)
+
+// ProductSearcher finds products by relevance to free text over their name and description
+type ProductSearcher interface {
+	SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error)
+}
+
+// ProductSearchDataBaseInteraction searches the products.search_vector column, a
+// generated tsvector weighting the name (A) above the description (B)
+type ProductSearchDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+// productSearchSQL ranks and pages the matches first so ts_headline, which is
+// expensive, only runs for the rows of the page
+const productSearchSQL = `
+SELECT p.*, hits.rank,
+	ts_headline('english', p.product_name, hits.query, @name_options) AS name_highlight,
+	ts_headline('english', coalesce(p.description, ''), hits.query, @description_options) AS description_highlight
+FROM (
+	SELECT id, query, ts_rank_cd(search_vector, query, 32) AS rank
+	FROM products, to_tsquery('english', @query) AS query
+	WHERE deleted_at IS NULL AND search_vector @@ query
+	ORDER BY rank DESC, id ASC
+	LIMIT @limit OFFSET @offset
+) AS hits
+JOIN products p ON p.id = hits.id
+ORDER BY hits.rank DESC, p.id ASC`
+
+type productSearchRow struct {
+	user.Product
+	Rank                 float64
+	NameHighlight        string
+	DescriptionHighlight string
+}
+
+func (s *ProductSearchDataBaseInteraction) SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error) {
+	tsQuery := PrefixTSQuery(query.Query)
+	if tsQuery == "" {
+		return nil, 0, ErrEmptySearch
+	}
+
+	var total int64
+	if err := s.DB.Model(&user.Product{}).Where("search_vector @@ to_tsquery('english', ?)", tsQuery).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting search results: %w", err)
+	}
+
+	var rows []productSearchRow
+	highlight := fmt.Sprintf("StartSel=%s, StopSel=%s", matchStart, matchStop)
+	err := s.DB.Raw(productSearchSQL, map[string]interface{}{
+		"query":               tsQuery,
+		"name_options":        highlight + ", HighlightAll=true",
+		"description_options": highlight + ", MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \"",
+		"limit":               query.Limit,
+		"offset":              pagination.Offset(query.Page, query.Limit),
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("searching products: %w", err)
+	}
+
+	hits := make([]user.ProductSearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, user.ProductSearchHit{
+			Product:              row.Product,
+			Rank:                 row.Rank,
+			NameHighlight:        escapeHighlight(row.NameHighlight),
+			DescriptionHighlight: escapeHighlight(row.DescriptionHighlight),
+		})
+	}
+	return hits, total, nil
+}
+
+// PrefixTSQuery turns free text into a to_tsquery expression that requires every
+// word and matches the last one as a prefix. Only letters and digits survive so
+// user input cannot inject tsquery operators.
+func PrefixTSQuery(text string) string {
+	words := searchWords(text)
+	if len(words) == 0 {
+		return ""
+	}
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MemoryProductSearcher is a ProductSearcher over an in-memory product list for
+// tests. It matches whole words without stemming and mimics the weighting.
+type MemoryProductSearcher struct {
+	mu       sync.RWMutex
+	products []user.Product
+}
+
+// Weights of a word found in the name and the description
+const (
+	memoryNameWeight        = 1.0
+	memoryDescriptionWeight = 0.4
+)
+
+func (m *MemoryProductSearcher) Add(products ...user.Product) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.products = append(m.products, products...)
+}
+
+func (m *MemoryProductSearcher) SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error) {
+	terms := searchWords(query.Query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptySearch
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []user.ProductSearchHit
+	for _, product := range m.products {
+		if product.DeletedAt.Valid {
+			continue
+		}
+		rank, ok := 0.0, true
+		for i, term := range terms {
+			prefix := i == len(terms)-1
+			weight := 0.0
+			if containsWord(product.ProductName, term, prefix) {
+				weight += memoryNameWeight
+			}
+			if containsWord(product.Description, term, prefix) {
+				weight += memoryDescriptionWeight
+			}
+			if weight == 0 {
+				ok = false
+				break
+			}
+			rank += weight
+		}
+		if !ok {
+			continue
+		}
+		hits = append(hits, user.ProductSearchHit{
+			Product:              product,
+			Rank:                 rank / (rank + 1),
+			NameHighlight:        highlightWords(product.ProductName, terms),
+			DescriptionHighlight: highlightWords(product.Description, terms),
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Product.ID < hits[j].Product.ID
+	})
+
+	total := int64(len(hits))
+	start := pagination.Offset(query.Page, query.Limit)
+	if start > len(hits) {
+		start = len(hits)
+	}
+	end := start + query.Limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	return hits[start:end], total, nil
+}
+
+func containsWord(text, term string, prefix bool) bool {
+	for _, word := range searchWords(text) {
+		if word == term || (prefix && strings.HasPrefix(word, term)) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeHighlight HTML escapes text and only then turns the match placeholders into markers
+func escapeHighlight(text string) string {
+	return strings.NewReplacer(matchStart, HighlightStart, matchStop, HighlightStop).Replace(html.EscapeString(text))
+}
+
+// highlightWords marks every word of text matching a term, the last term as a prefix
+func highlightWords(text string, terms []string) string {
+	var out strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		lower := strings.ToLower(string(word))
+		matched := false
+		for i, term := range terms {
+			if lower == term || (i == len(terms)-1 && strings.HasPrefix(lower, term)) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			out.WriteString(matchStart + string(word) + matchStop)
+		} else {
+			out.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		out.WriteRune(r)
+	}
+	flush()
+	return escapeHighlight(out.String())
+}
+
+func NewProductSearcher(db *gorm.DB) ProductSearcher {
+	return &ProductSearchDataBaseInteraction{
+		DB: db,
+	}
+}
+
+func NewMemoryProductSearcher(products ...user.Product) *MemoryProductSearcher {
+	return &MemoryProductSearcher{products: products}
+}
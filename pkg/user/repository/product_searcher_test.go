@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestPrefixTSQuery(t *testing.T) {
+	assert.Equal(t, "running & sho:*", PrefixTSQuery("Running  sho"))
+	// Operators and quotes are dropped instead of reaching to_tsquery
+	assert.Equal(t, "red & shoe:*", PrefixTSQuery("red & !shoe' | :*"))
+	assert.Equal(t, "café:*", PrefixTSQuery("Café"))
+	assert.Equal(t, "", PrefixTSQuery(" &|! "))
+}
+
+func TestMemoryProductSearcher(t *testing.T) {
+	searcher := NewMemoryProductSearcher(
+		user.Product{Model: gorm.Model{ID: 1}, ProductName: "Trail Running Shoe", Description: "Grippy sole"},
+		user.Product{Model: gorm.Model{ID: 2}, ProductName: "Socks", Description: "Soft socks for running shoes"},
+		user.Product{Model: gorm.Model{ID: 3}, ProductName: "Running Shorts", Description: "Light"},
+		user.Product{Model: gorm.Model{ID: 4, DeletedAt: gorm.DeletedAt{Valid: true}}, ProductName: "Old Running Shoe"},
+	)
+
+	// A name match outranks a description match, the last word matches as a prefix
+	hits, total, err := searcher.SearchProducts(&user.ProductSearchQuery{Query: "running sho", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	ids := []uint{}
+	for _, hit := range hits {
+		ids = append(ids, hit.Product.ID)
+	}
+	assert.Equal(t, []uint{1, 3, 2}, ids)
+	assert.Equal(t, "Trail <mark>Running</mark> <mark>Shoe</mark>", hits[0].NameHighlight)
+	assert.Equal(t, "Soft socks for <mark>running</mark> <mark>shoes</mark>", hits[2].DescriptionHighlight)
+
+	hits, total, err = searcher.SearchProducts(&user.ProductSearchQuery{Query: "running sho", Page: 2, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, hits, 1)
+
+	_, _, err = searcher.SearchProducts(&user.ProductSearchQuery{Query: "!!", Page: 1, Limit: 10})
+	assert.True(t, errors.Is(err, ErrEmptySearch))
+}
+
+func TestHighlightsEscapeProductText(t *testing.T) {
+	searcher := NewMemoryProductSearcher(
+		user.Product{Model: gorm.Model{ID: 1}, ProductName: "Shoe <b>", Description: `<script>alert("running")</script> running shoes`},
+	)
+
+	hits, _, err := searcher.SearchProducts(&user.ProductSearchQuery{Query: "running", Page: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "&lt;script&gt;alert(&#34;<mark>running</mark>&#34;)&lt;/script&gt; <mark>running</mark> shoes", hits[0].DescriptionHighlight)
+	assert.Equal(t, "Shoe &lt;b&gt;", hits[0].NameHighlight)
+
+	// The placeholders ts_headline puts around matches become the only markup
+	assert.Equal(t, "&lt;i&gt;<mark>red</mark>&lt;/i&gt;", escapeHighlight("<i>"+matchStart+"red"+matchStop+"</i>"))
+}
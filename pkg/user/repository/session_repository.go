@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenUsed is returned when a refresh token was already rotated
+var ErrRefreshTokenUsed = errors.New("refresh token already used")
+
+type SessionRepository interface {
+	CreateSession(session *user.Session, refresh *user.RefreshToken) error
+	GetSessionByID(id uint) (*user.Session, error)
+	FindRefreshToken(tokenHash string) (*user.RefreshToken, error)
+	RotateRefreshToken(used *user.RefreshToken, next *user.RefreshToken) error
+	RevokeSession(id uint) error
+	RevokeSubjectSessions(subjectID uint, account string) error
+	RevokeOtherSessions(subjectID uint, account string, keepSessionID uint) error
+}
+
+type SessionDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (s *SessionDataBaseInteraction) CreateSession(session *user.Session, refresh *user.RefreshToken) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return fmt.Errorf("creating session: %w", err)
+		}
+		refresh.SessionID = session.ID
+		if err := tx.Create(refresh).Error; err != nil {
+			return fmt.Errorf("creating refresh token: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SessionDataBaseInteraction) GetSessionByID(id uint) (*user.Session, error) {
+	var session user.Session
+	if err := s.DB.First(&session, id).Error; err != nil {
+		return nil, fmt.Errorf("getting session by ID :%w", err)
+	}
+	return &session, nil
+}
+
+func (s *SessionDataBaseInteraction) FindRefreshToken(tokenHash string) (*user.RefreshToken, error) {
+	var refresh user.RefreshToken
+	if err := s.DB.Where("token_hash = ?", tokenHash).First(&refresh).Error; err != nil {
+		return nil, fmt.Errorf("finding refresh token: %w", err)
+	}
+	return &refresh, nil
+}
+
+func (s *SessionDataBaseInteraction) RotateRefreshToken(used *user.RefreshToken, next *user.RefreshToken) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		//**Only one concurrent request may consume the token
+		result := tx.Model(&user.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", used.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("marking refresh token used: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenUsed
+		}
+
+		next.SessionID = used.SessionID
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("creating refresh token: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *SessionDataBaseInteraction) RevokeSession(id uint) error {
+	result := s.DB.Model(&user.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("revoking session: %w", result.Error)
+	}
+	return nil
+}
+
+func (s *SessionDataBaseInteraction) RevokeSubjectSessions(subjectID uint, account string) error {
+	result := s.DB.Model(&user.Session{}).
+		Where("subject_id = ? AND account = ? AND revoked_at IS NULL", subjectID, account).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("revoking sessions: %w", result.Error)
+	}
+	return nil
+}
+
+func (s *SessionDataBaseInteraction) RevokeOtherSessions(subjectID uint, account string, keepSessionID uint) error {
+	result := s.DB.Model(&user.Session{}).
+		Where("subject_id = ? AND account = ? AND id <> ? AND revoked_at IS NULL", subjectID, account, keepSessionID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("revoking sessions: %w", result.Error)
+	}
+	return nil
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &SessionDataBaseInteraction{
+		DB: db,
+	}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"gorm.io/gorm"
+)
+
+// TrashRepository reaches the rows gorm.Model soft deleted. Every method only
+// matches rows that are in the trash and returns gorm.ErrRecordNotFound otherwise
+type TrashRepository interface {
+	ListDeletedProducts(page, limit int) ([]user.Product, int64, error)
+	ListDeletedUsers(page, limit int) ([]user.UserRegister, int64, error)
+	FindDeletedProduct(id uint) (*user.Product, error)
+	RestoreProduct(id uint) (*user.Product, error)
+	RestoreUser(id uint) (*user.UserRegister, error)
+	PurgeProduct(id uint) error
+	PurgeUser(id uint) error
+	PurgeDeletedBefore(cutoff time.Time) (*user.PurgeResult, error)
+}
+
+type TrashDataBaseInteraction struct {
+	DB *gorm.DB
+}
+
+func (t *TrashDataBaseInteraction) ListDeletedProducts(page, limit int) ([]user.Product, int64, error) {
+	return listDeleted[user.Product](t.DB, page, limit)
+}
+
+func (t *TrashDataBaseInteraction) ListDeletedUsers(page, limit int) ([]user.UserRegister, int64, error) {
+	return listDeleted[user.UserRegister](t.DB, page, limit)
+}
+
+// listDeleted pages through the soft deleted rows of T
+func listDeleted[T any](db *gorm.DB, page, limit int) ([]T, int64, error) {
+	scope := db.Unscoped().Model(new(T)).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := scope.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting deleted records: %w", err)
+	}
+	var rows []T
+	if err := scope.Order("deleted_at DESC, id DESC").Limit(limit).Offset(pagination.Offset(page, limit)).Find(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("listing deleted records: %w", err)
+	}
+	return rows, total, nil
+}
+
+func (t *TrashDataBaseInteraction) FindDeletedProduct(id uint) (*user.Product, error) {
+	var product user.Product
+	if err := t.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&product, id).Error; err != nil {
+		return nil, fmt.Errorf("finding deleted product %d: %w", id, err)
+	}
+	return &product, nil
+}
+
+func (t *TrashDataBaseInteraction) RestoreProduct(id uint) (*user.Product, error) {
+	var product user.Product
+	if err := restore(t.DB, &product, id); err != nil {
+		return nil, fmt.Errorf("restoring product %d: %w", id, err)
+	}
+	return &product, nil
+}
+
+func (t *TrashDataBaseInteraction) RestoreUser(id uint) (*user.UserRegister, error) {
+	var restored user.UserRegister
+	if err := restore(t.DB, &restored, id); err != nil {
+		return nil, fmt.Errorf("restoring user %d: %w", id, err)
+	}
+	return &restored, nil
+}
+
+// restore clears deleted_at of a trashed row and reloads it into model,
+// the version moves on so edits made before the delete cannot be replayed
+func restore(db *gorm.DB, model interface{}, id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(model, id).Error
+	})
+}
+
+// PurgeProduct deletes a trashed product for good, its stock ledger goes with it
+func (t *TrashDataBaseInteraction) PurgeProduct(id uint) error {
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&user.Product{}).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&user.StockMovement{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&user.Product{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("purging product %d: %w", id, err)
+	}
+	return nil
+}
+
+// PurgeUser deletes a trashed user for good, with the sessions, second factor
+// and mailed tokens kept for the account
+func (t *TrashDataBaseInteraction) PurgeUser(id uint) error {
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL").Delete(&user.UserRegister{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return purgeUserAccounts(tx, []uint{id})
+	})
+	if err != nil {
+		return fmt.Errorf("purging user %d: %w", id, err)
+	}
+	return nil
+}
+
+// PurgeDeletedBefore hard deletes every product and user soft deleted before cutoff
+func (t *TrashDataBaseInteraction) PurgeDeletedBefore(cutoff time.Time) (*user.PurgeResult, error) {
+	purged := &user.PurgeResult{}
+	err := t.DB.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&user.Product{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err := tx.Where("product_id IN (?)", expired).Delete(&user.StockMovement{}).Error; err != nil {
+			return err
+		}
+		products := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&user.Product{})
+		if products.Error != nil {
+			return products.Error
+		}
+		var expiredUsers []uint
+		if err := tx.Unscoped().Model(&user.UserRegister{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Pluck("id", &expiredUsers).Error; err != nil {
+			return err
+		}
+		if len(expiredUsers) > 0 {
+			if err := purgeUserAccounts(tx, expiredUsers); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Delete(&user.UserRegister{}, expiredUsers).Error; err != nil {
+				return err
+			}
+		}
+		purged.Products, purged.Users = products.RowsAffected, int64(len(expiredUsers))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("purging expired trash: %w", err)
+	}
+	return purged, nil
+}
+
+// purgeUserAccounts deletes what the login tables keep about the users in ids,
+// those rows are keyed by subject and account and have no foreign key to cascade
+func purgeUserAccounts(tx *gorm.DB, ids []uint) error {
+	sessions := tx.Unscoped().Model(&user.Session{}).Select("id").Where("subject_id IN ? AND account = ?", ids, user.AccountUser)
+	if err := tx.Unscoped().Where("session_id IN (?)", sessions).Delete(&user.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("subject_id IN ? AND account = ?", ids, user.AccountUser).Delete(&user.Session{}).Error; err != nil {
+		return err
+	}
+	factors := tx.Unscoped().Model(&user.MFAFactor{}).Select("id").Where("subject_id IN ? AND account = ?", ids, user.AccountUser)
+	if err := tx.Unscoped().Where("factor_id IN (?)", factors).Delete(&user.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("subject_id IN ? AND account = ?", ids, user.AccountUser).Delete(&user.MFAFactor{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("subject_id IN ? AND account = ?", ids, user.AccountUser).Delete(&user.MFAChallenge{}).Error; err != nil {
+		return err
+	}
+	//**Action tokens are only mailed to users, they carry no account
+	return tx.Unscoped().Where("subject_id IN ?", ids).Delete(&user.ActionToken{}).Error
+}
+
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &TrashDataBaseInteraction{
+		DB: db,
+	}
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	require.NoError(t, adminRepo.DB.AutoMigrate(&user.UserRegister{}))
+	trash := NewTrashRepository(adminRepo.DB)
+
+	kept := &user.Product{ProductName: "Shoe", Quantity: 4, CategoryID: 1}
+	trashed := &user.Product{ProductName: "Boot", Quantity: 2, CategoryID: 1}
+	require.NoError(t, adminRepo.AddProduct(kept, "root"))
+	require.NoError(t, adminRepo.AddProduct(trashed, "root"))
+	require.NoError(t, adminRepo.DeleteProduct(int(trashed.ID)))
+
+	// Only soft deleted rows are in the trash
+	deleted, total, err := trash.ListDeletedProducts(1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, trashed.ID, deleted[0].ID)
+	assert.True(t, deleted[0].DeletedAt.Valid)
+
+	_, err = trash.RestoreProduct(kept.ID)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	assert.True(t, errors.Is(trash.PurgeProduct(kept.ID), gorm.ErrRecordNotFound))
+
+	restored, err := trash.RestoreProduct(trashed.ID)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Equal(t, uint(2), restored.Version)
+	_, err = adminRepo.FindProduct(trashed.ID)
+	require.NoError(t, err)
+
+	// Purging takes the product's stock ledger with it
+	require.NoError(t, adminRepo.DeleteProduct(int(trashed.ID)))
+	require.NoError(t, trash.PurgeProduct(trashed.ID))
+	var rows, movements int64
+	adminRepo.DB.Unscoped().Model(&user.Product{}).Where("id = ?", trashed.ID).Count(&rows)
+	adminRepo.DB.Model(&user.StockMovement{}).Where("product_id = ?", trashed.ID).Count(&movements)
+	assert.Zero(t, rows)
+	assert.Zero(t, movements)
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	require.NoError(t, adminRepo.DB.AutoMigrate(&user.UserRegister{}))
+	trash := NewTrashRepository(adminRepo.DB)
+
+	old := &user.Product{ProductName: "Old", Quantity: 1, CategoryID: 1}
+	recent := &user.Product{ProductName: "Recent", CategoryID: 1}
+	require.NoError(t, adminRepo.AddProduct(old, "root"))
+	require.NoError(t, adminRepo.AddProduct(recent, "root"))
+	stale := &user.UserRegister{UserName: "gone", Name: "Gone", Email: "gone@example.com", Phone: "1", Password: "x"}
+	require.NoError(t, adminRepo.DB.Create(stale).Error)
+	seedLoginData(t, adminRepo.DB, stale.ID, user.AccountUser)
+
+	now := time.Now()
+	adminRepo.DB.Unscoped().Model(old).Update("deleted_at", now.Add(-40*24*time.Hour))
+	adminRepo.DB.Unscoped().Model(recent).Update("deleted_at", now.Add(-time.Hour))
+	adminRepo.DB.Unscoped().Model(stale).Update("deleted_at", now.Add(-31*24*time.Hour))
+
+	purged, err := trash.PurgeDeletedBefore(now.Add(-30 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, &user.PurgeResult{Products: 1, Users: 1}, purged)
+	assertNoLoginData(t, adminRepo.DB)
+
+	// The recently deleted product stays restorable
+	remaining, total, err := trash.ListDeletedProducts(1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, recent.ID, remaining[0].ID)
+}
+
+// loginTables are the tables that keep rows about an account outside its own row
+var loginTables = []interface{}{&user.Session{}, &user.RefreshToken{}, &user.MFAFactor{}, &user.RecoveryCode{}, &user.MFAChallenge{}, &user.ActionToken{}}
+
+// seedLoginData gives the account a session, a second factor and a mailed token
+func seedLoginData(tb testing.TB, db *gorm.DB, subjectID uint, account string) {
+	tb.Helper()
+	require.NoError(tb, db.AutoMigrate(loginTables...))
+	expires := time.Now().Add(time.Hour)
+	session := &user.Session{SubjectID: subjectID, Account: account, Role: "customer", ExpiresAt: expires}
+	require.NoError(tb, db.Create(session).Error)
+	require.NoError(tb, db.Create(&user.RefreshToken{SessionID: session.ID, TokenHash: fmt.Sprintf("refresh-%s-%d", account, subjectID), ExpiresAt: expires}).Error)
+	factor := &user.MFAFactor{SubjectID: subjectID, Account: account, Secret: "JBSWY3DPEHPK3PXP"}
+	require.NoError(tb, db.Create(factor).Error)
+	require.NoError(tb, db.Create(&user.RecoveryCode{FactorID: factor.ID, CodeHash: "code"}).Error)
+	require.NoError(tb, db.Create(&user.MFAChallenge{SubjectID: subjectID, Account: account, Username: "u", Role: "customer",
+		TokenHash: fmt.Sprintf("challenge-%s-%d", account, subjectID), ExpiresAt: expires}).Error)
+	if account == user.AccountUser {
+		require.NoError(tb, db.Create(&user.ActionToken{SubjectID: subjectID, Purpose: user.PurposePasswordReset, TokenHash: fmt.Sprintf("reset-%d", subjectID), ExpiresAt: expires}).Error)
+	}
+}
+
+func countLoginData(tb testing.TB, db *gorm.DB) map[string]int64 {
+	tb.Helper()
+	counts := map[string]int64{}
+	for _, table := range loginTables {
+		var count int64
+		require.NoError(tb, db.Unscoped().Model(table).Count(&count).Error)
+		counts[fmt.Sprintf("%T", table)] = count
+	}
+	return counts
+}
+
+func assertNoLoginData(tb testing.TB, db *gorm.DB) {
+	tb.Helper()
+	for table, count := range countLoginData(tb, db) {
+		assert.Zero(tb, count, table)
+	}
+}
+
+func TestPurgeUserTakesLoginData(t *testing.T) {
+	adminRepo := newTestAdminRepository(t)
+	require.NoError(t, adminRepo.DB.AutoMigrate(&user.UserRegister{}))
+	trash := NewTrashRepository(adminRepo.DB)
+
+	gone := &user.UserRegister{UserName: "gone", Name: "Gone", Email: "gone@example.com", Phone: "1", Password: "x"}
+	require.NoError(t, adminRepo.DB.Create(gone).Error)
+	seedLoginData(t, adminRepo.DB, gone.ID, user.AccountUser)
+
+	// Live users cannot be purged and keep everything
+	assert.True(t, errors.Is(trash.PurgeUser(gone.ID), gorm.ErrRecordNotFound))
+	assert.EqualValues(t, 1, countLoginData(t, adminRepo.DB)["*user.MFAFactor"])
+
+	require.NoError(t, adminRepo.DB.Delete(gone).Error)
+	require.NoError(t, trash.PurgeUser(gone.ID))
+	assertNoLoginData(t, adminRepo.DB)
+
+	// An admin with the same ID is a different account and keeps its rows
+	again := &user.UserRegister{UserName: "again", Name: "Again", Email: "again@example.com", Phone: "2", Password: "y"}
+	require.NoError(t, adminRepo.DB.Create(again).Error)
+	seedLoginData(t, adminRepo.DB, again.ID, user.AccountUser)
+	seedLoginData(t, adminRepo.DB, again.ID, user.AccountAdmin)
+	require.NoError(t, adminRepo.DB.Delete(again).Error)
+	require.NoError(t, trash.PurgeUser(again.ID))
+	counts := countLoginData(t, adminRepo.DB)
+	assert.EqualValues(t, 1, counts["*user.Session"])
+	assert.EqualValues(t, 1, counts["*user.RefreshToken"])
+	assert.EqualValues(t, 1, counts["*user.MFAFactor"])
+	assert.EqualValues(t, 1, counts["*user.RecoveryCode"])
+	assert.Zero(t, counts["*user.ActionToken"])
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	user "github.com/ratheeshkumar25/pkg/user/entity"
 	"golang.org/x/crypto/bcrypt"
@@ -11,6 +12,10 @@ import (
 type UserRepository interface {
 	CreateUser(user *user.UserRegister) error
 	FindUserByName(username string) (*user.UserRegister, error)
+	FindUserByEmail(email string) (*user.UserRegister, error)
+	UpdatePassword(id uint, password string) error
+	ResetPassword(id, actionTokenID uint, password string) error
+	MarkEmailVerified(id uint) error
 	UpdateUser(user *user.UserRegister) error
 	GetUserByID(id uint) (*user.UserRegister, error)
 	DeleteUser(id int) error
@@ -36,21 +41,27 @@ func (u *UserDataBaseInteraction) CreateUser(user *user.UserRegister) error {
 	return nil
 }
 
+// UpdateUser writes the profile columns only, passwords change through UpdatePassword.
+// The row must still carry user.Version, ErrVersionConflict is returned otherwise
 func (u *UserDataBaseInteraction) UpdateUser(user *user.UserRegister) error {
 //Ensure that the ID is set in the User Object
 	if user.ID == 0{
 		return fmt.Errorf("user ID is not set")
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return fmt.Errorf("failed to hash password %W", err)
-	}
-	user.Password = string(hashedPassword)
 //Use Model and specify the ID explicity 
-	result := u.DB.Model(&user).Where("id = ?", user.ID).Updates(user)
+	expected := user.Version
+	user.Version = expected + 1
+	result := u.DB.Model(user).Where("id = ? AND version = ?", user.ID, expected).
+		Select("name", "email", "phone", "verified_at", "version").
+		Updates(user)
 	if result.Error != nil {
+		user.Version = expected
 		return fmt.Errorf("updating the user: %w", result.Error)
 	}
+	if result.RowsAffected == 0 {
+		user.Version = expected
+		return ErrVersionConflict
+	}
 	return nil
 }
 
@@ -62,6 +73,66 @@ func (u *UserDataBaseInteraction) FindUserByName(username string) (*user.UserReg
 	return user, nil
 }
 
+func (u *UserDataBaseInteraction) FindUserByEmail(email string) (*user.UserRegister, error) {
+	var found user.UserRegister
+	if err := u.DB.Where("LOWER(email) = LOWER(?)", email).First(&found).Error; err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
+
+func (u *UserDataBaseInteraction) UpdatePassword(id uint, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return updatePassword(u.DB, id, hashedPassword)
+}
+
+// ResetPassword consumes the reset token and sets the password in one transaction,
+// a failed update leaves the token usable and ErrActionTokenUsed means it was spent
+func (u *UserDataBaseInteraction) ResetPassword(id, actionTokenID uint, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := NewActionTokenRepository(tx).ConsumeActionToken(actionTokenID); err != nil {
+			return err
+		}
+		return updatePassword(tx, id, hashedPassword)
+	})
+}
+
+func hashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password %w", err)
+	}
+	return string(hashedPassword), nil
+}
+
+func updatePassword(db *gorm.DB, id uint, hashedPassword string) error {
+	result := db.Model(&user.UserRegister{}).Where("id = ?", id).Update("password", hashedPassword)
+	if result.Error != nil {
+		return fmt.Errorf("updating the password: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no user found with ID: %d", id)
+	}
+	return nil
+}
+
+func (u *UserDataBaseInteraction) MarkEmailVerified(id uint) error {
+	result := u.DB.Model(&user.UserRegister{}).
+		Where("id = ? AND verified_at IS NULL", id).
+		Update("verified_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("marking email verified: %w", result.Error)
+	}
+	return nil
+}
+
 func (u *UserDataBaseInteraction) GetUserByID(id uint) (*user.UserRegister, error) {
 	var user user.UserRegister
 	if err := u.DB.First(&user, id).Error; err != nil {
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/internal/testdb"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestResetPasswordConsumesTokenWithUpdate(t *testing.T) {
+	db := testdb.Open(t, &user.UserRegister{}, &user.ActionToken{})
+	users := &UserDataBaseInteraction{DB: db}
+
+	reset := func() *user.ActionToken {
+		actionToken := &user.ActionToken{SubjectID: 1, Purpose: user.PurposePasswordReset, TokenHash: time.Now().String(), ExpiresAt: time.Now().Add(time.Hour)}
+		require.NoError(t, db.Create(actionToken).Error)
+		return actionToken
+	}
+	usedAt := func(actionToken *user.ActionToken) *time.Time {
+		var stored user.ActionToken
+		require.NoError(t, db.First(&stored, actionToken.ID).Error)
+		return stored.UsedAt
+	}
+
+	// A failing update leaves the token for another try
+	pending := reset()
+	assert.Error(t, users.ResetPassword(1, pending.ID, "new-password"))
+	assert.Nil(t, usedAt(pending))
+
+	owner := &user.UserRegister{UserName: "ann", Name: "Ann", Email: "ann@example.com", Phone: "1", Password: "old-password"}
+	require.NoError(t, users.CreateUser(owner))
+	require.NoError(t, users.ResetPassword(owner.ID, pending.ID, "new-password"))
+	assert.NotNil(t, usedAt(pending))
+	stored, err := users.GetUserByID(owner.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("new-password")))
+
+	// A spent token changes nothing
+	err = users.ResetPassword(owner.ID, pending.ID, "other-password")
+	assert.True(t, errors.Is(err, ErrActionTokenUsed))
+	stored, err = users.GetUserByID(owner.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("new-password")))
+}
@@ -0,0 +1,6 @@
+package repository
+
+import "errors"
+
+// ErrVersionConflict is returned when a row was updated after the caller read it
+var ErrVersionConflict = errors.New("record was modified by someone else, reload it and try again")
@@ -1,91 +1,361 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/pagination"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/token"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
 	"github.com/ratheeshkumar25/pkg/user/repository"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AdminUseCase interface {
-	RegisterAdmin(admin *user.AdminRegister) error
-	Login(login *user.AdminLogin) (*user.AdminRegister, error)
-	GetUseList(user string) (*[]user.UserRegister, error)
-	AddProduct(product *user.Product) error
-	GetProducts(productname string) (*[]user.Product, error)
+	RegisterAdmin(admin *user.AdminRegister, inviteToken string) error
+	InviteAdmin(actorID uint, request *user.AdminInviteRequest) (*user.AdminInvite, string, error)
+	BootstrapSuperAdmin(admin *user.AdminRegister) (bool, error)
+	Login(login *user.AdminLogin, clientIP string) (*user.AdminRegister, error)
+	UnlockAccount(actor, account, username string) error
+	GetAdminDetail(id uint) (*user.AdminRegister, error)
+	AssignRole(actorID, adminID uint, role string) error
+	GetUseList(query *user.UserListQuery) (*[]user.UserRegister, *pagination.Page, error)
+	AddProduct(actor string, product *user.Product) error
+	GetProducts(query *user.ProductListQuery) (*[]user.Product, *pagination.Page, error)
 	FindProduct(id uint) (*user.Product, error)
 	UpdateProduct(product *user.Product) error
+	PatchProduct(id uint, patch *user.ProductPatch) (*user.Product, error)
 	DeleteProduct(id int) error
 }
 
+var (
+	ErrInvalidRole   = errors.New("role cannot be assigned to an admin account")
+	ErrOwnRole       = errors.New("admins cannot change their own role")
+	ErrInvalidInvite = errors.New("a valid admin invite is required")
+	ErrUsernameTaken = repository.ErrUsernameTaken
+
+	ErrInvalidPriceRange   = errors.New("min_price cannot be greater than max_price")
+	ErrPriceFilterCurrency = errors.New("currency is required with min_price or max_price")
+	ErrInvalidPriceFilter  = errors.New("invalid price filter")
+	ErrInvalidPrice        = errors.New("price needs a non-negative amount and a supported currency")
+)
+
 type adminInteraction struct {
-	adminRepo repository.AdminRepository
+	adminRepo    repository.AdminRepository
+	categoryRepo repository.CategoryRepository
+	loginGuard   LoginGuard
+	inviteTTL    time.Duration
+}
+
+func (admn *adminInteraction) RegisterAdmin(admin *user.AdminRegister, inviteToken string) error {
+	if inviteToken == "" {
+		return ErrInvalidInvite
+	}
+	invite, err := admn.adminRepo.FindInviteByHash(token.HashOpaqueToken(inviteToken))
+	if err != nil {
+		return ErrInvalidInvite
+	}
+	if invite.UsedAt != nil || time.Now().After(invite.ExpiresAt) {
+		return ErrInvalidInvite
+	}
+	if !strings.EqualFold(invite.Email, admin.Email) {
+		return ErrInvalidInvite
+	}
+
+	//**The role comes from the invite, never from the signup payload
+	admin.Role = invite.Role
+	if err := admn.adminRepo.CreateAdminWithInvite(admin, invite.ID); err != nil {
+		if errors.Is(err, repository.ErrInviteUsed) {
+			return ErrInvalidInvite
+		}
+		return err
+	}
+	return nil
 }
 
-func (admn *adminInteraction) RegisterAdmin(admin *user.AdminRegister) error {
-	return admn.adminRepo.CreateAdmin(admin)
+func (admn *adminInteraction) InviteAdmin(actorID uint, request *user.AdminInviteRequest) (*user.AdminInvite, string, error) {
+	if !rbac.IsStaffRole(request.Role) {
+		return nil, "", ErrInvalidRole
+	}
+
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	invite := &user.AdminInvite{
+		Email:     request.Email,
+		Role:      request.Role,
+		TokenHash: hash,
+		InvitedBy: actorID,
+		ExpiresAt: time.Now().Add(admn.inviteTTL),
+	}
+	if err := admn.adminRepo.CreateInvite(invite); err != nil {
+		return nil, "", fmt.Errorf("failed to create invite: %w", err)
+	}
+	return invite, plain, nil
 }
 
-func (admn *adminInteraction) Login(login *user.AdminLogin) (*user.AdminRegister, error) {
-	admin, err := admn.adminRepo.FindAdmin(login.Username)
+// BootstrapSuperAdmin creates the first super-admin, it does nothing once any admin exists
+func (admn *adminInteraction) BootstrapSuperAdmin(admin *user.AdminRegister) (bool, error) {
+	count, err := admn.adminRepo.CountAdmins()
 	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	admin.Role = rbac.RoleSuperAdmin
+	if err := admn.adminRepo.CreateAdmin(admin); err != nil {
+		return false, fmt.Errorf("failed to create bootstrap admin: %w", err)
+	}
+	return true, nil
+}
+
+func (admn *adminInteraction) Login(login *user.AdminLogin, clientIP string) (*user.AdminRegister, error) {
+	if err := admn.loginGuard.Check(user.AccountAdmin, login.Username, clientIP); err != nil {
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(login.Password))
+	admin, err := admn.adminRepo.FindAdmin(login.Username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(login.Password))
+		if err != nil {
+			err = fmt.Errorf("invalid password: %w", err)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid password: %w", err)
+		if guardErr := admn.loginGuard.Failed(user.AccountAdmin, login.Username, clientIP); guardErr != nil {
+			return nil, guardErr
+		}
+		return nil, err
+	}
+
+	if err := admn.loginGuard.Succeeded(user.AccountAdmin, login.Username, clientIP); err != nil {
+		return nil, err
 	}
 	return admin, nil
 }
 
-func (admn *adminInteraction) GetUseList(user string) (*[]user.UserRegister, error) {
-	users, err := admn.adminRepo.GetUserList(user)
+func (admn *adminInteraction) UnlockAccount(actor, account, username string) error {
+	return admn.loginGuard.Unlock(actor, account, username)
+}
+
+func (admn *adminInteraction) GetAdminDetail(id uint) (*user.AdminRegister, error) {
+	return admn.adminRepo.GetAdminByID(id)
+}
+
+func (admn *adminInteraction) AssignRole(actorID, adminID uint, role string) error {
+	if !rbac.IsStaffRole(role) {
+		return ErrInvalidRole
+	}
+	if actorID == adminID {
+		return ErrOwnRole
+	}
+	return admn.adminRepo.UpdateAdminRole(adminID, role)
+}
+
+func (admn *adminInteraction) GetUseList(query *user.UserListQuery) (*[]user.UserRegister, *pagination.Page, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	users, page, err := admn.adminRepo.GetUserList(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user list: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user list: %w", err)
 	}
-	return users, nil
+	return users, page, nil
 }
 
-func (admn *adminInteraction) AddProduct(product *user.Product) error {
-	err := admn.adminRepo.AddProduct(product)
+func (admn *adminInteraction) AddProduct(actor string, product *user.Product) error {
+	if product.Quantity < 0 {
+		return ErrInvalidStockQuantity
+	}
+	if err := product.Price.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPrice, err)
+	}
+	if err := admn.checkCategory(product.CategoryID); err != nil {
+		return err
+	}
+	err := admn.adminRepo.AddProduct(product, actor)
 	if err != nil {
 		return fmt.Errorf("failed to add product: %w", err)
 	}
 	return nil
 }
 
-func (admn *adminInteraction) GetProducts(productname string) (*[]user.Product, error) {
-	products, err := admn.adminRepo.GetProducts(productname)
+func (admn *adminInteraction) GetProducts(query *user.ProductListQuery) (*[]user.Product, *pagination.Page, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	if err := priceBounds(query); err != nil {
+		return nil, nil, err
+	}
+	products, page, err := admn.adminRepo.GetProducts(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get products: %w", err)
+		return nil, nil, fmt.Errorf("failed to get products: %w", err)
 	}
-	return products, nil
+	return products, page, nil
+}
+
+// priceBounds converts the decimal price filters to minor units of the query's currency
+func priceBounds(query *user.ProductListQuery) error {
+	if query.MinPrice == nil && query.MaxPrice == nil {
+		return nil
+	}
+	if query.Currency == "" {
+		return ErrPriceFilterCurrency
+	}
+	query.Currency = strings.ToUpper(query.Currency)
+
+	for _, bound := range []struct {
+		value  *string
+		amount **int64
+	}{{query.MinPrice, &query.MinAmount}, {query.MaxPrice, &query.MaxAmount}} {
+		if bound.value == nil {
+			continue
+		}
+		price, err := money.Parse(*bound.value, query.Currency)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPriceFilter, err)
+		}
+		*bound.amount = &price.Amount
+	}
+	if query.MinAmount != nil && query.MaxAmount != nil && *query.MinAmount > *query.MaxAmount {
+		return ErrInvalidPriceRange
+	}
+	return nil
 }
 
 func (admn *adminInteraction) FindProduct(id uint) (*user.Product, error) {
 	product, err := admn.adminRepo.FindProduct(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
 		return nil, fmt.Errorf("failed to find product: %w", err)
 	}
 	return product, nil
 }
 
 func (admn *adminInteraction) UpdateProduct(product *user.Product) error {
+	if err := product.Price.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPrice, err)
+	}
+	if err := admn.checkCategory(product.CategoryID); err != nil {
+		return err
+	}
 	return admn.adminRepo.UpdateProduct(product)
 }
 
-func (admn *adminInteraction) DeleteProduct(id int) error {
-	return admn.adminRepo.DeleteProduct(id)
+// PatchProduct applies a merge patch to a product, only the members present in
+// the patch are validated and written back
+func (admn *adminInteraction) PatchProduct(id uint, patch *user.ProductPatch) (*user.Product, error) {
+	if patch.Quantity.Set {
+		return nil, fmt.Errorf("%w: quantity only changes through stock movements", mergepatch.ErrInvalidPatch)
+	}
+
+	product, err := admn.adminRepo.FindProduct(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, fmt.Errorf("failed to find product: %w", err)
+	}
+	if patch.Version != 0 {
+		product.Version = patch.Version
+	}
+
+	if patch.ProductName.Set {
+		if patch.ProductName.Null || strings.TrimSpace(patch.ProductName.Value) == "" {
+			return nil, fmt.Errorf("%w: product_name cannot be empty", mergepatch.ErrInvalidPatch)
+		}
+		product.ProductName = patch.ProductName.Value
+	}
+	//**null clears the description
+	patch.Description.Apply(&product.Description)
+
+	if patch.Price.Set {
+		price, err := patchPrice(product.Price, patch.Price)
+		if err != nil {
+			return nil, err
+		}
+		product.Price = price
+	}
+
+	if patch.CategoryID.Set {
+		if patch.CategoryID.Null {
+			return nil, fmt.Errorf("%w: category_id cannot be null", mergepatch.ErrInvalidPatch)
+		}
+		if err := admn.checkCategory(patch.CategoryID.Value); err != nil {
+			return nil, err
+		}
+		product.CategoryID = patch.CategoryID.Value
+	}
+
+	//**null turns the low stock alert off
+	if patch.LowStockThreshold.Set && patch.LowStockThreshold.Value < 0 {
+		return nil, fmt.Errorf("%w: low_stock_threshold cannot be negative", mergepatch.ErrInvalidPatch)
+	}
+	patch.LowStockThreshold.Apply(&product.LowStockThreshold)
+
+	if err := admn.adminRepo.UpdateProduct(product); err != nil {
+		return nil, err
+	}
+	return product, nil
 }
 
+// patchPrice merges amount and currency into the current price, an amount left
+// out is kept as a decimal and read again in the new currency
+func patchPrice(current money.Money, patch mergepatch.Field[user.PricePatch]) (money.Money, error) {
+	if patch.Null {
+		return money.Money{}, fmt.Errorf("%w: price cannot be null", mergepatch.ErrInvalidPatch)
+	}
+	amount, currency := current.Decimal(), current.Currency
+	if member := patch.Value.Amount; member.Set {
+		if member.Null {
+			return money.Money{}, fmt.Errorf("%w: price.amount cannot be null", mergepatch.ErrInvalidPatch)
+		}
+		amount = member.Value.String()
+	}
+	if member := patch.Value.Currency; member.Set {
+		if member.Null {
+			return money.Money{}, fmt.Errorf("%w: price.currency cannot be null", mergepatch.ErrInvalidPatch)
+		}
+		currency = strings.ToUpper(member.Value)
+	}
 
+	price, err := money.Parse(amount, currency)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: %v", ErrInvalidPrice, err)
+	}
+	if err := price.Validate(); err != nil {
+		return money.Money{}, fmt.Errorf("%w: %v", ErrInvalidPrice, err)
+	}
+	return price, nil
+}
 
+// checkCategory backs the products foreign key, which cannot see soft deleted categories
+func (admn *adminInteraction) checkCategory(id uint) error {
+	if _, err := admn.categoryRepo.GetCategory(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+		}
+		return err
+	}
+	return nil
+}
 
+func (admn *adminInteraction) DeleteProduct(id int) error {
+	return admn.adminRepo.DeleteProduct(id)
+}
 
-func NewAdminUseCase(adminRepo repository.AdminRepository) AdminUseCase {
+func NewAdminUseCase(adminRepo repository.AdminRepository, categoryRepo repository.CategoryRepository, loginGuard LoginGuard, inviteTTL time.Duration) AdminUseCase {
 	return &adminInteraction{
-		adminRepo: adminRepo,
+		adminRepo:    adminRepo,
+		categoryRepo: categoryRepo,
+		loginGuard:   loginGuard,
+		inviteTTL:    inviteTTL,
 	}
 }
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/money"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// productStubRepository serves one product, the embedded interface panics for anything else
+type productStubRepository struct {
+	repository.AdminRepository
+	product *user.Product
+	saved   *user.Product
+}
+
+func (s *productStubRepository) FindProduct(id uint) (*user.Product, error) {
+	if s.product == nil || s.product.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *s.product
+	return &copied, nil
+}
+
+func (s *productStubRepository) UpdateProduct(product *user.Product) error {
+	if product.Version != s.product.Version {
+		return repository.ErrVersionConflict
+	}
+	product.Version++
+	saved := *product
+	s.saved = &saved
+	return nil
+}
+
+// inviteStubRepository keeps admins and invites in memory, the embedded interface panics for anything else
+type inviteStubRepository struct {
+	repository.AdminRepository
+	admins  []user.AdminRegister
+	invites map[uint]*user.AdminInvite
+}
+
+func newInviteStubRepository() *inviteStubRepository {
+	return &inviteStubRepository{invites: map[uint]*user.AdminInvite{}}
+}
+
+func (s *inviteStubRepository) CountAdmins() (int64, error) {
+	return int64(len(s.admins)), nil
+}
+
+func (s *inviteStubRepository) CreateAdmin(admin *user.AdminRegister) error {
+	admin.ID = uint(len(s.admins) + 1)
+	s.admins = append(s.admins, *admin)
+	return nil
+}
+
+func (s *inviteStubRepository) CreateInvite(invite *user.AdminInvite) error {
+	invite.ID = uint(len(s.invites) + 1)
+	copied := *invite
+	s.invites[invite.ID] = &copied
+	return nil
+}
+
+func (s *inviteStubRepository) FindInviteByHash(tokenHash string) (*user.AdminInvite, error) {
+	for _, invite := range s.invites {
+		if invite.TokenHash == tokenHash {
+			copied := *invite
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *inviteStubRepository) CreateAdminWithInvite(admin *user.AdminRegister, inviteID uint) error {
+	invite := s.invites[inviteID]
+	if invite.UsedAt != nil {
+		return repository.ErrInviteUsed
+	}
+	for _, existing := range s.admins {
+		if existing.Username == admin.Username {
+			return repository.ErrUsernameTaken
+		}
+	}
+	now := time.Now()
+	invite.UsedAt = &now
+	return s.CreateAdmin(admin)
+}
+
+func TestRegisterAdminWithInvite(t *testing.T) {
+	stub := newInviteStubRepository()
+	admins := NewAdminUseCase(stub, nil, nil, time.Hour)
+
+	_, _, err := admins.InviteAdmin(1, &user.AdminInviteRequest{Email: "new@example.com", Role: rbac.RoleCustomer})
+	assert.True(t, errors.Is(err, ErrInvalidRole))
+	assert.Empty(t, stub.invites)
+
+	invite, plain, err := admins.InviteAdmin(1, &user.AdminInviteRequest{Email: "new@example.com", Role: rbac.RoleSupport})
+	require.NoError(t, err)
+	assert.Equal(t, token.HashOpaqueToken(plain), invite.TokenHash)
+	assert.Equal(t, uint(1), invite.InvitedBy)
+
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Email: "new@example.com"}, ""), ErrInvalidInvite))
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Email: "new@example.com"}, "unknown"), ErrInvalidInvite))
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Email: "other@example.com"}, plain), ErrInvalidInvite))
+	assert.Empty(t, stub.admins)
+
+	// An invitee cannot take the name of an existing admin and keeps the invite
+	stub.admins = append(stub.admins, user.AdminRegister{Username: "root"})
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Username: "root", Email: "new@example.com"}, plain), ErrUsernameTaken))
+	assert.Nil(t, stub.invites[1].UsedAt)
+	stub.admins = nil
+
+	// The role comes from the invite, not from the signup payload
+	require.NoError(t, admins.RegisterAdmin(&user.AdminRegister{Username: "new", Email: "New@Example.com", Role: rbac.RoleSuperAdmin}, plain))
+	require.Len(t, stub.admins, 1)
+	assert.Equal(t, rbac.RoleSupport, stub.admins[0].Role)
+
+	// An invite signs up one admin only
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Email: "new@example.com"}, plain), ErrInvalidInvite))
+
+	_, expired, err := admins.InviteAdmin(1, &user.AdminInviteRequest{Email: "late@example.com", Role: rbac.RoleCatalogManager})
+	require.NoError(t, err)
+	stub.invites[2].ExpiresAt = time.Now().Add(-time.Minute)
+	assert.True(t, errors.Is(admins.RegisterAdmin(&user.AdminRegister{Email: "late@example.com"}, expired), ErrInvalidInvite))
+	assert.Len(t, stub.admins, 1)
+}
+
+func TestBootstrapSuperAdminOnlyOnce(t *testing.T) {
+	stub := newInviteStubRepository()
+	admins := NewAdminUseCase(stub, nil, nil, time.Hour)
+
+	created, err := admins.BootstrapSuperAdmin(&user.AdminRegister{Username: "root", Role: rbac.RoleSupport})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, rbac.RoleSuperAdmin, stub.admins[0].Role)
+
+	created, err = admins.BootstrapSuperAdmin(&user.AdminRegister{Username: "second"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Len(t, stub.admins, 1)
+}
+
+func TestPriceBounds(t *testing.T) {
+	min, max := "10", "25.50"
+	query := &user.ProductListQuery{Currency: "usd", MinPrice: &min, MaxPrice: &max}
+	require.NoError(t, priceBounds(query))
+	assert.Equal(t, "USD", query.Currency)
+	assert.Equal(t, int64(1000), *query.MinAmount)
+	assert.Equal(t, int64(2550), *query.MaxAmount)
+
+	// Bounds need a currency to know their minor units
+	assert.True(t, errors.Is(priceBounds(&user.ProductListQuery{MinPrice: &min}), ErrPriceFilterCurrency))
+
+	bad := "10.001"
+	assert.True(t, errors.Is(priceBounds(&user.ProductListQuery{Currency: "USD", MinPrice: &bad}), ErrInvalidPriceFilter))
+
+	min, max = "30", "5"
+	assert.True(t, errors.Is(priceBounds(&user.ProductListQuery{Currency: "USD", MinPrice: &min, MaxPrice: &max}), ErrInvalidPriceRange))
+}
+
+func TestPatchProduct(t *testing.T) {
+	categories := newMemoryCategoryRepository()
+	require.NoError(t, categories.CreateCategory(&user.Category{Name: "Shoes"}))
+	stub := &productStubRepository{product: &user.Product{
+		Model:             gorm.Model{ID: 1},
+		ProductName:       "Shoe",
+		Description:       "Leather",
+		Price:             money.Money{Amount: 1999, Currency: "USD"},
+		CategoryID:        1,
+		LowStockThreshold: 5,
+		Version:           2,
+	}}
+	admins := NewAdminUseCase(stub, categories, nil, 0)
+
+	// Absent members are kept, null clears and a price member merges into the current price
+	patched, err := admins.PatchProduct(1, &user.ProductPatch{
+		Description:       mergepatch.Null[string](),
+		LowStockThreshold: mergepatch.Null[int](),
+		Price:             mergepatch.Value(user.PricePatch{Amount: mergepatch.Value(json.Number("24.50"))}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Shoe", patched.ProductName)
+	assert.Equal(t, "", patched.Description)
+	assert.Equal(t, 0, patched.LowStockThreshold)
+	assert.Equal(t, money.Money{Amount: 2450, Currency: "USD"}, patched.Price)
+	assert.Equal(t, uint(3), stub.saved.Version)
+
+	// Switching currency re-reads the kept amount, JPY has no minor units
+	_, err = admins.PatchProduct(1, &user.ProductPatch{Price: mergepatch.Value(user.PricePatch{Currency: mergepatch.Value("jpy")})})
+	assert.True(t, errors.Is(err, ErrInvalidPrice))
+
+	for name, patch := range map[string]*user.ProductPatch{
+		"null name":      {ProductName: mergepatch.Null[string]()},
+		"blank name":     {ProductName: mergepatch.Value(" ")},
+		"null category":  {CategoryID: mergepatch.Null[uint]()},
+		"null price":     {Price: mergepatch.Null[user.PricePatch]()},
+		"quantity":       {Quantity: mergepatch.Value(json.RawMessage("3"))},
+		"negative alert": {LowStockThreshold: mergepatch.Value(-1)},
+	} {
+		_, err := admins.PatchProduct(1, patch)
+		assert.True(t, errors.Is(err, mergepatch.ErrInvalidPatch), name)
+	}
+
+	_, err = admins.PatchProduct(1, &user.ProductPatch{CategoryID: mergepatch.Value(uint(9))})
+	assert.True(t, errors.Is(err, ErrCategoryNotFound))
+
+	_, err = admins.PatchProduct(2, &user.ProductPatch{})
+	assert.True(t, errors.Is(err, ErrProductNotFound))
+
+	// A patch based on an older version does not overwrite newer data
+	_, err = admins.PatchProduct(1, &user.ProductPatch{ProductName: mergepatch.Value("Boot"), Version: 1})
+	assert.True(t, errors.Is(err, repository.ErrVersionConflict))
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryCycle    = errors.New("a category cannot be moved below itself or its subcategories")
+	ErrCategoryInUse    = errors.New("category still has subcategories or products")
+)
+
+type CategoryUseCase interface {
+	CreateCategory(request *user.CategoryRequest) (*user.Category, error)
+	GetCategory(id uint) (*user.Category, []user.Category, error)
+	ListCategories() ([]user.Category, error)
+	UpdateCategory(id uint, request *user.CategoryRequest) (*user.Category, error)
+	DeleteCategory(id uint) error
+}
+
+type categoryInteraction struct {
+	categoryRepo repository.CategoryRepository
+}
+
+func (c *categoryInteraction) CreateCategory(request *user.CategoryRequest) (*user.Category, error) {
+	if request.ParentID != nil {
+		if _, err := c.find(*request.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	category := &user.Category{Name: request.Name, Description: request.Description, ParentID: request.ParentID}
+	if err := c.categoryRepo.CreateCategory(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// GetCategory returns the category with its direct subcategories
+func (c *categoryInteraction) GetCategory(id uint) (*user.Category, []user.Category, error) {
+	category, err := c.find(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	children, err := c.categoryRepo.ListChildren(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return category, children, nil
+}
+
+func (c *categoryInteraction) ListCategories() ([]user.Category, error) {
+	return c.categoryRepo.ListCategories()
+}
+
+func (c *categoryInteraction) UpdateCategory(id uint, request *user.CategoryRequest) (*user.Category, error) {
+	category, err := c.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ParentID != nil {
+		if _, err := c.find(*request.ParentID); err != nil {
+			return nil, err
+		}
+		//**Moving a category under its own subtree would detach the whole branch
+		subtree, err := c.categoryRepo.SubtreeIDs(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, descendant := range subtree {
+			if descendant == *request.ParentID {
+				return nil, ErrCategoryCycle
+			}
+		}
+	}
+
+	category.Name = request.Name
+	category.Description = request.Description
+	category.ParentID = request.ParentID
+	if err := c.categoryRepo.UpdateCategory(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory refuses categories that still hold subcategories or products,
+// trashed products count so that they can still be restored
+func (c *categoryInteraction) DeleteCategory(id uint) error {
+	if _, err := c.find(id); err != nil {
+		return err
+	}
+	children, err := c.categoryRepo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	products, err := c.categoryRepo.CountProducts(id)
+	if err != nil {
+		return err
+	}
+	if children > 0 || products > 0 {
+		return ErrCategoryInUse
+	}
+	return c.categoryRepo.DeleteCategory(id)
+}
+
+func (c *categoryInteraction) find(id uint) (*user.Category, error) {
+	category, err := c.categoryRepo.GetCategory(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrCategoryNotFound, id)
+	}
+	return category, err
+}
+
+func NewCategoryUseCase(categoryRepo repository.CategoryRepository) CategoryUseCase {
+	return &categoryInteraction{
+		categoryRepo: categoryRepo,
+	}
+}
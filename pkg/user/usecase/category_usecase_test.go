@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memoryCategoryRepository keeps categories in a map for the use case tests
+type memoryCategoryRepository struct {
+	categories map[uint]*user.Category
+	products   map[uint]int64
+	nextID     uint
+}
+
+func newMemoryCategoryRepository() *memoryCategoryRepository {
+	return &memoryCategoryRepository{categories: map[uint]*user.Category{}, products: map[uint]int64{}}
+}
+
+func (m *memoryCategoryRepository) CreateCategory(category *user.Category) error {
+	m.nextID++
+	category.ID = m.nextID
+	stored := *category
+	m.categories[category.ID] = &stored
+	return nil
+}
+
+func (m *memoryCategoryRepository) GetCategory(id uint) (*user.Category, error) {
+	category, ok := m.categories[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *category
+	return &copied, nil
+}
+
+func (m *memoryCategoryRepository) ListCategories() ([]user.Category, error) {
+	var categories []user.Category
+	for _, category := range m.categories {
+		categories = append(categories, *category)
+	}
+	return categories, nil
+}
+
+func (m *memoryCategoryRepository) ListChildren(parentID uint) ([]user.Category, error) {
+	var children []user.Category
+	for _, category := range m.categories {
+		if category.ParentID != nil && *category.ParentID == parentID {
+			children = append(children, *category)
+		}
+	}
+	return children, nil
+}
+
+func (m *memoryCategoryRepository) SubtreeIDs(id uint) ([]uint, error) {
+	ids := []uint{id}
+	children, _ := m.ListChildren(id)
+	for _, child := range children {
+		below, _ := m.SubtreeIDs(child.ID)
+		ids = append(ids, below...)
+	}
+	return ids, nil
+}
+
+func (m *memoryCategoryRepository) UpdateCategory(category *user.Category) error {
+	stored := *category
+	m.categories[category.ID] = &stored
+	return nil
+}
+
+func (m *memoryCategoryRepository) CountChildren(id uint) (int64, error) {
+	children, _ := m.ListChildren(id)
+	return int64(len(children)), nil
+}
+
+func (m *memoryCategoryRepository) CountProducts(id uint) (int64, error) {
+	return m.products[id], nil
+}
+
+func (m *memoryCategoryRepository) DeleteCategory(id uint) error {
+	delete(m.categories, id)
+	return nil
+}
+
+func TestCategoryHierarchy(t *testing.T) {
+	repo := newMemoryCategoryRepository()
+	categories := NewCategoryUseCase(repo)
+
+	clothing, err := categories.CreateCategory(&user.CategoryRequest{Name: "Clothing"})
+	require.NoError(t, err)
+	shoes, err := categories.CreateCategory(&user.CategoryRequest{Name: "Shoes", ParentID: &clothing.ID})
+	require.NoError(t, err)
+	running, err := categories.CreateCategory(&user.CategoryRequest{Name: "Running", ParentID: &shoes.ID})
+	require.NoError(t, err)
+
+	missing := uint(99)
+	_, err = categories.CreateCategory(&user.CategoryRequest{Name: "Orphan", ParentID: &missing})
+	assert.True(t, errors.Is(err, ErrCategoryNotFound))
+
+	// A category cannot move below itself or one of its descendants
+	_, err = categories.UpdateCategory(clothing.ID, &user.CategoryRequest{Name: "Clothing", ParentID: &clothing.ID})
+	assert.True(t, errors.Is(err, ErrCategoryCycle))
+	_, err = categories.UpdateCategory(clothing.ID, &user.CategoryRequest{Name: "Clothing", ParentID: &running.ID})
+	assert.True(t, errors.Is(err, ErrCategoryCycle))
+
+	moved, err := categories.UpdateCategory(running.ID, &user.CategoryRequest{Name: "Running Shoes", ParentID: &clothing.ID})
+	require.NoError(t, err)
+	assert.Equal(t, clothing.ID, *moved.ParentID)
+
+	_, children, err := categories.GetCategory(clothing.ID)
+	require.NoError(t, err)
+	assert.Len(t, children, 2)
+
+	// Categories holding subcategories or products stay
+	assert.True(t, errors.Is(categories.DeleteCategory(clothing.ID), ErrCategoryInUse))
+	repo.products[shoes.ID] = 3
+	assert.True(t, errors.Is(categories.DeleteCategory(shoes.ID), ErrCategoryInUse))
+	assert.NoError(t, categories.DeleteCategory(running.ID))
+	assert.True(t, errors.Is(categories.DeleteCategory(running.ID), ErrCategoryNotFound))
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductNotFound      = errors.New("product not found")
+	ErrInvalidStockQuantity = errors.New("stock quantity must be positive, use an adjustment to correct stock")
+)
+
+type InventoryUseCase interface {
+	RecordMovement(actor string, productID uint, request *user.StockMovementRequest) (*user.StockMovement, *user.Product, error)
+	StockHistory(productID uint, query *user.StockHistoryQuery) (*user.StockHistory, error)
+	LowStock() ([]user.Product, error)
+}
+
+type inventoryInteraction struct {
+	inventoryRepo repository.InventoryRepository
+	adminRepo     repository.AdminRepository
+}
+
+func (i *inventoryInteraction) RecordMovement(actor string, productID uint, request *user.StockMovementRequest) (*user.StockMovement, *user.Product, error) {
+	delta, err := movementDelta(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	movement := &user.StockMovement{
+		ProductID: productID,
+		Type:      request.Type,
+		Quantity:  delta,
+		Reason:    request.Reason,
+		Actor:     actor,
+	}
+	product, err := i.inventoryRepo.RecordMovement(movement)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//**Only report the movement that crossed the threshold, not every one below it
+	if product.LowStock() && product.Quantity-delta > product.LowStockThreshold {
+		log.Printf("product %d %q is low on stock: %d left, threshold %d", product.ID, product.ProductName, product.Quantity, product.LowStockThreshold)
+	}
+	return movement, product, nil
+}
+
+// movementDelta turns a request into the signed change it makes to the stock
+func movementDelta(request *user.StockMovementRequest) (int, error) {
+	switch request.Type {
+	case user.MovementAdjustment:
+		if request.Quantity == 0 {
+			return 0, ErrInvalidStockQuantity
+		}
+		return request.Quantity, nil
+	case user.MovementReceipt, user.MovementReturn:
+		if request.Quantity <= 0 {
+			return 0, ErrInvalidStockQuantity
+		}
+		return request.Quantity, nil
+	case user.MovementSale:
+		if request.Quantity <= 0 {
+			return 0, ErrInvalidStockQuantity
+		}
+		return -request.Quantity, nil
+	}
+	return 0, fmt.Errorf("%w: unknown movement type %q", ErrInvalidStockQuantity, request.Type)
+}
+
+func (i *inventoryInteraction) StockHistory(productID uint, query *user.StockHistoryQuery) (*user.StockHistory, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	product, err := i.adminRepo.FindProduct(productID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	movements, total, err := i.inventoryRepo.ListMovements(productID, query.Page, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := i.inventoryRepo.LedgerBalance(productID)
+	if err != nil {
+		return nil, err
+	}
+	return &user.StockHistory{Product: product, Movements: movements, Total: total, LedgerBalance: balance}, nil
+}
+
+func (i *inventoryInteraction) LowStock() ([]user.Product, error) {
+	return i.inventoryRepo.ListLowStock()
+}
+
+func NewInventoryUseCase(inventoryRepo repository.InventoryRepository, adminRepo repository.AdminRepository) InventoryUseCase {
+	return &inventoryInteraction{
+		inventoryRepo: inventoryRepo,
+		adminRepo:     adminRepo,
+	}
+}
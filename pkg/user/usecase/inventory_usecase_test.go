@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovementDelta(t *testing.T) {
+	cases := []struct {
+		request user.StockMovementRequest
+		delta   int
+	}{
+		{user.StockMovementRequest{Type: user.MovementReceipt, Quantity: 5}, 5},
+		{user.StockMovementRequest{Type: user.MovementReturn, Quantity: 1}, 1},
+		{user.StockMovementRequest{Type: user.MovementSale, Quantity: 3}, -3},
+		{user.StockMovementRequest{Type: user.MovementAdjustment, Quantity: -2}, -2},
+		{user.StockMovementRequest{Type: user.MovementAdjustment, Quantity: 4}, 4},
+	}
+	for _, tc := range cases {
+		delta, err := movementDelta(&tc.request)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.delta, delta, tc.request.Type)
+	}
+
+	// Only adjustments may be negative, and nothing may be zero
+	for _, request := range []user.StockMovementRequest{
+		{Type: user.MovementSale, Quantity: -3},
+		{Type: user.MovementReceipt, Quantity: 0},
+		{Type: user.MovementAdjustment, Quantity: 0},
+		{Type: "theft", Quantity: 1},
+	} {
+		_, err := movementDelta(&request)
+		assert.True(t, errors.Is(err, ErrInvalidStockQuantity), request.Type)
+	}
+}
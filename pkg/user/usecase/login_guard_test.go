@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/audit"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingAuditLogger keeps events in memory for assertions
+type recordingAuditLogger struct {
+	events []audit.Event
+}
+
+func (r *recordingAuditLogger) Record(event audit.Event) {
+	r.events = append(r.events, event)
+}
+
+func newTestLoginGuard() (*loginGuard, *recordingAuditLogger, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	auditLogger := &recordingAuditLogger{}
+	guard := NewLoginGuard(repository.NewMemoryLoginAttemptStore(), auditLogger, LockoutConfig{
+		MaxAccountFailures: 3,
+		MaxIPFailures:      10,
+		FailureWindow:      15 * time.Minute,
+		BaseLockout:        time.Minute,
+		MaxLockout:         10 * time.Minute,
+	}).(*loginGuard)
+	guard.now = func() time.Time { return now }
+	return guard, auditLogger, &now
+}
+
+func TestLoginGuardLocksAccountAfterFailures(t *testing.T) {
+	guard, auditLogger, now := newTestLoginGuard()
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, guard.Check("user", "alice", "10.0.0.1"))
+		assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	}
+	assert.NoError(t, guard.Check("user", "alice", "10.0.0.1"))
+	assert.Empty(t, auditLogger.events)
+
+	// The third failure reaches the threshold and locks for the base lockout
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	var locked *LockedError
+	assert.True(t, errors.As(guard.Check("user", "Alice", "10.0.0.2"), &locked))
+	assert.Equal(t, time.Minute, locked.RetryAfter)
+	assert.Len(t, auditLogger.events, 1)
+	assert.Equal(t, audit.EventAccountLocked, auditLogger.events[0].Type)
+
+	// Other accounts are unaffected
+	assert.NoError(t, guard.Check("user", "bob", "10.0.0.2"))
+
+	// Failing again after the lock expired doubles the lockout
+	*now = now.Add(2 * time.Minute)
+	assert.NoError(t, guard.Check("user", "alice", "10.0.0.1"))
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.True(t, errors.As(guard.Check("user", "alice", "10.0.0.1"), &locked))
+	assert.Equal(t, 2*time.Minute, locked.RetryAfter)
+}
+
+func TestLoginGuardLockoutIsCapped(t *testing.T) {
+	guard, _, _ := newTestLoginGuard()
+
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, guard.Failed("admin", "root", "10.0.0.1"))
+	}
+	var locked *LockedError
+	assert.True(t, errors.As(guard.Check("admin", "root", "10.0.0.9"), &locked))
+	assert.Equal(t, 10*time.Minute, locked.RetryAfter)
+}
+
+func TestLoginGuardThrottlesClientIP(t *testing.T) {
+	guard, _, _ := newTestLoginGuard()
+
+	// Spread over many accounts so no single account locks
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, guard.Failed("user", string(rune('a'+i)), "10.0.0.1"))
+	}
+	var locked *LockedError
+	assert.True(t, errors.As(guard.Check("user", "fresh", "10.0.0.1"), &locked))
+	assert.NoError(t, guard.Check("user", "fresh", "10.0.0.2"))
+}
+
+func TestLoginGuardSuccessAndUnlock(t *testing.T) {
+	guard, auditLogger, _ := newTestLoginGuard()
+
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.NoError(t, guard.Succeeded("user", "alice", "10.0.0.1"))
+
+	// The counter restarted, two more failures do not lock
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.NoError(t, guard.Check("user", "alice", "10.0.0.1"))
+
+	assert.NoError(t, guard.Failed("user", "alice", "10.0.0.1"))
+	assert.Error(t, guard.Check("user", "alice", "10.0.0.1"))
+
+	assert.NoError(t, guard.Unlock("root", "user", "alice"))
+	assert.NoError(t, guard.Check("user", "alice", "10.0.0.1"))
+	assert.Equal(t, audit.EventAccountUnlocked, auditLogger.events[len(auditLogger.events)-1].Type)
+}
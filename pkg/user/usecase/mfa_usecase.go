@@ -0,0 +1,277 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/token"
+	"github.com/ratheeshkumar25/pkg/totp"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrMFANotPending       = errors.New("no pending two-factor enrollment, start again")
+	ErrMFAMandatory        = errors.New("two-factor authentication is mandatory for this account")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge = errors.New("two-factor challenge is invalid or expired")
+)
+
+// MFAConfig holds the tunables of two-factor authentication
+type MFAConfig struct {
+	Issuer            string
+	ChallengeTTL      time.Duration
+	MaxAttempts       int
+	RecoveryCodeCount int
+	RequireForAdmins  bool
+}
+
+type MFAUseCase interface {
+	Enroll(subjectID uint, account, label string) (*user.MFAEnrollment, error)
+	ConfirmEnrollment(subjectID uint, account, code string) ([]string, error)
+	Disable(subjectID uint, account, code string) error
+	RegenerateRecoveryCodes(subjectID uint, account, code string) ([]string, error)
+	Enabled(subjectID uint, account string) (bool, error)
+	Required(account string) bool
+	StartChallenge(pending *user.MFAChallenge) (string, error)
+	VerifyChallenge(challengeToken, code, clientIP string) (*user.MFAChallenge, error)
+}
+
+type mfaInteraction struct {
+	mfaRepo    repository.MFARepository
+	loginGuard LoginGuard
+	config     MFAConfig
+	now        func() time.Time
+}
+
+func (m *mfaInteraction) Enroll(subjectID uint, account, label string) (*user.MFAEnrollment, error) {
+	factor, err := m.findFactor(subjectID, account)
+	if err != nil {
+		return nil, err
+	}
+	if factor != nil && factor.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	pending := &user.MFAFactor{SubjectID: subjectID, Account: account, Secret: secret}
+	if err := m.mfaRepo.SavePendingFactor(pending); err != nil {
+		return nil, err
+	}
+	return &user.MFAEnrollment{Secret: secret, URI: totp.URI(m.config.Issuer, label, secret)}, nil
+}
+
+func (m *mfaInteraction) ConfirmEnrollment(subjectID uint, account, code string) ([]string, error) {
+	factor, err := m.findFactor(subjectID, account)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil {
+		return nil, ErrMFANotPending
+	}
+	if factor.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	//**Enrollment proves the app is set up, recovery codes do not count yet
+	step, ok := totp.Validate(factor.Secret, code, m.now(), 1)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes, hashes, err := m.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.mfaRepo.ConfirmFactor(factor.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (m *mfaInteraction) Disable(subjectID uint, account, code string) error {
+	if m.Required(account) {
+		return ErrMFAMandatory
+	}
+	factor, err := m.enabledFactor(subjectID, account)
+	if err != nil {
+		return err
+	}
+	if err := m.verifyCode(factor, code); err != nil {
+		return err
+	}
+	return m.mfaRepo.DeleteFactor(factor.ID)
+}
+
+func (m *mfaInteraction) RegenerateRecoveryCodes(subjectID uint, account, code string) ([]string, error) {
+	factor, err := m.enabledFactor(subjectID, account)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verifyCode(factor, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := m.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.mfaRepo.ReplaceRecoveryCodes(factor.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (m *mfaInteraction) Enabled(subjectID uint, account string) (bool, error) {
+	factor, err := m.findFactor(subjectID, account)
+	if err != nil {
+		return false, err
+	}
+	return factor != nil && factor.Enabled(), nil
+}
+
+// Required reports whether policy forces two-factor authentication on the account kind
+func (m *mfaInteraction) Required(account string) bool {
+	return account == user.AccountAdmin && m.config.RequireForAdmins
+}
+
+func (m *mfaInteraction) StartChallenge(pending *user.MFAChallenge) (string, error) {
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	pending.TokenHash = hash
+	pending.ExpiresAt = m.now().Add(m.config.ChallengeTTL)
+	if err := m.mfaRepo.CreateChallenge(pending); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+func (m *mfaInteraction) VerifyChallenge(challengeToken, code, clientIP string) (*user.MFAChallenge, error) {
+	challenge, err := m.mfaRepo.FindChallenge(token.HashOpaqueToken(challengeToken))
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if challenge.UsedAt != nil || m.now().After(challenge.ExpiresAt) || challenge.Attempts >= m.config.MaxAttempts {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err := m.loginGuard.Check(challenge.Account, challenge.Username, clientIP); err != nil {
+		return nil, err
+	}
+
+	factor, err := m.enabledFactor(challenge.SubjectID, challenge.Account)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err := m.verifyCode(factor, code); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return nil, err
+		}
+		//**Wrong codes burn the challenge and feed the same lockout as wrong passwords
+		if attemptErr := m.mfaRepo.RecordChallengeAttempt(challenge.ID); attemptErr != nil {
+			return nil, attemptErr
+		}
+		if guardErr := m.loginGuard.Failed(challenge.Account, challenge.Username, clientIP); guardErr != nil {
+			return nil, guardErr
+		}
+		return nil, err
+	}
+
+	if err := m.mfaRepo.ConsumeChallenge(challenge.ID); err != nil {
+		if errors.Is(err, repository.ErrMFAChallengeUsed) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// verifyCode accepts a current TOTP code or an unused recovery code
+func (m *mfaInteraction) verifyCode(factor *user.MFAFactor, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(factor.Secret, code, m.now(), 1); ok {
+		if err := m.mfaRepo.UseFactorStep(factor.ID, step); err != nil {
+			if errors.Is(err, repository.ErrMFACodeReplayed) {
+				return ErrInvalidMFACode
+			}
+			return err
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidMFACode
+	}
+	if err := m.mfaRepo.ConsumeRecoveryCode(factor.ID, token.HashOpaqueToken(normalized)); err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeUsed) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+// findFactor returns nil without an error when the account never enrolled
+func (m *mfaInteraction) findFactor(subjectID uint, account string) (*user.MFAFactor, error) {
+	factor, err := m.mfaRepo.GetFactor(subjectID, account)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return factor, nil
+}
+
+func (m *mfaInteraction) enabledFactor(subjectID uint, account string) (*user.MFAFactor, error) {
+	factor, err := m.findFactor(subjectID, account)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.Enabled() {
+		return nil, ErrMFANotEnabled
+	}
+	return factor, nil
+}
+
+// newRecoveryCodes returns codes formatted for the user and the hashes to store
+func (m *mfaInteraction) newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, m.config.RecoveryCodeCount)
+	hashes := make([]string, 0, m.config.RecoveryCodeCount)
+	for i := 0; i < m.config.RecoveryCodeCount; i++ {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("generating recovery codes: %w", err)
+		}
+		plain := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes = append(codes, plain[:5]+"-"+plain[5:])
+		hashes = append(hashes, token.HashOpaqueToken(plain))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func NewMFAUseCase(mfaRepo repository.MFARepository, loginGuard LoginGuard, config MFAConfig) MFAUseCase {
+	return &mfaInteraction{
+		mfaRepo:    mfaRepo,
+		loginGuard: loginGuard,
+		config:     config,
+		now:        time.Now,
+	}
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/totp"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// memoryMFARepository keeps factors, codes and challenges in maps
+type memoryMFARepository struct {
+	factors    map[uint]*user.MFAFactor
+	codes      map[uint]map[string]bool
+	challenges map[uint]*user.MFAChallenge
+	nextID     uint
+}
+
+func newMemoryMFARepository() *memoryMFARepository {
+	return &memoryMFARepository{
+		factors:    map[uint]*user.MFAFactor{},
+		codes:      map[uint]map[string]bool{},
+		challenges: map[uint]*user.MFAChallenge{},
+	}
+}
+
+func (m *memoryMFARepository) id() uint {
+	m.nextID++
+	return m.nextID
+}
+
+func (m *memoryMFARepository) GetFactor(subjectID uint, account string) (*user.MFAFactor, error) {
+	for _, factor := range m.factors {
+		if factor.SubjectID == subjectID && factor.Account == account {
+			copied := *factor
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("getting mfa factor: %w", gorm.ErrRecordNotFound)
+}
+
+func (m *memoryMFARepository) SavePendingFactor(factor *user.MFAFactor) error {
+	for id, existing := range m.factors {
+		if existing.SubjectID == factor.SubjectID && existing.Account == factor.Account && !existing.Enabled() {
+			delete(m.factors, id)
+		}
+	}
+	factor.ID = m.id()
+	copied := *factor
+	m.factors[factor.ID] = &copied
+	return nil
+}
+
+func (m *memoryMFARepository) ConfirmFactor(factorID uint, step int64, codeHashes []string) error {
+	now := time.Now()
+	m.factors[factorID].ConfirmedAt = &now
+	m.factors[factorID].LastUsedStep = step
+	return m.ReplaceRecoveryCodes(factorID, codeHashes)
+}
+
+func (m *memoryMFARepository) UseFactorStep(factorID uint, step int64) error {
+	if m.factors[factorID].LastUsedStep >= step {
+		return repository.ErrMFACodeReplayed
+	}
+	m.factors[factorID].LastUsedStep = step
+	return nil
+}
+
+func (m *memoryMFARepository) DeleteFactor(factorID uint) error {
+	delete(m.factors, factorID)
+	delete(m.codes, factorID)
+	return nil
+}
+
+func (m *memoryMFARepository) ReplaceRecoveryCodes(factorID uint, codeHashes []string) error {
+	m.codes[factorID] = map[string]bool{}
+	for _, hash := range codeHashes {
+		m.codes[factorID][hash] = false
+	}
+	return nil
+}
+
+func (m *memoryMFARepository) ConsumeRecoveryCode(factorID uint, codeHash string) error {
+	used, ok := m.codes[factorID][codeHash]
+	if !ok || used {
+		return repository.ErrRecoveryCodeUsed
+	}
+	m.codes[factorID][codeHash] = true
+	return nil
+}
+
+func (m *memoryMFARepository) CreateChallenge(challenge *user.MFAChallenge) error {
+	challenge.ID = m.id()
+	copied := *challenge
+	m.challenges[challenge.ID] = &copied
+	return nil
+}
+
+func (m *memoryMFARepository) FindChallenge(tokenHash string) (*user.MFAChallenge, error) {
+	for _, challenge := range m.challenges {
+		if challenge.TokenHash == tokenHash {
+			copied := *challenge
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryMFARepository) RecordChallengeAttempt(id uint) error {
+	m.challenges[id].Attempts++
+	return nil
+}
+
+func (m *memoryMFARepository) ConsumeChallenge(id uint) error {
+	if m.challenges[id].UsedAt != nil {
+		return repository.ErrMFAChallengeUsed
+	}
+	now := time.Now()
+	m.challenges[id].UsedAt = &now
+	return nil
+}
+
+func newTestMFAUseCase() (*mfaInteraction, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard, _, _ := newTestLoginGuard()
+	mfa := NewMFAUseCase(newMemoryMFARepository(), guard, MFAConfig{
+		Issuer:            "newCrudClean",
+		ChallengeTTL:      5 * time.Minute,
+		MaxAttempts:       3,
+		RecoveryCodeCount: 4,
+		RequireForAdmins:  true,
+	}).(*mfaInteraction)
+	mfa.now = func() time.Time { return now }
+	return mfa, &now
+}
+
+// enrollTestFactor enrolls subject 1 and returns its secret and recovery codes
+func enrollTestFactor(t *testing.T, mfa *mfaInteraction, account string) (string, []string) {
+	enrollment, err := mfa.Enroll(1, account, "alice")
+	assert.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/newCrudClean:alice?")
+
+	code, _ := totp.Code(enrollment.Secret, totp.Step(mfa.now()))
+	codes, err := mfa.ConfirmEnrollment(1, account, code)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 4)
+	return enrollment.Secret, codes
+}
+
+func TestMFAEnrollmentAndChallenge(t *testing.T) {
+	mfa, now := newTestMFAUseCase()
+
+	enabled, err := mfa.Enabled(1, user.AccountUser)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	secret, recoveryCodes := enrollTestFactor(t, mfa, user.AccountUser)
+	enabled, _ = mfa.Enabled(1, user.AccountUser)
+	assert.True(t, enabled)
+
+	_, err = mfa.Enroll(1, user.AccountUser, "alice")
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	challengeToken, err := mfa.StartChallenge(&user.MFAChallenge{SubjectID: 1, Account: user.AccountUser, Username: "alice"})
+	assert.NoError(t, err)
+
+	// The code used for enrollment cannot be replayed
+	code, _ := totp.Code(secret, totp.Step(*now))
+	_, err = mfa.VerifyChallenge(challengeToken, code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	*now = now.Add(totp.Period)
+	code, _ = totp.Code(secret, totp.Step(*now))
+	challenge, err := mfa.VerifyChallenge(challengeToken, code, "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), challenge.SubjectID)
+
+	// A challenge is single-use
+	_, err = mfa.VerifyChallenge(challengeToken, code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	// Recovery codes work once, with or without the dash
+	challengeToken, _ = mfa.StartChallenge(&user.MFAChallenge{SubjectID: 1, Account: user.AccountUser, Username: "alice"})
+	_, err = mfa.VerifyChallenge(challengeToken, " "+recoveryCodes[0]+" ", "10.0.0.1")
+	assert.NoError(t, err)
+	challengeToken, _ = mfa.StartChallenge(&user.MFAChallenge{SubjectID: 1, Account: user.AccountUser, Username: "alice"})
+	_, err = mfa.VerifyChallenge(challengeToken, recoveryCodes[0], "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+}
+
+func TestMFAChallengeLimitsAttempts(t *testing.T) {
+	mfa, now := newTestMFAUseCase()
+	secret, _ := enrollTestFactor(t, mfa, user.AccountUser)
+	*now = now.Add(totp.Period)
+
+	challengeToken, _ := mfa.StartChallenge(&user.MFAChallenge{SubjectID: 1, Account: user.AccountUser, Username: "alice"})
+	for i := 0; i < 3; i++ {
+		_, err := mfa.VerifyChallenge(challengeToken, "000000", "10.0.0.1")
+		assert.True(t, errors.Is(err, ErrInvalidMFACode) || errors.As(err, new(*LockedError)))
+	}
+
+	code, _ := totp.Code(secret, totp.Step(*now))
+	_, err := mfa.VerifyChallenge(challengeToken, code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	// Challenges also expire
+	challengeToken, _ = mfa.StartChallenge(&user.MFAChallenge{SubjectID: 1, Account: user.AccountUser, Username: "alice"})
+	*now = now.Add(10 * time.Minute)
+	_, err = mfa.VerifyChallenge(challengeToken, code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidMFAChallenge)
+}
+
+func TestMFAPolicyKeepsAdminFactor(t *testing.T) {
+	mfa, now := newTestMFAUseCase()
+	secret, oldCodes := enrollTestFactor(t, mfa, user.AccountAdmin)
+	*now = now.Add(totp.Period)
+	code, _ := totp.Code(secret, totp.Step(*now))
+
+	assert.True(t, mfa.Required(user.AccountAdmin))
+	assert.False(t, mfa.Required(user.AccountUser))
+	assert.ErrorIs(t, mfa.Disable(1, user.AccountAdmin, code), ErrMFAMandatory)
+
+	// Regenerating invalidates the old recovery codes
+	codes, err := mfa.RegenerateRecoveryCodes(1, user.AccountAdmin, code)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 4)
+	_, err = mfa.RegenerateRecoveryCodes(1, user.AccountAdmin, oldCodes[1])
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	_, err = mfa.RegenerateRecoveryCodes(1, user.AccountAdmin, codes[1])
+	assert.NoError(t, err)
+}
@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	assert.NoError(t, policy.Validate("Rathee@12345"))
+
+	var violation *PasswordPolicyError
+	assert.True(t, errors.As(policy.Validate("rathee"), &violation))
+	assert.Equal(t, []string{"at least 10 characters", "an uppercase letter", "a digit", "a symbol"}, violation.Violations)
+
+	// Length counts characters, not bytes
+	assert.NoError(t, PasswordPolicy{MinLength: 4}.Validate("ääää"))
+	assert.Error(t, PasswordPolicy{MinLength: 5}.Validate("ääää"))
+}
@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+)
+
+type SearchUseCase interface {
+	SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error)
+}
+
+type searchInteraction struct {
+	searcher repository.ProductSearcher
+}
+
+func (s *searchInteraction) SearchProducts(query *user.ProductSearchQuery) ([]user.ProductSearchHit, int64, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	hits, total, err := s.searcher.SearchProducts(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search products: %w", err)
+	}
+	return hits, total, nil
+}
+
+func NewSearchUseCase(searcher repository.ProductSearcher) SearchUseCase {
+	return &searchInteraction{
+		searcher: searcher,
+	}
+}
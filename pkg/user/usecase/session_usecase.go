@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+)
+
+type SessionUseCase interface {
+	StartSession(subjectID uint, account, role, userAgent, clientIP string, mfaVerified bool) (*user.TokenPair, error)
+	Refresh(refreshToken string) (*user.TokenPair, error)
+	ValidateSession(sessionID uint) (*user.Session, error)
+	Logout(sessionID uint) error
+	LogoutEverywhere(subjectID uint, account string) error
+	LogoutOthers(subjectID uint, account string, keepSessionID uint) error
+}
+
+type sessionInteraction struct {
+	sessionRepo repository.SessionRepository
+	tokenMaker  token.Maker
+	refreshTTL  time.Duration
+}
+
+func (s *sessionInteraction) StartSession(subjectID uint, account, role, userAgent, clientIP string, mfaVerified bool) (*user.TokenPair, error) {
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.refreshTTL)
+	session := &user.Session{
+		SubjectID:   subjectID,
+		Account:     account,
+		Role:        role,
+		UserAgent:   userAgent,
+		ClientIP:    clientIP,
+		ExpiresAt:   expiresAt,
+		MFAVerified: mfaVerified,
+	}
+	refresh := &user.RefreshToken{TokenHash: hash, ExpiresAt: expiresAt}
+	if err := s.sessionRepo.CreateSession(session, refresh); err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	return s.tokenPair(session, plain, refresh.ExpiresAt)
+}
+
+func (s *sessionInteraction) Refresh(refreshToken string) (*user.TokenPair, error) {
+	used, err := s.sessionRepo.FindRefreshToken(token.HashOpaqueToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetSessionByID(used.SessionID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	//**A rotated token showing up again means it was stolen, kill the whole session
+	if used.UsedAt != nil {
+		s.revokeAfterReuse(session.ID)
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(used.ExpiresAt) || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	next := &user.RefreshToken{TokenHash: hash, ExpiresAt: session.ExpiresAt}
+	if err := s.sessionRepo.RotateRefreshToken(used, next); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenUsed) {
+			s.revokeAfterReuse(session.ID)
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return s.tokenPair(session, plain, next.ExpiresAt)
+}
+
+func (s *sessionInteraction) ValidateSession(sessionID uint) (*user.Session, error) {
+	session, err := s.sessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, ErrSessionRevoked
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrSessionRevoked
+	}
+	return session, nil
+}
+
+func (s *sessionInteraction) Logout(sessionID uint) error {
+	return s.sessionRepo.RevokeSession(sessionID)
+}
+
+func (s *sessionInteraction) LogoutEverywhere(subjectID uint, account string) error {
+	return s.sessionRepo.RevokeSubjectSessions(subjectID, account)
+}
+
+func (s *sessionInteraction) LogoutOthers(subjectID uint, account string, keepSessionID uint) error {
+	return s.sessionRepo.RevokeOtherSessions(subjectID, account, keepSessionID)
+}
+
+func (s *sessionInteraction) revokeAfterReuse(sessionID uint) {
+	log.Printf("refresh token reuse detected for session %d", sessionID)
+	if err := s.sessionRepo.RevokeSession(sessionID); err != nil {
+		log.Printf("revoking session %d failed: %v", sessionID, err)
+	}
+}
+
+func (s *sessionInteraction) tokenPair(session *user.Session, refreshToken string, refreshExpiresAt time.Time) (*user.TokenPair, error) {
+	accessToken, claims, err := s.tokenMaker.CreateToken(session.SubjectID, session.Account, session.Role, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue access token: %w", err)
+	}
+	return &user.TokenPair{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresAt:             claims.ExpiresAt.Time,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func NewSessionUseCase(sessionRepo repository.SessionRepository, tokenMaker token.Maker, refreshTTL time.Duration) SessionUseCase {
+	return &sessionInteraction{
+		sessionRepo: sessionRepo,
+		tokenMaker:  tokenMaker,
+		refreshTTL:  refreshTTL,
+	}
+}
@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// memorySessionRepository keeps sessions and refresh tokens in maps
+type memorySessionRepository struct {
+	sessions map[uint]*user.Session
+	tokens   map[uint]*user.RefreshToken
+	nextID   uint
+	// beforeRotate runs inside RotateRefreshToken, to let a concurrent request win the race
+	beforeRotate func()
+}
+
+func newMemorySessionRepository() *memorySessionRepository {
+	return &memorySessionRepository{sessions: map[uint]*user.Session{}, tokens: map[uint]*user.RefreshToken{}}
+}
+
+func (m *memorySessionRepository) id() uint {
+	m.nextID++
+	return m.nextID
+}
+
+func (m *memorySessionRepository) CreateSession(session *user.Session, refresh *user.RefreshToken) error {
+	session.ID = m.id()
+	copied := *session
+	m.sessions[session.ID] = &copied
+	refresh.SessionID = session.ID
+	refresh.ID = m.id()
+	copiedRefresh := *refresh
+	m.tokens[refresh.ID] = &copiedRefresh
+	return nil
+}
+
+func (m *memorySessionRepository) GetSessionByID(id uint) (*user.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("getting session by ID :%w", gorm.ErrRecordNotFound)
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memorySessionRepository) FindRefreshToken(tokenHash string) (*user.RefreshToken, error) {
+	for _, refresh := range m.tokens {
+		if refresh.TokenHash == tokenHash {
+			copied := *refresh
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("finding refresh token: %w", gorm.ErrRecordNotFound)
+}
+
+func (m *memorySessionRepository) RotateRefreshToken(used *user.RefreshToken, next *user.RefreshToken) error {
+	if m.beforeRotate != nil {
+		m.beforeRotate()
+	}
+	if m.tokens[used.ID].UsedAt != nil {
+		return repository.ErrRefreshTokenUsed
+	}
+	now := time.Now()
+	m.tokens[used.ID].UsedAt = &now
+	next.SessionID = used.SessionID
+	next.ID = m.id()
+	copied := *next
+	m.tokens[next.ID] = &copied
+	return nil
+}
+
+func (m *memorySessionRepository) RevokeSession(id uint) error {
+	if session, ok := m.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (m *memorySessionRepository) RevokeSubjectSessions(subjectID uint, account string) error {
+	return m.RevokeOtherSessions(subjectID, account, 0)
+}
+
+func (m *memorySessionRepository) RevokeOtherSessions(subjectID uint, account string, keepSessionID uint) error {
+	for id, session := range m.sessions {
+		if session.SubjectID == subjectID && session.Account == account && id != keepSessionID {
+			m.RevokeSession(id)
+		}
+	}
+	return nil
+}
+
+func newTestSessionUseCase(t *testing.T) (SessionUseCase, *memorySessionRepository, token.Maker) {
+	t.Helper()
+	maker, err := token.NewHS256Maker([]byte("0123456789abcdef0123456789abcdef"), 15*time.Minute, "test")
+	require.NoError(t, err)
+	sessions := newMemorySessionRepository()
+	return NewSessionUseCase(sessions, maker, 24*time.Hour), sessions, maker
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	sessions, repo, maker := newTestSessionUseCase(t)
+
+	first, err := sessions.StartSession(7, user.AccountUser, "customer", "test-agent", "192.0.2.1", false)
+	require.NoError(t, err)
+	claims, err := maker.VerifyToken(first.AccessToken)
+	require.NoError(t, err)
+
+	second, err := sessions.Refresh(first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	rotated, err := maker.VerifyToken(second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, rotated.SessionID)
+	assert.Equal(t, "customer", rotated.Role)
+
+	// Only hashes are stored
+	for _, refresh := range repo.tokens {
+		assert.NotEqual(t, first.RefreshToken, refresh.TokenHash)
+	}
+
+	third, err := sessions.Refresh(second.RefreshToken)
+	require.NoError(t, err)
+	_, err = sessions.ValidateSession(claims.SessionID)
+	require.NoError(t, err)
+
+	// Replaying a rotated token revokes the whole session, the newest token included
+	_, err = sessions.Refresh(first.RefreshToken)
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+	_, err = sessions.ValidateSession(claims.SessionID)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+	_, err = sessions.Refresh(third.RefreshToken)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+}
+
+func TestRefreshLosingRotationRaceRevokesSession(t *testing.T) {
+	sessions, repo, maker := newTestSessionUseCase(t)
+	pair, err := sessions.StartSession(7, user.AccountUser, "customer", "", "", false)
+	require.NoError(t, err)
+	claims, err := maker.VerifyToken(pair.AccessToken)
+	require.NoError(t, err)
+
+	// Another request rotates the token between the lookup and the rotation
+	repo.beforeRotate = func() {
+		now := time.Now()
+		for _, refresh := range repo.tokens {
+			refresh.UsedAt = &now
+		}
+	}
+	_, err = sessions.Refresh(pair.RefreshToken)
+	assert.True(t, errors.Is(err, ErrRefreshTokenReused))
+	_, err = sessions.ValidateSession(claims.SessionID)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+}
+
+func TestRefreshRejectsExpiredTokens(t *testing.T) {
+	sessions, repo, maker := newTestSessionUseCase(t)
+
+	_, err := sessions.Refresh("unknown")
+	assert.True(t, errors.Is(err, ErrInvalidRefreshToken))
+
+	expiredToken, err := sessions.StartSession(7, user.AccountUser, "customer", "", "", false)
+	require.NoError(t, err)
+	for _, refresh := range repo.tokens {
+		refresh.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+	_, err = sessions.Refresh(expiredToken.RefreshToken)
+	assert.True(t, errors.Is(err, ErrInvalidRefreshToken))
+
+	expiredSession, err := sessions.StartSession(7, user.AccountUser, "customer", "", "", false)
+	require.NoError(t, err)
+	claims, err := maker.VerifyToken(expiredSession.AccessToken)
+	require.NoError(t, err)
+	repo.sessions[claims.SessionID].ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = sessions.Refresh(expiredSession.RefreshToken)
+	assert.True(t, errors.Is(err, ErrInvalidRefreshToken))
+	_, err = sessions.ValidateSession(claims.SessionID)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+
+	// Expired tokens are turned down without being treated as stolen
+	assert.Nil(t, repo.sessions[claims.SessionID].RevokedAt)
+}
+
+func TestLogoutOthersKeepsCurrentSession(t *testing.T) {
+	sessions, _, maker := newTestSessionUseCase(t)
+
+	var ids []uint
+	for _, account := range []string{user.AccountUser, user.AccountUser, user.AccountAdmin} {
+		pair, err := sessions.StartSession(7, account, "customer", "", "", false)
+		require.NoError(t, err)
+		claims, err := maker.VerifyToken(pair.AccessToken)
+		require.NoError(t, err)
+		ids = append(ids, claims.SessionID)
+	}
+
+	require.NoError(t, sessions.LogoutOthers(7, user.AccountUser, ids[0]))
+	_, err := sessions.ValidateSession(ids[0])
+	assert.NoError(t, err)
+	_, err = sessions.ValidateSession(ids[1])
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+	// An admin account with the same ID is someone else
+	_, err = sessions.ValidateSession(ids[2])
+	assert.NoError(t, err)
+
+	require.NoError(t, sessions.Logout(ids[0]))
+	_, err = sessions.ValidateSession(ids[0])
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/pagination"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotInTrash          = errors.New("record is not in the trash")
+	ErrRestoreCategoryGone = errors.New("the product's category was deleted, restore or recreate it first")
+)
+
+type TrashUseCase interface {
+	ListProducts(query *user.TrashQuery) ([]user.Product, int64, error)
+	ListUsers(query *user.TrashQuery) ([]user.UserRegister, int64, error)
+	RestoreProduct(id uint) (*user.Product, error)
+	RestoreUser(id uint) (*user.UserRegister, error)
+	PurgeProduct(id uint) error
+	PurgeUser(id uint) error
+	PurgeExpired() (*user.PurgeResult, error)
+}
+
+type trashInteraction struct {
+	trashRepo    repository.TrashRepository
+	categoryRepo repository.CategoryRepository
+	retention    time.Duration
+}
+
+func (t *trashInteraction) ListProducts(query *user.TrashQuery) ([]user.Product, int64, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	return t.trashRepo.ListDeletedProducts(query.Page, query.Limit)
+}
+
+func (t *trashInteraction) ListUsers(query *user.TrashQuery) ([]user.UserRegister, int64, error) {
+	query.Page, query.Limit = pagination.Normalize(query.Page, query.Limit)
+	return t.trashRepo.ListDeletedUsers(query.Page, query.Limit)
+}
+
+func (t *trashInteraction) RestoreProduct(id uint) (*user.Product, error) {
+	deleted, err := t.trashRepo.FindDeletedProduct(id)
+	if err != nil {
+		return nil, notInTrash(err)
+	}
+	//**A product cannot come back into a category that is itself in the trash
+	if _, err := t.categoryRepo.GetCategory(deleted.CategoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: category %d", ErrRestoreCategoryGone, deleted.CategoryID)
+		}
+		return nil, err
+	}
+
+	product, err := t.trashRepo.RestoreProduct(id)
+	if err != nil {
+		return nil, notInTrash(err)
+	}
+	return product, nil
+}
+
+func (t *trashInteraction) RestoreUser(id uint) (*user.UserRegister, error) {
+	restored, err := t.trashRepo.RestoreUser(id)
+	if err != nil {
+		return nil, notInTrash(err)
+	}
+	return restored, nil
+}
+
+func (t *trashInteraction) PurgeProduct(id uint) error {
+	return notInTrash(t.trashRepo.PurgeProduct(id))
+}
+
+func (t *trashInteraction) PurgeUser(id uint) error {
+	return notInTrash(t.trashRepo.PurgeUser(id))
+}
+
+// PurgeExpired hard deletes what stayed in the trash longer than the retention,
+// a retention of zero keeps trashed records forever
+func (t *trashInteraction) PurgeExpired() (*user.PurgeResult, error) {
+	if t.retention <= 0 {
+		return &user.PurgeResult{}, nil
+	}
+	return t.trashRepo.PurgeDeletedBefore(time.Now().Add(-t.retention))
+}
+
+// notInTrash turns the repository's missing row into ErrNotInTrash
+func notInTrash(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotInTrash
+	}
+	return err
+}
+
+func NewTrashUseCase(trashRepo repository.TrashRepository, categoryRepo repository.CategoryRepository, retention time.Duration) TrashUseCase {
+	return &trashInteraction{
+		trashRepo:    trashRepo,
+		categoryRepo: categoryRepo,
+		retention:    retention,
+	}
+}
@@ -1,45 +1,220 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
 
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/notify"
+	"github.com/ratheeshkumar25/pkg/rbac"
+	"github.com/ratheeshkumar25/pkg/token"
 	user "github.com/ratheeshkumar25/pkg/user/entity"
 	"github.com/ratheeshkumar25/pkg/user/repository"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidResetToken        = errors.New("reset token is invalid or expired")
+	ErrInvalidVerificationToken = errors.New("verification token is invalid or expired")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrWrongPassword            = errors.New("current password is incorrect")
+	ErrSamePassword             = errors.New("new password must differ from the current one")
+)
+
+// UserConfig holds the tunables of the user flows
+type UserConfig struct {
+	ResetTTL             time.Duration
+	VerificationTTL      time.Duration
+	RequireVerifiedEmail bool
+	PasswordPolicy       PasswordPolicy
+}
+
 type UserUseCase interface {
 	RegisterUser(user *user.UserRegister) error
-	Login(login *user.UserLogin) (*user.UserRegister, error)
-	UpdateUser(user *user.UserRegister) error
+	Login(login *user.UserLogin, clientIP string) (*user.UserRegister, error)
+	UpdateUser(id uint, profile *user.UserProfileUpdate) error
+	PatchUser(id uint, patch *user.UserPatch) (*user.UserRegister, error)
+	ChangePassword(id, keepSessionID uint, currentPassword, newPassword, clientIP string) error
 	GetUserDetail(id uint) (*user.UserRegister, error)
 	RemoveUser(id uint) error
+	ForgotPassword(email string) error
+	ResetPassword(resetToken, password string) error
+	VerifyEmail(verificationToken string) error
+	ResendVerification(email string) error
 }
 
 type userInteraction struct {
-	userRepo repository.UserRepository
+	userRepo        repository.UserRepository
+	actionTokenRepo repository.ActionTokenRepository
+	sessionUseCase  SessionUseCase
+	loginGuard      LoginGuard
+	notifier        notify.Notifier
+	config          UserConfig
 }
 
-func (u *userInteraction) RegisterUser(user *user.UserRegister) error {
-	return u.userRepo.CreateUser(user)
+func (u *userInteraction) RegisterUser(newUser *user.UserRegister) error {
+	if err := u.config.PasswordPolicy.Validate(newUser.Password); err != nil {
+		return err
+	}
+	newUser.Role = rbac.RoleCustomer
+	newUser.VerifiedAt = nil
+	if err := u.userRepo.CreateUser(newUser); err != nil {
+		return err
+	}
+
+	//**The account exists even if the mail fails, the user can ask for a new one
+	if err := u.sendVerification(newUser); err != nil {
+		log.Printf("sending verification email to user %d failed: %v", newUser.ID, err)
+	}
+	return nil
 }
 
-func (u *userInteraction) Login(login *user.UserLogin) (*user.UserRegister, error) {
-	user, err := u.userRepo.FindUserByName(login.UserName)
+func (u *userInteraction) Login(login *user.UserLogin, clientIP string) (*user.UserRegister, error) {
+	if err := u.loginGuard.Check(user.AccountUser, login.UserName, clientIP); err != nil {
+		return nil, err
+	}
+
+	found, err := u.userRepo.FindUserByName(login.UserName)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(found.Password), []byte(login.Password))
+		if err != nil {
+			err = fmt.Errorf("invalid password:%w", err)
+		}
+	}
 	if err != nil {
+		//**Unknown usernames count too, otherwise the lockout would reveal which accounts exist
+		if guardErr := u.loginGuard.Failed(user.AccountUser, login.UserName, clientIP); guardErr != nil {
+			return nil, guardErr
+		}
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password))
+	if err := u.loginGuard.Succeeded(user.AccountUser, login.UserName, clientIP); err != nil {
+		return nil, err
+	}
+	if u.config.RequireVerifiedEmail && !found.EmailVerified() {
+		return nil, ErrEmailNotVerified
+	}
+	return found, nil
+}
+
+
+func (u *userInteraction) UpdateUser(id uint, profile *user.UserProfileUpdate) error {
+	existing, err := u.userRepo.GetUserByID(id)
 	if err != nil {
-		return nil, fmt.Errorf("invalid password:%w", err)
+		return err
 	}
-	return user, nil
+
+	//**A client sending the version it read only overwrites that version
+	if profile.Version != 0 {
+		existing.Version = profile.Version
+	}
+	if profile.Name != "" {
+		existing.Name = profile.Name
+	}
+	if profile.Phone != "" {
+		existing.Phone = profile.Phone
+	}
+	emailChanged := profile.Email != "" && !strings.EqualFold(profile.Email, existing.Email)
+	if profile.Email != "" {
+		existing.Email = profile.Email
+	}
+	return u.saveProfile(existing, emailChanged)
 }
 
+// PatchUser applies a merge patch to a profile, name, email and phone are
+// required columns so they can be replaced but not nulled
+func (u *userInteraction) PatchUser(id uint, patch *user.UserPatch) (*user.UserRegister, error) {
+	existing, err := u.userRepo.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version != 0 {
+		existing.Version = patch.Version
+	}
+
+	if err := requiredMember("name", patch.Name); err != nil {
+		return nil, err
+	}
+	if err := requiredMember("phone", patch.Phone); err != nil {
+		return nil, err
+	}
+	if err := requiredMember("email", patch.Email); err != nil {
+		return nil, err
+	}
+	if patch.Email.Set {
+		if address, err := mail.ParseAddress(patch.Email.Value); err != nil || address.Address != patch.Email.Value {
+			return nil, fmt.Errorf("%w: email is not a valid address", mergepatch.ErrInvalidPatch)
+		}
+	}
 
-func (u *userInteraction) UpdateUser(user *user.UserRegister) error {
-	return u.userRepo.UpdateUser(user)
+	emailChanged := patch.Email.Set && !strings.EqualFold(patch.Email.Value, existing.Email)
+	patch.Name.Apply(&existing.Name)
+	patch.Phone.Apply(&existing.Phone)
+	patch.Email.Apply(&existing.Email)
+	if err := u.saveProfile(existing, emailChanged); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// requiredMember rejects null and blank values for a member that may only be replaced
+func requiredMember(name string, member mergepatch.Field[string]) error {
+	if member.Set && (member.Null || strings.TrimSpace(member.Value) == "") {
+		return fmt.Errorf("%w: %s cannot be empty", mergepatch.ErrInvalidPatch, name)
+	}
+	return nil
+}
+
+// saveProfile writes the profile back, a new address has to be verified again
+func (u *userInteraction) saveProfile(existing *user.UserRegister, emailChanged bool) error {
+	if emailChanged {
+		existing.VerifiedAt = nil
+	}
+	if err := u.userRepo.UpdateUser(existing); err != nil {
+		return err
+	}
+	if emailChanged {
+		if err := u.sendVerification(existing); err != nil {
+			log.Printf("sending verification email to user %d failed: %v", existing.ID, err)
+		}
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a logged in user and signs out every
+// session except keepSessionID
+func (u *userInteraction) ChangePassword(id, keepSessionID uint, currentPassword, newPassword, clientIP string) error {
+	existing, err := u.userRepo.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+
+	//**Guessing the current password counts against the same lockout as the login
+	if err := u.loginGuard.Check(user.AccountUser, existing.UserName, clientIP); err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(currentPassword)); err != nil {
+		if guardErr := u.loginGuard.Failed(user.AccountUser, existing.UserName, clientIP); guardErr != nil {
+			return guardErr
+		}
+		return ErrWrongPassword
+	}
+	if currentPassword == newPassword {
+		return ErrSamePassword
+	}
+	if err := u.config.PasswordPolicy.Validate(newPassword); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.UpdatePassword(id, newPassword); err != nil {
+		return err
+	}
+	return u.sessionUseCase.LogoutOthers(id, user.AccountUser, keepSessionID)
 }
 
 func (u *userInteraction) GetUserDetail(id uint) (*user.UserRegister, error) {
@@ -50,8 +225,120 @@ func (u *userInteraction) RemoveUser(id uint) error {
 	return u.userRepo.DeleteUser(int(id))
 }
 
-func NewUserUsecase(userRepo repository.UserRepository) UserUseCase {
+// ForgotPassword mails a reset token, unknown emails are ignored so accounts cannot be probed
+func (u *userInteraction) ForgotPassword(email string) error {
+	found, err := u.userRepo.FindUserByEmail(email)
+	if err != nil {
+		log.Printf("password reset requested for unknown email")
+		return nil
+	}
+
+	plain, err := u.issueActionToken(found, user.PurposePasswordReset, u.config.ResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.notifier.Send(notify.Message{
+		To:      found.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Use this token to reset your password, it expires in %s: %s", u.config.ResetTTL, plain),
+	})
+}
+
+func (u *userInteraction) ResetPassword(resetToken, password string) error {
+	found, err := u.actionTokenRepo.FindActionToken(token.HashOpaqueToken(resetToken), user.PurposePasswordReset)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if found.UsedAt != nil || time.Now().After(found.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	//**Check the policy first so a rejected password does not burn the token
+	if err := u.config.PasswordPolicy.Validate(password); err != nil {
+		return err
+	}
+	if err := u.userRepo.ResetPassword(found.SubjectID, found.ID, password); err != nil {
+		if errors.Is(err, repository.ErrActionTokenUsed) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	//**Whoever held the old password must not stay logged in
+	return u.sessionUseCase.LogoutEverywhere(found.SubjectID, user.AccountUser)
+}
+
+func (u *userInteraction) VerifyEmail(verificationToken string) error {
+	found, err := u.actionTokenRepo.FindActionToken(token.HashOpaqueToken(verificationToken), user.PurposeEmailVerification)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	if found.UsedAt != nil || time.Now().After(found.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+	//**A link mailed to a previous address cannot verify the current one
+	subject, err := u.userRepo.GetUserByID(found.SubjectID)
+	if err != nil || !strings.EqualFold(subject.Email, found.Email) {
+		return ErrInvalidVerificationToken
+	}
+	if err := u.actionTokenRepo.ConsumeActionToken(found.ID); err != nil {
+		if errors.Is(err, repository.ErrActionTokenUsed) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	return u.userRepo.MarkEmailVerified(found.SubjectID)
+}
+
+// ResendVerification mails a fresh token, unknown or verified emails are ignored
+func (u *userInteraction) ResendVerification(email string) error {
+	found, err := u.userRepo.FindUserByEmail(email)
+	if err != nil || found.EmailVerified() {
+		return nil
+	}
+	return u.sendVerification(found)
+}
+
+func (u *userInteraction) sendVerification(target *user.UserRegister) error {
+	plain, err := u.issueActionToken(target, user.PurposeEmailVerification, u.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.notifier.Send(notify.Message{
+		To:      target.Email,
+		Subject: "Verify your email address",
+		Body:    fmt.Sprintf("Use this token to verify your email address, it expires in %s: %s", u.config.VerificationTTL, plain),
+	})
+}
+
+// issueActionToken stores the hash of a new single-use token for the subject's
+// current address and returns the plain value
+func (u *userInteraction) issueActionToken(subject *user.UserRegister, purpose string, ttl time.Duration) (string, error) {
+	plain, hash, err := token.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	actionToken := &user.ActionToken{
+		SubjectID: subject.ID,
+		Email:     subject.Email,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := u.actionTokenRepo.CreateActionToken(actionToken); err != nil {
+		return "", fmt.Errorf("failed to create %s token: %w", purpose, err)
+	}
+	return plain, nil
+}
+
+func NewUserUsecase(userRepo repository.UserRepository, actionTokenRepo repository.ActionTokenRepository, sessionUseCase SessionUseCase, loginGuard LoginGuard, notifier notify.Notifier, config UserConfig) UserUseCase {
 	return &userInteraction{
-		userRepo: userRepo,
+		userRepo:        userRepo,
+		actionTokenRepo: actionTokenRepo,
+		sessionUseCase:  sessionUseCase,
+		loginGuard:      loginGuard,
+		notifier:        notifier,
+		config:          config,
 	}
 }
@@ -0,0 +1,383 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ratheeshkumar25/pkg/mergepatch"
+	"github.com/ratheeshkumar25/pkg/notify"
+	"github.com/ratheeshkumar25/pkg/token"
+	user "github.com/ratheeshkumar25/pkg/user/entity"
+	"github.com/ratheeshkumar25/pkg/user/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// userStubRepository serves one user, the embedded interface panics for anything else
+type userStubRepository struct {
+	repository.UserRepository
+	stored *user.UserRegister
+}
+
+func (s *userStubRepository) GetUserByID(id uint) (*user.UserRegister, error) {
+	copied := *s.stored
+	return &copied, nil
+}
+
+func (s *userStubRepository) UpdateUser(updated *user.UserRegister) error {
+	if updated.Version != s.stored.Version {
+		return repository.ErrVersionConflict
+	}
+	updated.Version++
+	copied := *updated
+	s.stored = &copied
+	return nil
+}
+
+func TestPatchUser(t *testing.T) {
+	stub := &userStubRepository{stored: &user.UserRegister{Model: gorm.Model{ID: 1}, Name: "Ratheesh G", Email: "ratheeshgk@live1.com", Phone: "9961429911", Version: 1}}
+	users := NewUserUsecase(stub, nil, nil, nil, nil, UserConfig{})
+
+	patched, err := users.PatchUser(1, &user.UserPatch{Phone: mergepatch.Value("9961429921")})
+	require.NoError(t, err)
+	assert.Equal(t, "Ratheesh G", patched.Name)
+	assert.Equal(t, "ratheeshgk@live1.com", patched.Email)
+	assert.Equal(t, "9961429921", patched.Phone)
+	assert.Equal(t, uint(2), patched.Version)
+
+	for name, patch := range map[string]*user.UserPatch{
+		"null name":     {Name: mergepatch.Null[string]()},
+		"blank phone":   {Phone: mergepatch.Value("")},
+		"bad email":     {Email: mergepatch.Value("not-an-address")},
+		"display email": {Email: mergepatch.Value("Ratheesh <ratheeshgk@live1.com>")},
+	} {
+		_, err := users.PatchUser(1, patch)
+		assert.True(t, errors.Is(err, mergepatch.ErrInvalidPatch), name)
+	}
+
+	_, err = users.PatchUser(1, &user.UserPatch{Name: mergepatch.Value("Ratheesh"), Version: 1})
+	assert.True(t, errors.Is(err, repository.ErrVersionConflict))
+}
+
+// memoryUserRepository keeps users in a map, the embedded interface panics for anything else
+type memoryUserRepository struct {
+	repository.UserRepository
+	users        map[uint]*user.UserRegister
+	actionTokens *memoryActionTokenRepository
+}
+
+func (m *memoryUserRepository) add(t *testing.T, stored user.UserRegister, password string) {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	stored.Password = string(hashed)
+	m.users[stored.ID] = &stored
+}
+
+func (m *memoryUserRepository) CreateUser(created *user.UserRegister) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(created.Password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	created.ID = uint(len(m.users) + 1)
+	created.Password = string(hashed)
+	copied := *created
+	m.users[created.ID] = &copied
+	return nil
+}
+
+func (m *memoryUserRepository) FindUserByName(username string) (*user.UserRegister, error) {
+	for _, found := range m.users {
+		if found.UserName == username {
+			copied := *found
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryUserRepository) FindUserByEmail(email string) (*user.UserRegister, error) {
+	for _, found := range m.users {
+		if found.Email == email {
+			copied := *found
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryUserRepository) GetUserByID(id uint) (*user.UserRegister, error) {
+	found, ok := m.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *found
+	return &copied, nil
+}
+
+func (m *memoryUserRepository) UpdatePassword(id uint, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	m.users[id].Password = string(hashed)
+	return nil
+}
+
+func (m *memoryUserRepository) ResetPassword(id, actionTokenID uint, password string) error {
+	if err := m.actionTokens.ConsumeActionToken(actionTokenID); err != nil {
+		return err
+	}
+	return m.UpdatePassword(id, password)
+}
+
+func (m *memoryUserRepository) UpdateUser(updated *user.UserRegister) error {
+	copied := *updated
+	m.users[updated.ID] = &copied
+	return nil
+}
+
+func (m *memoryUserRepository) MarkEmailVerified(id uint) error {
+	now := time.Now()
+	m.users[id].VerifiedAt = &now
+	return nil
+}
+
+// memoryActionTokenRepository keeps action tokens in a map
+type memoryActionTokenRepository struct {
+	tokens map[uint]*user.ActionToken
+}
+
+func (m *memoryActionTokenRepository) CreateActionToken(actionToken *user.ActionToken) error {
+	actionToken.ID = uint(len(m.tokens) + 1)
+	copied := *actionToken
+	m.tokens[actionToken.ID] = &copied
+	return nil
+}
+
+func (m *memoryActionTokenRepository) FindActionToken(tokenHash, purpose string) (*user.ActionToken, error) {
+	for _, found := range m.tokens {
+		if found.TokenHash == tokenHash && found.Purpose == purpose {
+			copied := *found
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryActionTokenRepository) ConsumeActionToken(id uint) error {
+	found := m.tokens[id]
+	if found.UsedAt != nil {
+		return repository.ErrActionTokenUsed
+	}
+	now := time.Now()
+	found.UsedAt = &now
+	return nil
+}
+
+// recordingNotifier keeps every message it is asked to send
+type recordingNotifier struct {
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Send(msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// lastToken returns the plain token at the end of the latest message
+func (n *recordingNotifier) lastToken(t *testing.T) string {
+	t.Helper()
+	require.NotEmpty(t, n.messages)
+	body := n.messages[len(n.messages)-1].Body
+	return body[strings.LastIndex(body, " ")+1:]
+}
+
+type userFixture struct {
+	users    UserUseCase
+	repo     *memoryUserRepository
+	tokens   *memoryActionTokenRepository
+	sessions SessionUseCase
+	maker    token.Maker
+	notifier *recordingNotifier
+}
+
+func newUserFixture(t *testing.T) *userFixture {
+	t.Helper()
+	tokens := &memoryActionTokenRepository{tokens: map[uint]*user.ActionToken{}}
+	repo := &memoryUserRepository{users: map[uint]*user.UserRegister{}, actionTokens: tokens}
+	repo.add(t, user.UserRegister{Model: gorm.Model{ID: 1}, UserName: "ratheesh", Email: "ratheeshgk@live1.com"}, "Old-passw0rd")
+	sessions, _, maker := newTestSessionUseCase(t)
+	guard, _, _ := newTestLoginGuard()
+	notifier := &recordingNotifier{}
+	users := NewUserUsecase(repo, tokens, sessions, guard, notifier, UserConfig{
+		ResetTTL:             time.Hour,
+		VerificationTTL:      time.Hour,
+		RequireVerifiedEmail: true,
+		PasswordPolicy:       PasswordPolicy{MinLength: 10, RequireDigit: true},
+	})
+	return &userFixture{users: users, repo: repo, tokens: tokens, sessions: sessions, maker: maker, notifier: notifier}
+}
+
+// startSession logs the fixture user in and returns the session ID
+func (f *userFixture) startSession(t *testing.T) uint {
+	t.Helper()
+	pair, err := f.sessions.StartSession(1, user.AccountUser, "customer", "", "", false)
+	require.NoError(t, err)
+	claims, err := f.maker.VerifyToken(pair.AccessToken)
+	require.NoError(t, err)
+	return claims.SessionID
+}
+
+func (f *userFixture) assertPassword(t *testing.T, password string) {
+	t.Helper()
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(f.repo.users[1].Password), []byte(password)))
+}
+
+func TestResetPassword(t *testing.T) {
+	f := newUserFixture(t)
+	sessionID := f.startSession(t)
+
+	// Unknown emails get no mail and no error
+	require.NoError(t, f.users.ForgotPassword("nobody@example.com"))
+	assert.Empty(t, f.notifier.messages)
+
+	require.NoError(t, f.users.ForgotPassword("ratheeshgk@live1.com"))
+	resetToken := f.notifier.lastToken(t)
+	assert.Equal(t, "ratheeshgk@live1.com", f.notifier.messages[0].To)
+	assert.Equal(t, token.HashOpaqueToken(resetToken), f.tokens.tokens[1].TokenHash)
+
+	assert.True(t, errors.Is(f.users.ResetPassword("unknown", "New-passw0rd"), ErrInvalidResetToken))
+
+	// A password the policy rejects leaves the token usable
+	var policyErr *PasswordPolicyError
+	assert.True(t, errors.As(f.users.ResetPassword(resetToken, "short"), &policyErr))
+	assert.Nil(t, f.tokens.tokens[1].UsedAt)
+	f.assertPassword(t, "Old-passw0rd")
+
+	require.NoError(t, f.users.ResetPassword(resetToken, "New-passw0rd"))
+	f.assertPassword(t, "New-passw0rd")
+	assert.NotNil(t, f.tokens.tokens[1].UsedAt)
+	_, err := f.sessions.ValidateSession(sessionID)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+
+	// The token works once
+	assert.True(t, errors.Is(f.users.ResetPassword(resetToken, "Other-passw0rd"), ErrInvalidResetToken))
+	f.assertPassword(t, "New-passw0rd")
+
+	// A verification token cannot reset a password
+	require.NoError(t, f.users.ResendVerification("ratheeshgk@live1.com"))
+	assert.True(t, errors.Is(f.users.ResetPassword(f.notifier.lastToken(t), "Other-passw0rd"), ErrInvalidResetToken))
+
+	require.NoError(t, f.users.ForgotPassword("ratheeshgk@live1.com"))
+	expired := f.notifier.lastToken(t)
+	f.tokens.tokens[3].ExpiresAt = time.Now().Add(-time.Minute)
+	assert.True(t, errors.Is(f.users.ResetPassword(expired, "Other-passw0rd"), ErrInvalidResetToken))
+	f.assertPassword(t, "New-passw0rd")
+}
+
+func TestVerifyEmail(t *testing.T) {
+	f := newUserFixture(t)
+
+	// Signing up mails a verification token, logging in waits for it
+	require.NoError(t, f.users.RegisterUser(&user.UserRegister{UserName: "anu", Email: "anu@example.com", Password: "First-passw0rd"}))
+	require.Len(t, f.notifier.messages, 1)
+	assert.Equal(t, "anu@example.com", f.notifier.messages[0].To)
+	verificationToken := f.notifier.lastToken(t)
+	_, err := f.users.Login(&user.UserLogin{UserName: "anu", Password: "First-passw0rd"}, "192.0.2.1")
+	assert.True(t, errors.Is(err, ErrEmailNotVerified))
+
+	assert.True(t, errors.Is(f.users.VerifyEmail("unknown"), ErrInvalidVerificationToken))
+	// A reset token cannot verify an address
+	require.NoError(t, f.users.ForgotPassword("anu@example.com"))
+	assert.True(t, errors.Is(f.users.VerifyEmail(f.notifier.lastToken(t)), ErrInvalidVerificationToken))
+	assert.False(t, f.repo.users[2].EmailVerified())
+
+	require.NoError(t, f.users.VerifyEmail(verificationToken))
+	assert.True(t, f.repo.users[2].EmailVerified())
+	_, err = f.users.Login(&user.UserLogin{UserName: "anu", Password: "First-passw0rd"}, "192.0.2.1")
+	assert.NoError(t, err)
+
+	// The token works once
+	assert.True(t, errors.Is(f.users.VerifyEmail(verificationToken), ErrInvalidVerificationToken))
+}
+
+func TestVerifyEmailRejectsLinkForPreviousAddress(t *testing.T) {
+	f := newUserFixture(t)
+
+	require.NoError(t, f.users.ResendVerification("ratheeshgk@live1.com"))
+	oldLink := f.notifier.lastToken(t)
+
+	_, err := f.users.PatchUser(1, &user.UserPatch{Email: mergepatch.Value("someone-else@example.com")})
+	require.NoError(t, err)
+	require.Len(t, f.notifier.messages, 2)
+	assert.Equal(t, "someone-else@example.com", f.notifier.messages[1].To)
+
+	// The link mailed to the old address does not vouch for the new one
+	assert.True(t, errors.Is(f.users.VerifyEmail(oldLink), ErrInvalidVerificationToken))
+	assert.False(t, f.repo.users[1].EmailVerified())
+
+	require.NoError(t, f.users.VerifyEmail(f.notifier.lastToken(t)))
+	assert.True(t, f.repo.users[1].EmailVerified())
+}
+
+func TestResendVerification(t *testing.T) {
+	f := newUserFixture(t)
+
+	// Unknown emails get no mail and no error
+	require.NoError(t, f.users.ResendVerification("nobody@example.com"))
+	assert.Empty(t, f.notifier.messages)
+
+	require.NoError(t, f.users.ResendVerification("ratheeshgk@live1.com"))
+	require.Len(t, f.notifier.messages, 1)
+	expired := f.notifier.lastToken(t)
+	f.tokens.tokens[1].ExpiresAt = time.Now().Add(-time.Minute)
+	assert.True(t, errors.Is(f.users.VerifyEmail(expired), ErrInvalidVerificationToken))
+	assert.False(t, f.repo.users[1].EmailVerified())
+
+	require.NoError(t, f.users.ResendVerification("ratheeshgk@live1.com"))
+	require.NoError(t, f.users.VerifyEmail(f.notifier.lastToken(t)))
+	assert.True(t, f.repo.users[1].EmailVerified())
+
+	// Verified addresses are not mailed again
+	require.NoError(t, f.users.ResendVerification("ratheeshgk@live1.com"))
+	assert.Len(t, f.notifier.messages, 2)
+}
+
+func TestChangePassword(t *testing.T) {
+	f := newUserFixture(t)
+	current := f.startSession(t)
+	other := f.startSession(t)
+
+	assert.True(t, errors.Is(f.users.ChangePassword(1, current, "wrong", "New-passw0rd", "192.0.2.1"), ErrWrongPassword))
+	assert.True(t, errors.Is(f.users.ChangePassword(1, current, "Old-passw0rd", "Old-passw0rd", "192.0.2.1"), ErrSamePassword))
+	var policyErr *PasswordPolicyError
+	assert.True(t, errors.As(f.users.ChangePassword(1, current, "Old-passw0rd", "short", "192.0.2.1"), &policyErr))
+	f.assertPassword(t, "Old-passw0rd")
+
+	// Only the session that changed the password stays logged in
+	require.NoError(t, f.users.ChangePassword(1, current, "Old-passw0rd", "New-passw0rd", "192.0.2.1"))
+	f.assertPassword(t, "New-passw0rd")
+	_, err := f.sessions.ValidateSession(current)
+	assert.NoError(t, err)
+	_, err = f.sessions.ValidateSession(other)
+	assert.True(t, errors.Is(err, ErrSessionRevoked))
+}
+
+func TestChangePasswordGuessesLockAccount(t *testing.T) {
+	f := newUserFixture(t)
+	current := f.startSession(t)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, errors.Is(f.users.ChangePassword(1, current, "wrong", "New-passw0rd", "192.0.2.1"), ErrWrongPassword))
+	}
+
+	// The right password no longer helps once the account is locked
+	var locked *LockedError
+	assert.True(t, errors.As(f.users.ChangePassword(1, current, "Old-passw0rd", "New-passw0rd", "192.0.2.1"), &locked))
+	f.assertPassword(t, "Old-passw0rd")
+}